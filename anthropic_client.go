@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	anthropicDefaultEndpoint  = "https://api.anthropic.com/v1"
+	anthropicAPIVersion       = "2023-06-01"
+	anthropicDefaultMaxTokens = 1024 // max_tokens is mandatory in the Messages API
+)
+
+// AnthropicClient talks to the Anthropic Messages API.
+type AnthropicClient struct {
+	apiEndpoint string
+	apiKey      string
+	httpClient  *http.Client
+}
+
+// NewAnthropicClient creates a client for the Messages API at apiEndpoint
+// (e.g. https://api.anthropic.com/v1).
+func NewAnthropicClient(apiEndpoint, apiKey string, timeout int) *AnthropicClient {
+	if apiEndpoint == "" {
+		apiEndpoint = anthropicDefaultEndpoint
+	}
+
+	return &AnthropicClient{
+		apiEndpoint: strings.TrimRight(apiEndpoint, "/"),
+		apiKey:      apiKey,
+		httpClient: &http.Client{
+			Timeout: time.Duration(timeout) * time.Second,
+		},
+	}
+}
+
+type anthropicRequest struct {
+	Model       string    `json:"model"`
+	System      string    `json:"system,omitempty"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature float32   `json:"temperature,omitempty"`
+	TopP        float32   `json:"top_p,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type anthropicResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      anthropicUsage `json:"usage"`
+}
+
+// anthropicStreamEvent covers the fields used from every SSE event type.
+type anthropicStreamEvent struct {
+	Type    string             `json:"type"`
+	Message *anthropicResponse `json:"message,omitempty"` // message_start
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"` // content_block_delta, message_delta
+	Usage *anthropicUsage `json:"usage,omitempty"` // message_delta
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// GenerateCompletion sends request to /messages. System messages are moved
+// into the top-level system prompt; penalties have no Anthropic equivalent
+// and are dropped.
+func (c *AnthropicClient) GenerateCompletion(ctx context.Context, request OpenAIRequest, headers map[string]string, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
+	payload := anthropicRequest{
+		Model:       request.Model,
+		MaxTokens:   request.MaxTokens,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		Stream:      request.Stream,
+	}
+	if payload.MaxTokens <= 0 {
+		payload.MaxTokens = anthropicDefaultMaxTokens
+	}
+
+	var system []string
+	for _, message := range request.Messages {
+		if message.Role == "system" {
+			system = append(system, message.Content)
+			continue
+		}
+		payload.Messages = append(payload.Messages, message)
+	}
+	payload.System = strings.Join(system, "\n\n")
+
+	resp, startTime, err := postJSON(ctx, c.httpClient, c.apiEndpoint+"/messages", payload, headers, func(req *http.Request) {
+		req.Header.Set("x-api-key", c.apiKey)
+		req.Header.Set("anthropic-version", anthropicAPIVersion)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if request.Stream && strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return c.handleStreamingResponse(resp.Body, startTime, onToken, onFirstToken)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading response: %v", err)
+	}
+
+	var message anthropicResponse
+	if err := json.Unmarshal(body, &message); err != nil {
+		return nil, 0, fmt.Errorf("error unmarshaling response: %v", err)
+	}
+
+	var text strings.Builder
+	for _, block := range message.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	return newTextResponse(message.ID, message.Model, text.String(), message.StopReason, anthropicToUsage(message.Usage)), time.Since(startTime), nil
+}
+
+func (c *AnthropicClient) handleStreamingResponse(body io.Reader, startTime time.Time, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
+	reader := bufio.NewReader(body)
+	collector := newStreamCollector(startTime, onToken, onFirstToken)
+
+	var id, model, stopReason string
+	var usage anthropicUsage
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, 0, fmt.Errorf("error reading stream response: %v", err)
+		}
+
+		// The event type is repeated inside the data payload, so the
+		// "event:" lines can be skipped.
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			return nil, 0, fmt.Errorf("error unmarshaling stream event: %v", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				id = event.Message.ID
+				model = event.Message.Model
+				usage = event.Message.Usage
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				collector.add(event.Delta.Text)
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				stopReason = event.Delta.StopReason
+			}
+			// message_delta carries the cumulative output token count.
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+				if event.Usage.InputTokens > 0 {
+					usage.InputTokens = event.Usage.InputTokens
+				}
+			}
+		case "error":
+			if event.Error != nil {
+				return nil, 0, fmt.Errorf("stream error (%s): %s", event.Error.Type, event.Error.Message)
+			}
+			return nil, 0, fmt.Errorf("stream error")
+		case "message_stop":
+			return collector.response(id, model, stopReason, anthropicToUsage(usage)), collector.firstTokenLatency(), nil
+		}
+	}
+
+	return collector.response(id, model, stopReason, anthropicToUsage(usage)), collector.firstTokenLatency(), nil
+}
+
+func anthropicToUsage(usage anthropicUsage) Usage {
+	return Usage{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      usage.InputTokens + usage.OutputTokens,
+	}
+}
@@ -3,15 +3,18 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 const (
-	maxStoredBatches = 50
-	appVersion       = "v0.3"
+	appVersion = "v0.3"
 )
 
 // App struct
@@ -19,26 +22,87 @@ type App struct {
 	ctx               context.Context
 	speedTestService  *SpeedTestService
 	exportService     *ExportService
+	historyService    *HistoryService
+	retention         HistoryRetentionPolicy
 	activeTests       map[string]*TestBatch
 	cancelFuncs       map[string]context.CancelFunc
 	completedBatchIDs []string
+	metricsExporter   *MetricsExporter
+	metricsServer     *http.Server
+	metricsAddress    string
 	mu                sync.RWMutex
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{
+	app := &App{
 		speedTestService: NewSpeedTestService(),
 		exportService:    NewExportService("exports"),
 		activeTests:      make(map[string]*TestBatch),
 		cancelFuncs:      make(map[string]context.CancelFunc),
+		metricsExporter:  NewMetricsExporter(),
 	}
+	app.metricsExporter.Subscribe(app.speedTestService.Events())
+	return app
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.loadHistory()
+	a.emitEvents(a.speedTestService.Events().Subscribe("", 0))
+
+	if cfg, err := loadAppConfigFromDisk(); err == nil {
+		a.speedTestService.SetTokenizers(cfg.Tokenizers)
+		if cfg.MetricsAddress != "" {
+			if err := a.startMetricsServer(cfg.MetricsAddress); err != nil {
+				log.Printf("metrics: %v", err)
+			}
+		}
+	}
+}
+
+// loadHistory opens the on-disk history store and restores previously
+// completed batches so they are available after a restart.
+func (a *App) loadHistory() {
+	// The retention policy also bounds the in-memory batches, so it is
+	// set even when the history cannot be stored on disk.
+	cfg, err := loadAppConfigFromDisk()
+	if err != nil {
+		log.Printf("history: failed to load retention policy: %v", err)
+	}
+	a.retention = historyRetentionFor(cfg)
+
+	dir, err := getHistoryDir()
+	if err != nil {
+		log.Printf("history: %v", err)
+		return
+	}
+
+	history, err := NewHistoryService(dir)
+	if err != nil {
+		log.Printf("history: %v", err)
+		return
+	}
+
+	batches, err := history.LoadAll()
+	if err != nil {
+		log.Printf("history: %v", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.historyService = history
+	for i := range batches {
+		batch := batches[i]
+		if _, exists := a.activeTests[batch.ID]; !exists {
+			a.completedBatchIDs = append(a.completedBatchIDs, batch.ID)
+		}
+		a.activeTests[batch.ID] = &batch
+	}
+	a.applyRetentionLocked()
 }
 
 // GetAppVersion returns the current application version.
@@ -60,10 +124,35 @@ func (a *App) GetAvailableModels(endpoint, apiKey string) ([]string, error) {
 
 // StartSpeedTest starts a new speed test with the given configuration
 func (a *App) StartSpeedTest(config TestConfiguration) (*TestBatch, error) {
-	// Store the batch ID for progress tracking
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	return a.startSpeedTestLocked(config, runOptions{}), nil
+}
 
+// ReplayTestBatch re-runs a stored batch with its configuration and seed,
+// so the server receives the same prompts on the same schedule. A batch of
+// a matrix run is replayed against its own target only.
+func (a *App) ReplayTestBatch(batchID string) (*TestBatch, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	batch, exists := a.activeTests[batchID]
+	if !exists {
+		return nil, fmt.Errorf("test batch not found: %s", batchID)
+	}
+	if batch.Seed == 0 {
+		return nil, fmt.Errorf("test batch %s has no recorded seed and cannot be replayed", batchID)
+	}
+
+	config := batch.Configuration
+	config.Seed = batch.Seed
+	return a.startSpeedTestLocked(config, runOptions{replayOf: batchID}), nil
+}
+
+// startSpeedTestLocked runs a batch in the background and returns its
+// placeholder. a.mu must be held.
+func (a *App) startSpeedTestLocked(config TestConfiguration, opts runOptions) *TestBatch {
+	// Store the batch ID for progress tracking
 	batchID := uuid.New().String()
 
 	// Create a context with cancel for this test batch
@@ -78,7 +167,7 @@ func (a *App) StartSpeedTest(config TestConfiguration) (*TestBatch, error) {
 			a.mu.Unlock()
 		}()
 
-		batch, err := a.speedTestService.RunSpeedTest(ctx, config, batchID)
+		batch, err := a.speedTestService.runSpeedTest(ctx, config, batchID, opts)
 		if err != nil {
 			fmt.Printf("Error running speed test: %v\n", err)
 			return
@@ -92,9 +181,77 @@ func (a *App) StartSpeedTest(config TestConfiguration) (*TestBatch, error) {
 	// Return a placeholder batch that will be updated
 	return &TestBatch{
 		ID:            batchID,
+		ReplayOf:      opts.replayOf,
+		Seed:          config.Seed,
 		Configuration: config,
 		Results:       []TestResult{},
-	}, nil
+	}
+}
+
+// StartMatrixTest runs config against every target side by side. The
+// returned placeholder lists the batch ID assigned to each target; each
+// batch can be followed through its events and stopped with StopSpeedTest.
+func (a *App) StartMatrixTest(config TestConfiguration, targets []MatrixTarget) (*ComparisonResult, error) {
+	if err := validateMatrixTargets(targets); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	matrixID := uuid.New().String()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	placeholder := &ComparisonResult{MatrixID: matrixID, Batches: []TestBatch{}}
+	targets = append([]MatrixTarget(nil), targets...)
+	for i := range targets {
+		targets[i].BatchID = uuid.New().String()
+		a.cancelFuncs[targets[i].BatchID] = cancel
+		placeholder.Targets = append(placeholder.Targets, MatrixTargetSummary{
+			Name:        matrixTargetName(targets[i]),
+			APIEndpoint: targets[i].APIEndpoint,
+			Provider:    targets[i].Provider,
+			Model:       targets[i].Model,
+			BatchID:     targets[i].BatchID,
+		})
+	}
+
+	go func() {
+		defer func() {
+			cancel()
+			a.mu.Lock()
+			for _, target := range targets {
+				delete(a.cancelFuncs, target.BatchID)
+			}
+			a.mu.Unlock()
+		}()
+
+		result, err := a.speedTestService.RunMatrix(ctx, config, targets, matrixID)
+		if err != nil {
+			fmt.Printf("Error running matrix test: %v\n", err)
+		}
+		if result == nil {
+			return
+		}
+
+		a.mu.Lock()
+		for i := range result.Batches {
+			a.addCompletedBatchLocked(&result.Batches[i])
+		}
+		a.mu.Unlock()
+	}()
+
+	return placeholder, nil
+}
+
+// GetMatrixComparison returns the per-target comparison of a finished
+// matrix run.
+func (a *App) GetMatrixComparison(matrixID string) (*ComparisonResult, error) {
+	batches, err := a.GetAllTestBatches()
+	if err != nil {
+		return nil, err
+	}
+	return a.speedTestService.matrixComparison(matrixID, batches)
 }
 
 // StopSpeedTest stops a running speed test
@@ -122,52 +279,15 @@ func (a *App) StopSpeedTest(batchID string) error {
 	return fmt.Errorf("test batch not found or not running: %s", batchID)
 }
 
-// GetTestProgress retrieves the current progress of active tests
-func (a *App) GetTestProgress() ([]ProgressUpdate, error) {
-	progress := make([]ProgressUpdate, 0)
-
-	// Collect progress updates from the channel
-	for {
-		select {
-		case update := <-a.speedTestService.GetProgressChannel():
-			progress = append(progress, update)
-		default:
-			// No more updates available
-			return progress, nil
-		}
-	}
-}
-
-// GetTestResults retrieves completed test results
-func (a *App) GetTestResults() ([]TestResult, error) {
-	results := make([]TestResult, 0)
-
-	// Collect results from the channel
-	for {
-		select {
-		case result := <-a.speedTestService.GetResultsChannel():
-			results = append(results, result)
-		default:
-			// No more results available
-			return results, nil
-		}
-	}
-}
-
-// GetTelemetryUpdates retrieves the latest telemetry updates
-func (a *App) GetTelemetryUpdates() ([]TelemetryUpdate, error) {
-	updates := make([]TelemetryUpdate, 0)
-
-	// Collect updates from the channel
-	for {
-		select {
-		case update := <-a.speedTestService.GetTelemetryChannel():
-			updates = append(updates, update)
-		default:
-			// No more updates available
-			return updates, nil
+// emitEvents pushes every batch event to the frontend as a Wails event
+// named "speedtest:<type>" (progress, result, telemetry, completed) with
+// the Event as payload.
+func (a *App) emitEvents(sub *Subscription) {
+	go func() {
+		for event := range sub.Events() {
+			runtime.EventsEmit(a.ctx, "speedtest:"+event.Type, event)
 		}
-	}
+	}()
 }
 
 // GetTestBatch retrieves a completed test batch by ID
@@ -299,6 +419,12 @@ func (a *App) OpenExportDirectory() error {
 
 // GetDefaultTestConfiguration returns a default test configuration
 func (a *App) GetDefaultTestConfiguration() TestConfiguration {
+	return defaultTestConfiguration()
+}
+
+// defaultTestConfiguration is shared by the desktop app and the headless CLI
+// so both start from the same baseline.
+func defaultTestConfiguration() TestConfiguration {
 	return TestConfiguration{
 		APIEndpoint:      "https://api.openai.com/v1",
 		APIKey:           "",
@@ -315,6 +441,12 @@ func (a *App) GetDefaultTestConfiguration() TestConfiguration {
 		ConcurrentTests:  3,
 		Timeout:          60,
 		Headers:          make(map[string]string),
+		PrefixConfig:     PrefixConfiguration{HitRatio: 1},
+		ConversationConfig: ConversationConfiguration{
+			Sessions:   4,
+			Turns:      5,
+			TurnLength: 64,
+		},
 	}
 }
 
@@ -333,9 +465,9 @@ func (a *App) ValidatePromptConfig(promptType string, promptLength int) error {
 	return ValidatePromptConfig(promptType, promptLength)
 }
 
-// addCompletedBatchLocked adds a completed batch to the in-memory store
-// and enforces an upper bound on how many batches we retain to avoid
-// unbounded memory growth over a long-running session.
+// addCompletedBatchLocked adds a completed batch to the in-memory store,
+// persists it to the history directory and prunes whatever falls outside
+// the configured retention policy.
 // Caller must hold a.mu.Lock().
 func (a *App) addCompletedBatchLocked(batch *TestBatch) {
 	if batch == nil || batch.ID == "" {
@@ -349,22 +481,260 @@ func (a *App) addCompletedBatchLocked(batch *TestBatch) {
 
 	a.activeTests[batch.ID] = batch
 
-	if len(a.completedBatchIDs) <= maxStoredBatches {
-		return
+	if a.historyService != nil {
+		if err := a.historyService.Save(*batch); err != nil {
+			log.Printf("history: %v", err)
+		}
+	}
+
+	a.applyRetentionLocked()
+}
+
+// applyRetentionLocked drops batches outside the retention policy from
+// memory and disk. Caller must hold a.mu.Lock().
+func (a *App) applyRetentionLocked() {
+	ordered := make([]TestBatch, 0, len(a.completedBatchIDs))
+	for _, id := range a.completedBatchIDs {
+		if batch, exists := a.activeTests[id]; exists {
+			ordered = append(ordered, *batch)
+		}
+	}
+
+	for _, id := range selectBatchesToPrune(ordered, a.retention, time.Now()) {
+		a.removeBatchLocked(id)
+	}
+}
+
+// removeBatchLocked deletes a batch from memory and disk.
+// Caller must hold a.mu.Lock().
+func (a *App) removeBatchLocked(batchID string) {
+	delete(a.activeTests, batchID)
+	for i, id := range a.completedBatchIDs {
+		if id == batchID {
+			a.completedBatchIDs = append(a.completedBatchIDs[:i], a.completedBatchIDs[i+1:]...)
+			break
+		}
 	}
 
-	// Drop oldest batches beyond the retention window.
-	overflow := len(a.completedBatchIDs) - maxStoredBatches
-	toRemove := a.completedBatchIDs[:overflow]
-	a.completedBatchIDs = a.completedBatchIDs[overflow:]
+	if a.historyService != nil {
+		if err := a.historyService.Delete(batchID); err != nil {
+			log.Printf("history: %v", err)
+		}
+	}
+}
+
+// DeleteTestBatch removes a completed batch from the history.
+func (a *App) DeleteTestBatch(batchID string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, exists := a.activeTests[batchID]; !exists {
+		return fmt.Errorf("test batch not found: %s", batchID)
+	}
+
+	a.removeBatchLocked(batchID)
+	return nil
+}
+
+// RenameTestBatch sets the display name of a completed batch.
+// An empty name clears the label.
+func (a *App) RenameTestBatch(batchID string, name string) error {
+	return a.updateBatch(batchID, func(batch *TestBatch) {
+		batch.Name = strings.TrimSpace(name)
+	})
+}
+
+// SetTestBatchTags replaces the tags of a completed batch.
+func (a *App) SetTestBatchTags(batchID string, tags []string) error {
+	return a.updateBatch(batchID, func(batch *TestBatch) {
+		batch.Tags = normalizeTags(tags)
+	})
+}
+
+// SetBaselineBatch marks or unmarks a completed batch as a regression
+// baseline. Baselines are exempt from history retention.
+func (a *App) SetBaselineBatch(batchID string, baseline bool) error {
+	return a.updateBatch(batchID, func(batch *TestBatch) {
+		batch.Baseline = baseline
+	})
+}
 
-	for _, id := range toRemove {
-		// Do not delete the just-added batch if IDs somehow overlap.
-		if id == batch.ID {
-			continue
+// GetDefaultRegressionTolerances returns the tolerances used when the UI
+// has not customised them.
+func (a *App) GetDefaultRegressionTolerances() RegressionTolerances {
+	return defaultRegressionTolerances()
+}
+
+// CompareWithBaseline checks a batch against the newest baseline for the
+// same model, or against baselineID when it is not empty.
+func (a *App) CompareWithBaseline(batchID string, baselineID string, tolerances RegressionTolerances) (*RegressionReport, error) {
+	if err := validateRegressionTolerances(tolerances); err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	candidate, exists := a.activeTests[batchID]
+	if !exists {
+		return nil, fmt.Errorf("test batch not found: %s", batchID)
+	}
+
+	var baseline *TestBatch
+	if baselineID != "" {
+		if baseline, exists = a.activeTests[baselineID]; !exists {
+			return nil, fmt.Errorf("baseline batch not found: %s", baselineID)
+		}
+	} else {
+		// completedBatchIDs is oldest first, so the last match is the newest.
+		for _, id := range a.completedBatchIDs {
+			batch, ok := a.activeTests[id]
+			if ok && batch.Baseline && id != batchID && batch.Configuration.Model == candidate.Configuration.Model {
+				baseline = batch
+			}
+		}
+		if baseline == nil {
+			return nil, fmt.Errorf("no baseline batch for model %s", candidate.Configuration.Model)
 		}
-		delete(a.activeTests, id)
 	}
+
+	report := CompareToBaseline(*baseline, *candidate, tolerances)
+	return &report, nil
+}
+
+// updateBatch applies fn to a stored batch and writes the result back to disk.
+func (a *App) updateBatch(batchID string, fn func(batch *TestBatch)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	batch, exists := a.activeTests[batchID]
+	if !exists {
+		return fmt.Errorf("test batch not found: %s", batchID)
+	}
+
+	updated := *batch
+	fn(&updated)
+
+	if a.historyService != nil {
+		if err := a.historyService.Save(updated); err != nil {
+			return err
+		}
+	}
+
+	a.activeTests[batchID] = &updated
+	return nil
+}
+
+// GetHistoryRetention returns the active history retention policy.
+func (a *App) GetHistoryRetention() HistoryRetentionPolicy {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.retention
+}
+
+// SetHistoryRetention updates, persists and immediately applies the
+// history retention policy.
+func (a *App) SetHistoryRetention(policy HistoryRetentionPolicy) error {
+	if policy.MaxBatches < 0 || policy.MaxAgeDays < 0 {
+		return fmt.Errorf("retention limits cannot be negative")
+	}
+
+	cfg, err := loadAppConfigFromDisk()
+	if err != nil {
+		return err
+	}
+	cfg.HistoryRetention = &policy
+	if err := saveAppConfigToDisk(cfg); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.retention = policy
+	a.applyRetentionLocked()
+	return nil
+}
+
+// GetMetricsAddress returns the address the Prometheus /metrics endpoint
+// listens on, or an empty string when it is disabled.
+func (a *App) GetMetricsAddress() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.metricsAddress
+}
+
+// SetMetricsAddress starts, moves or (with an empty address) stops the
+// Prometheus /metrics endpoint and persists the choice.
+func (a *App) SetMetricsAddress(addr string) error {
+	addr = strings.TrimSpace(addr)
+	if err := a.startMetricsServer(addr); err != nil {
+		return err
+	}
+
+	cfg, err := loadAppConfigFromDisk()
+	if err != nil {
+		return err
+	}
+	cfg.MetricsAddress = addr
+	return saveAppConfigToDisk(cfg)
+}
+
+// GetTokenizerMappings returns the saved model to HuggingFace tokenizer
+// mappings.
+func (a *App) GetTokenizerMappings() ([]TokenizerMapping, error) {
+	cfg, err := loadAppConfigFromDisk()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Tokenizers, nil
+}
+
+// SetTokenizerMappings validates, persists and applies the model to
+// HuggingFace tokenizer mappings. Every tokenizer is loaded up front so a
+// bad path is reported here rather than when a test starts.
+func (a *App) SetTokenizerMappings(mappings []TokenizerMapping) error {
+	if err := validateTokenizerMappings(mappings); err != nil {
+		return err
+	}
+
+	cfg, err := loadAppConfigFromDisk()
+	if err != nil {
+		return err
+	}
+	cfg.Tokenizers = mappings
+	if err := saveAppConfigToDisk(cfg); err != nil {
+		return err
+	}
+
+	a.speedTestService.SetTokenizers(mappings)
+	return nil
+}
+
+// startMetricsServer replaces the running metrics server with one on addr.
+// An empty addr only stops the current server.
+func (a *App) startMetricsServer(addr string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.metricsServer != nil {
+		a.metricsServer.Close()
+		a.metricsServer = nil
+		a.metricsAddress = ""
+	}
+	if addr == "" {
+		return nil
+	}
+
+	server, err := startMetricsServer(addr, a.metricsExporter)
+	if err != nil {
+		return err
+	}
+	a.metricsServer = server
+	a.metricsAddress = addr
+	return nil
 }
 
 // domReady is called after front-end resources have been loaded
@@ -387,6 +757,10 @@ func (a *App) Shutdown() {
 		cancel()
 		delete(a.cancelFuncs, id)
 	}
+	if a.metricsServer != nil {
+		a.metricsServer.Close()
+		a.metricsServer = nil
+	}
 	a.mu.Unlock()
 
 	if a.speedTestService != nil {
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Providers selectable through TestConfiguration.Provider.
+const (
+	providerOpenAI            = "openai"
+	providerOpenAICompletions = "openai_completions" // legacy /completions with a raw prompt
+	providerAnthropic         = "anthropic"
+	providerOllama            = "ollama"
+	providerTGI               = "tgi"
+)
+
+// LLMBackend sends one chat request to a model server. Requests and
+// responses use the OpenAI shapes; each backend translates them to its own
+// wire format. The returned duration is the time to first token when
+// streaming, or the full request time otherwise.
+type LLMBackend interface {
+	GenerateCompletion(ctx context.Context, request OpenAIRequest, headers map[string]string, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error)
+}
+
+// TokenCallback receives the generated text of each streamed event, once
+// per event even when it carries several parts (reasoning and answer text,
+// tool call arguments), so callers can time chunk arrivals. usage is
+// set when the server attaches running usage to content chunks (e.g. vLLM's
+// continuous usage stats) and nil otherwise.
+type TokenCallback func(content string, usage *Usage)
+
+// NewBackend returns the client for provider. An empty provider means the
+// OpenAI-compatible API.
+func NewBackend(provider, apiEndpoint, apiKey string, timeout int) (LLMBackend, error) {
+	switch strings.ToLower(provider) {
+	case "", providerOpenAI:
+		return NewOpenAIClient(apiEndpoint, apiKey, timeout), nil
+	case providerOpenAICompletions:
+		return NewOpenAICompletionsClient(apiEndpoint, apiKey, timeout), nil
+	case providerAnthropic:
+		return NewAnthropicClient(apiEndpoint, apiKey, timeout), nil
+	case providerOllama:
+		return NewOllamaClient(apiEndpoint, apiKey, timeout), nil
+	case providerTGI:
+		return NewTGIClient(apiEndpoint, apiKey, timeout), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+}
+
+// postJSON sends payload to url and returns the response once the status is
+// known to be 200. Callers own the response body. setAuth applies the
+// provider's authentication headers before the custom headers, so custom
+// headers can override them.
+func postJSON(ctx context.Context, httpClient *http.Client, url string, payload interface{}, headers map[string]string, setAuth func(*http.Request)) (*http.Response, time.Time, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error marshaling request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if setAuth != nil {
+		setAuth(req)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	startTime := time.Now()
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, startTime, fmt.Errorf("error making request: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, startTime, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return resp, startTime, nil
+}
+
+// streamCollector accumulates streamed text and reports the first token,
+// so every streaming backend measures TTFT the same way.
+type streamCollector struct {
+	startTime    time.Time
+	ttft         time.Duration
+	builder      strings.Builder
+	onToken      TokenCallback
+	onFirstToken func(time.Duration)
+}
+
+func newStreamCollector(startTime time.Time, onToken TokenCallback, onFirstToken func(time.Duration)) *streamCollector {
+	return &streamCollector{startTime: startTime, onToken: onToken, onFirstToken: onFirstToken}
+}
+
+// add records one chunk of generated text. Empty chunks are ignored.
+func (c *streamCollector) add(content string) {
+	if content == "" {
+		return
+	}
+
+	c.builder.WriteString(content)
+	if c.onToken != nil {
+		c.onToken(content, nil)
+	}
+
+	if c.ttft == 0 {
+		c.ttft = time.Since(c.startTime)
+		if c.onFirstToken != nil {
+			c.onFirstToken(c.ttft)
+		}
+	}
+}
+
+// firstTokenLatency returns the TTFT, or the elapsed time if nothing was
+// generated.
+func (c *streamCollector) firstTokenLatency() time.Duration {
+	if c.ttft == 0 {
+		return time.Since(c.startTime)
+	}
+	return c.ttft
+}
+
+// response wraps the collected text in an OpenAI-shaped response.
+func (c *streamCollector) response(id, model, finishReason string, usage Usage) *OpenAIResponse {
+	return newTextResponse(id, model, c.builder.String(), finishReason, usage)
+}
+
+func newTextResponse(id, model, content, finishReason string, usage Usage) *OpenAIResponse {
+	return &OpenAIResponse{
+		ID:     id,
+		Object: "chat.completion",
+		Model:  model,
+		Choices: []Choice{{
+			Index:        0,
+			Message:      Message{Role: "assistant", Content: content},
+			FinishReason: finishReason,
+		}},
+		Usage: usage,
+	}
+}
+
+// flattenMessages joins chat messages into a single prompt for raw text
+// endpoints. A lone message is sent verbatim.
+func flattenMessages(messages []Message) string {
+	if len(messages) == 1 {
+		return messages[0].Content
+	}
+
+	var builder strings.Builder
+	for _, message := range messages {
+		role := message.Role
+		if role != "" {
+			role = strings.ToUpper(role[:1]) + role[1:]
+		}
+		fmt.Fprintf(&builder, "%s: %s\n\n", role, message.Content)
+	}
+	builder.WriteString("Assistant:")
+	return builder.String()
+}
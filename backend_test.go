@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func streamRequest() OpenAIRequest {
+	return OpenAIRequest{
+		Model: "test-model",
+		Messages: []Message{
+			{Role: "system", Content: "be brief"},
+			{Role: "user", Content: "hello"},
+		},
+		MaxTokens: 16,
+		Stream:    true,
+	}
+}
+
+// runBackend sends a streaming request and returns the response together
+// with the chunks passed to onToken.
+func runBackend(t *testing.T, backend LLMBackend) (*OpenAIResponse, []string) {
+	t.Helper()
+
+	var chunks []string
+	response, ttft, err := backend.GenerateCompletion(context.Background(), streamRequest(), nil,
+		func(s string, _ *Usage) { chunks = append(chunks, s) }, nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if ttft <= 0 {
+		t.Fatalf("expected a positive TTFT, got %v", ttft)
+	}
+	return response, chunks
+}
+
+func TestAnthropicClientStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "secret" || r.Header.Get("anthropic-version") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var body anthropicRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.System != "be brief" || len(body.Messages) != 1 {
+			http.Error(w, "system prompt not extracted", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_1","model":"test-model","usage":{"input_tokens":12,"output_tokens":1}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":3}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, event := range events {
+			var typed struct{ Type string }
+			json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	}))
+	defer server.Close()
+
+	response, chunks := runBackend(t, NewAnthropicClient(server.URL+"/v1", "secret", 5))
+	if len(chunks) != 2 || response.Choices[0].Message.Content != "Hi there" {
+		t.Fatalf("unexpected content %q from chunks %v", response.Choices[0].Message.Content, chunks)
+	}
+	if response.Usage.PromptTokens != 12 || response.Usage.CompletionTokens != 3 {
+		t.Fatalf("unexpected usage: %+v", response.Usage)
+	}
+	if response.Choices[0].FinishReason != "end_turn" {
+		t.Fatalf("unexpected stop reason %q", response.Choices[0].FinishReason)
+	}
+}
+
+func TestOllamaClientStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"model":"test-model","message":{"role":"assistant","content":"Hi"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"test-model","message":{"role":"assistant","content":" there"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"test-model","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":9,"eval_count":2}`)
+	}))
+	defer server.Close()
+
+	response, chunks := runBackend(t, NewOllamaClient(server.URL, "", 5))
+	if len(chunks) != 2 || response.Choices[0].Message.Content != "Hi there" {
+		t.Fatalf("unexpected content %q from chunks %v", response.Choices[0].Message.Content, chunks)
+	}
+	if response.Usage.PromptTokens != 9 || response.Usage.CompletionTokens != 2 {
+		t.Fatalf("unexpected usage: %+v", response.Usage)
+	}
+}
+
+func TestTGIClientStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/generate_stream" {
+			http.NotFound(w, r)
+			return
+		}
+		var body tgiRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if !strings.HasSuffix(body.Inputs, "Assistant:") || body.Parameters.MaxNewTokens != 16 {
+			http.Error(w, "unexpected payload", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data:{\"token\":{\"id\":1,\"text\":\"Hi\",\"special\":false},\"generated_text\":null,\"details\":null}\n\n")
+		fmt.Fprint(w, "data:{\"token\":{\"id\":2,\"text\":\" there\",\"special\":false},\"generated_text\":null,\"details\":null}\n\n")
+		fmt.Fprint(w, "data:{\"token\":{\"id\":3,\"text\":\"</s>\",\"special\":true},\"generated_text\":\"Hi there\",\"details\":{\"finish_reason\":\"eos_token\",\"generated_tokens\":3,\"input_length\":7}}\n\n")
+	}))
+	defer server.Close()
+
+	response, chunks := runBackend(t, NewTGIClient(server.URL, "", 5))
+	if len(chunks) != 2 || response.Choices[0].Message.Content != "Hi there" {
+		t.Fatalf("expected the special token to be skipped, got %q from chunks %v", response.Choices[0].Message.Content, chunks)
+	}
+	if response.Usage.PromptTokens != 7 || response.Usage.CompletionTokens != 3 {
+		t.Fatalf("unexpected usage: %+v", response.Usage)
+	}
+}
+
+func TestOpenAICompletionsClientStreaming(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var body OpenAICompletionRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		if !strings.Contains(body.Prompt, "User: hello") || body.StreamOptions == nil {
+			http.Error(w, "unexpected payload", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"text\":\"Hi\",\"finish_reason\":null}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"text\":\" there\",\"finish_reason\":\"length\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2,\"total_tokens\":7}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	request := streamRequest()
+	request.StreamOptions = &StreamOptions{IncludeUsage: true}
+	var chunks []string
+	response, _, err := NewOpenAICompletionsClient(server.URL+"/v1", "", 5).GenerateCompletion(
+		context.Background(), request, nil, func(s string, _ *Usage) { chunks = append(chunks, s) }, nil)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if len(chunks) != 2 || response.Choices[0].Message.Content != "Hi there" {
+		t.Fatalf("unexpected content %q from chunks %v", response.Choices[0].Message.Content, chunks)
+	}
+	if response.Usage.PromptTokens != 5 || response.Usage.CompletionTokens != 2 {
+		t.Fatalf("unexpected usage: %+v", response.Usage)
+	}
+}
+
+func TestNewBackendRejectsUnknownProvider(t *testing.T) {
+	if _, err := NewBackend("vertex", "", "", 5); err == nil {
+		t.Fatalf("expected an error for an unknown provider")
+	}
+	if backend, err := NewBackend("", "", "", 5); err != nil {
+		t.Fatalf("empty provider should default to OpenAI: %v", err)
+	} else if _, ok := backend.(*OpenAIClient); !ok {
+		t.Fatalf("expected an OpenAI client, got %T", backend)
+	}
+}
@@ -0,0 +1,1024 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit codes returned by the headless runner so scripts can act on them.
+const (
+	cliExitOK          = 0
+	cliExitFailed      = 1   // run error, no successful requests, or error rate above threshold
+	cliExitUsage       = 2   // invalid flags or configuration
+	cliExitRegressed   = 3   // slower than the -baseline batch beyond the tolerances
+	cliExitInterrupted = 130 // stopped by SIGINT/SIGTERM
+)
+
+const (
+	cliAPIKeyEnv         = "LLM_SPEED_TEST_API_KEY"
+	cliTelemetryInterval = 2 * time.Second
+)
+
+// cliOptions holds the runner settings that are not part of TestConfiguration.
+type cliOptions struct {
+	configPath    string
+	replay        string
+	outputDir     string
+	formats       string
+	maxErrorRate  float64
+	quiet         bool
+	baseline      string
+	tolerances    RegressionTolerances
+	metricsAddr   string
+	metricsLinger time.Duration
+	eventsLog     string
+	targetsPath   string
+	noSave        bool
+}
+
+// runCLI implements `llm-speed-test run`. It builds a TestConfiguration from
+// an optional JSON/YAML file plus flags, drives SpeedTestService.RunSpeedTest,
+// streams progress to the terminal and exports the batch through ExportService.
+func runCLI(args []string) int {
+	config := defaultTestConfiguration()
+	opts := cliOptions{
+		outputDir:  "exports",
+		formats:    "csv,json",
+		tolerances: defaultRegressionTolerances(),
+	}
+
+	// First pass picks up -config. If a file is given it becomes the new
+	// baseline and the flags are parsed again so explicit flags win.
+	fs := newRunFlagSet(&config, &opts)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return cliExitOK
+		}
+		return cliExitUsage
+	}
+
+	if opts.configPath != "" {
+		loaded, err := loadCLIConfigFile(opts.configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return cliExitUsage
+		}
+		config = *loaded
+
+		fs = newRunFlagSet(&config, &opts)
+		if err := fs.Parse(args); err != nil {
+			return cliExitUsage
+		}
+	}
+
+	// A replayed batch provides the configuration and seed the same way,
+	// so its prompts and arrivals are sent again unless flags change them.
+	var replayOf string
+	if opts.replay != "" {
+		replayed, err := loadStoredBatch(opts.replay, "replay batch")
+		if err == nil && opts.configPath != "" {
+			err = fmt.Errorf("-replay cannot be combined with -config")
+		}
+		if err == nil && replayed.Seed == 0 {
+			err = fmt.Errorf("batch %s has no recorded seed and cannot be replayed", replayed.ID)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return cliExitUsage
+		}
+		config = replayed.Configuration
+		config.Seed = replayed.Seed
+		replayOf = replayed.ID
+
+		fs = newRunFlagSet(&config, &opts)
+		if err := fs.Parse(args); err != nil {
+			return cliExitUsage
+		}
+	}
+
+	if config.APIKey == "" {
+		config.APIKey = os.Getenv(cliAPIKeyEnv)
+	}
+
+	// A matrix run applies the shared workload to every target; each
+	// resulting configuration must be valid on its own.
+	var targets []MatrixTarget
+	if opts.targetsPath != "" {
+		loaded, err := loadMatrixTargets(opts.targetsPath)
+		if err == nil {
+			err = validateMatrixTargets(loaded)
+		}
+		if err == nil && opts.baseline != "" {
+			err = fmt.Errorf("-baseline cannot be combined with -targets")
+		}
+		if err == nil && opts.replay != "" {
+			err = fmt.Errorf("-replay cannot be combined with -targets")
+		}
+		if err == nil && config.Tokenizer != "" {
+			err = fmt.Errorf("-tokenizer cannot be combined with -targets; set tokenizer per target instead")
+		}
+		for _, target := range loaded {
+			if err != nil {
+				break
+			}
+			if err = validateCLIConfig(applyMatrixTarget(config, target)); err != nil {
+				err = fmt.Errorf("target %s: %w", matrixTargetName(target), err)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return cliExitUsage
+		}
+		targets = loaded
+	} else if err := validateCLIConfig(config); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return cliExitUsage
+	}
+
+	formats, err := parseExportFormats(opts.formats)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return cliExitUsage
+	}
+
+	// Load the baseline up front so a typo fails before the run, not after.
+	var baseline *TestBatch
+	if opts.baseline != "" {
+		if err := validateRegressionTolerances(opts.tolerances); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return cliExitUsage
+		}
+		if baseline, err = loadBaselineBatch(opts.baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return cliExitUsage
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	service := NewSpeedTestService()
+
+	if opts.metricsAddr != "" {
+		exporter := NewMetricsExporter()
+		server, err := startMetricsServer(opts.metricsAddr, exporter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return cliExitUsage
+		}
+		defer server.Close()
+		exporter.Subscribe(service.Events())
+		fmt.Fprintf(os.Stdout, "Serving Prometheus metrics on http://%s/metrics\n", opts.metricsAddr)
+	}
+
+	// Subscribe before the run starts so no event is missed. The bus is
+	// closed once the run returns, which ends both subscriptions after
+	// they have delivered everything queued.
+	var logDone chan error
+	if opts.eventsLog != "" {
+		logFile, err := os.Create(opts.eventsLog)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: failed to create event log: %v\n", err)
+			return cliExitUsage
+		}
+		defer logFile.Close()
+
+		logDone = make(chan error, 1)
+		logSub := service.Events().Subscribe("", 0)
+		go func() { logDone <- writeEventLog(logSub, logFile) }()
+	}
+
+	// Matrix runs prefix every line with the target it belongs to.
+	labels := make(map[string]string)
+	for i := range targets {
+		targets[i].BatchID = uuid.New().String()
+		labels[targets[i].BatchID] = matrixTargetName(targets[i])
+	}
+
+	printerDone := make(chan struct{})
+	printerSub := service.Events().Subscribe("", 0)
+	go func() {
+		defer close(printerDone)
+		streamCLIUpdates(os.Stdout, printerSub, labels, opts.quiet)
+	}()
+
+	var batches []*TestBatch
+	var matrix *ComparisonResult
+	var runErr error
+	if targets != nil {
+		fmt.Fprintf(os.Stdout, "Running %s against %d targets side by side\n", describeTestMode(config.TestMode), len(targets))
+		matrix, runErr = service.RunMatrix(ctx, config, targets, "")
+		if matrix != nil {
+			for i := range matrix.Batches {
+				batches = append(batches, &matrix.Batches[i])
+			}
+		}
+	} else {
+		fmt.Fprintf(os.Stdout, "Running %s against %s (model %s)\n", describeTestMode(config.TestMode), config.APIEndpoint, config.Model)
+		var batch *TestBatch
+		batch, runErr = service.runSpeedTest(ctx, config, "", runOptions{replayOf: replayOf})
+		if batch != nil {
+			batches = append(batches, batch)
+		}
+	}
+
+	service.Close()
+	<-printerDone
+	if logDone != nil {
+		if err := <-logDone; err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+
+	interrupted := ctx.Err() != nil
+
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", runErr)
+		if len(batches) == 0 {
+			if interrupted {
+				return cliExitInterrupted
+			}
+			return cliExitFailed
+		}
+	}
+
+	for _, batch := range batches {
+		printCLISummary(os.Stdout, batch)
+	}
+	if matrix != nil {
+		printMatrixSummary(os.Stdout, matrix)
+	}
+
+	exporter := NewExportService(opts.outputDir)
+	exportFailed := false
+	for _, batch := range batches {
+		for _, format := range formats {
+			path, err := exporter.Export(*batch, ExportRequest{BatchID: batch.ID, Format: format})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: export %s failed: %v\n", format, err)
+				exportFailed = true
+				continue
+			}
+			fmt.Fprintf(os.Stdout, "Exported %s: %s\n", strings.ToUpper(string(format)), path)
+		}
+	}
+
+	saveFailed := false
+	if !opts.noSave && !interrupted {
+		if err := saveCLIBatches(os.Stdout, batches); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			saveFailed = true
+		}
+	}
+
+	// Give Prometheus a chance to scrape the final state before exiting.
+	if opts.metricsAddr != "" && opts.metricsLinger > 0 && !interrupted {
+		fmt.Fprintf(os.Stdout, "Keeping metrics endpoint up for %v\n", opts.metricsLinger)
+		select {
+		case <-time.After(opts.metricsLinger):
+		case <-ctx.Done():
+		}
+	}
+
+	regressed := false
+	if baseline != nil && !interrupted {
+		report := CompareToBaseline(*baseline, *batches[0], opts.tolerances)
+		fmt.Fprintln(os.Stdout, "")
+		fmt.Fprint(os.Stdout, report.Report)
+		regressed = !report.Passed
+	}
+
+	if interrupted {
+		return cliExitInterrupted
+	}
+	failed := runErr != nil || exportFailed || saveFailed
+	if matrix != nil {
+		for _, target := range matrix.Targets {
+			if target.Error != "" {
+				fmt.Fprintf(os.Stderr, "error: target %s failed: %s\n", target.Name, target.Error)
+				failed = true
+			}
+		}
+	}
+	for _, batch := range batches {
+		label := ""
+		if batch.Name != "" {
+			label = batch.Name + ": "
+		}
+		switch {
+		case batch.Summary.SuccessfulTests == 0:
+			fmt.Fprintf(os.Stderr, "error: %sno request succeeded\n", label)
+			failed = true
+		case batch.Summary.ErrorRate > opts.maxErrorRate:
+			fmt.Fprintf(os.Stderr, "error: %serror rate %.2f%% exceeds allowed %.2f%%\n",
+				label, batch.Summary.ErrorRate*100, opts.maxErrorRate*100)
+			failed = true
+		}
+	}
+
+	switch {
+	case failed:
+		return cliExitFailed
+	case regressed:
+		return cliExitRegressed
+	}
+	return cliExitOK
+}
+
+// saveCLIBatches stores batches in the app history, so their IDs work with
+// -baseline and -replay and they show up in the app, then applies the
+// app's retention policy.
+func saveCLIBatches(w io.Writer, batches []*TestBatch) error {
+	dir, err := getHistoryDir()
+	if err != nil {
+		return err
+	}
+	history, err := NewHistoryService(dir)
+	if err != nil {
+		return err
+	}
+	for _, batch := range batches {
+		if err := history.Save(*batch); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Saved to history: %s\n", batch.ID)
+	}
+
+	cfg, err := loadAppConfigFromDisk()
+	if err != nil {
+		return err
+	}
+	return history.Prune(historyRetentionFor(cfg), time.Now())
+}
+
+// newRunFlagSet binds the `run` flags to config and opts. Flag defaults are
+// taken from the current values so that re-parsing after loading a config
+// file only overrides what was passed explicitly.
+func newRunFlagSet(config *TestConfiguration, opts *cliOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-speed-test run [flags]")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintf(fs.Output(), "Runs a speed test without the desktop UI. The API key may also be set via %s.\n", cliAPIKeyEnv)
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintf(fs.Output(), "Exit codes: %d ok, %d failed, %d usage error, %d regressed against -baseline, %d interrupted\n",
+			cliExitOK, cliExitFailed, cliExitUsage, cliExitRegressed, cliExitInterrupted)
+	}
+
+	fs.StringVar(&opts.configPath, "config", opts.configPath, "path to a JSON or YAML TestConfiguration file")
+	fs.StringVar(&opts.replay, "replay", opts.replay, "re-run a stored batch with its configuration and seed: JSON export, history file or history batch ID")
+	fs.StringVar(&opts.outputDir, "output", opts.outputDir, "directory for exported results")
+	fs.BoolVar(&opts.noSave, "no-save", opts.noSave, "do not save the batch to the app history, where -baseline and -replay look up batch IDs")
+	fs.StringVar(&opts.formats, "format", opts.formats, "comma-separated export formats (csv, json, png); empty to skip export")
+	fs.Float64Var(&opts.maxErrorRate, "max-error-rate", opts.maxErrorRate, "highest error rate (0-1) that still exits successfully")
+	fs.BoolVar(&opts.quiet, "quiet", opts.quiet, "only print the final summary")
+	fs.StringVar(&opts.targetsPath, "targets", opts.targetsPath, "JSON or YAML list of endpoint/model targets to run side by side (matrix run)")
+	fs.StringVar(&opts.eventsLog, "events-log", opts.eventsLog, "write every progress, result and telemetry event to this JSON Lines file")
+	fs.StringVar(&opts.metricsAddr, "metrics-addr", opts.metricsAddr, "serve Prometheus metrics on this address (e.g. :9464) while the test runs")
+	fs.DurationVar(&opts.metricsLinger, "metrics-linger", opts.metricsLinger, "keep the metrics endpoint up this long after the run")
+	fs.StringVar(&opts.baseline, "baseline", opts.baseline, "batch to check for regressions: JSON export, history file or history batch ID")
+	fs.Float64Var(&opts.tolerances.MaxTTFTIncrease, "max-ttft-increase", opts.tolerances.MaxTTFTIncrease, "allowed relative rise in median TTFT vs -baseline")
+	fs.Float64Var(&opts.tolerances.MaxLatencyIncrease, "max-latency-increase", opts.tolerances.MaxLatencyIncrease, "allowed relative rise in median total latency vs -baseline")
+	fs.Float64Var(&opts.tolerances.MaxTPOTIncrease, "max-tpot-increase", opts.tolerances.MaxTPOTIncrease, "allowed relative rise in median TPOT vs -baseline")
+	fs.Float64Var(&opts.tolerances.MaxOutputTPSDecrease, "max-tps-decrease", opts.tolerances.MaxOutputTPSDecrease, "allowed relative drop in median output tok/s vs -baseline")
+	fs.Float64Var(&opts.tolerances.MaxErrorRateIncrease, "max-error-rate-increase", opts.tolerances.MaxErrorRateIncrease, "allowed absolute rise in error rate (0-1) vs -baseline")
+	fs.Float64Var(&opts.tolerances.Significance, "significance", opts.tolerances.Significance, "p-value below which a change vs -baseline counts as real")
+
+	fs.StringVar(&config.APIEndpoint, "endpoint", config.APIEndpoint, "API endpoint (base URL for the selected provider)")
+	fs.StringVar(&config.APIKey, "api-key", config.APIKey, "API key")
+	fs.StringVar(&config.Provider, "provider", config.Provider, "API provider: openai, openai_completions, anthropic, ollama or tgi")
+	fs.StringVar(&config.Model, "model", config.Model, "model to test")
+	fs.StringVar(&config.PromptType, "prompt-type", config.PromptType, "prompt type: fixed, simple, complex, custom or dataset")
+	fs.IntVar(&config.PromptLength, "prompt-length", config.PromptLength, "prompt length in tokens")
+	fs.StringVar(&config.PrefixConfig.Mode, "prefix-mode", config.PrefixConfig.Mode, "prompt prefix mode: shared (common prefix, see -prefix-length) or unique (no shared prefix); empty for random prompts")
+	fs.IntVar(&config.PrefixConfig.Length, "prefix-length", config.PrefixConfig.Length, "tokens of the shared prefix, part of -prompt-length (with -prefix-mode shared)")
+	fs.Float64Var(&config.PrefixConfig.HitRatio, "prefix-hit-ratio", config.PrefixConfig.HitRatio, "fraction of requests reusing the shared prefix, the rest get a fresh one (with -prefix-mode shared)")
+	fs.BoolVar(&config.PrefixConfig.SystemPrompt, "prefix-system", config.PrefixConfig.SystemPrompt, "send the shared prefix as a system message")
+	fs.BoolVar(&config.ExactPromptLength, "exact-prompt-length", config.ExactPromptLength, "probe the server to size synthetic prompts to exactly -prompt-length server tokens")
+	fs.StringVar(&config.Prompt, "prompt", config.Prompt, "custom prompt text (with -prompt-type custom)")
+	fs.StringVar(&config.DatasetConfig.Path, "dataset", config.DatasetConfig.Path, "ShareGPT JSON or JSONL workload file (with -prompt-type dataset)")
+	fs.StringVar(&config.DatasetConfig.Format, "dataset-format", config.DatasetConfig.Format, "dataset format: sharegpt or jsonl (default: from file extension)")
+	fs.Int64Var(&config.DatasetConfig.Seed, "dataset-seed", config.DatasetConfig.Seed, "seed for dataset sampling, 0 to derive it from -seed")
+	fs.IntVar(&config.DatasetConfig.MinPromptTokens, "dataset-min-tokens", config.DatasetConfig.MinPromptTokens, "skip dataset records with fewer prompt tokens, 0 for no limit")
+	fs.IntVar(&config.DatasetConfig.MaxPromptTokens, "dataset-max-tokens", config.DatasetConfig.MaxPromptTokens, "skip dataset records with more prompt tokens, 0 for no limit")
+	fs.StringVar(&config.Tokenizer, "tokenizer", config.Tokenizer, "HuggingFace tokenizer.json (or its directory) for prompt building and token counting")
+	fs.IntVar(&config.MaxTokens, "max-tokens", config.MaxTokens, "maximum output tokens")
+	fs.Var((*float32Flag)(&config.Temperature), "temperature", "sampling temperature")
+	fs.Var((*float32Flag)(&config.TopP), "top-p", "nucleus sampling top_p")
+	fs.Var((*float32Flag)(&config.PresencePenalty), "presence-penalty", "presence penalty")
+	fs.Var((*float32Flag)(&config.FrequencyPenalty), "frequency-penalty", "frequency penalty")
+	fs.StringVar(&config.TestMode, "mode", config.TestMode, "test mode: normal, concurrency_step, input_step, image_count_step, image_resolution_step, sweep, rate, rate_step or conversation")
+	fs.IntVar(&config.StepConfig.Start, "step-start", config.StepConfig.Start, "first step value for step modes")
+	fs.IntVar(&config.StepConfig.End, "step-end", config.StepConfig.End, "last step value for step modes")
+	fs.IntVar(&config.StepConfig.Step, "step-size", config.StepConfig.Step, "increment between steps")
+	fs.Var((*sweepFlag)(&config.Sweep), "sweep", "sweep dimension as 'field=v1,v2,...' or 'field=start:end:step' (repeatable, sweep mode)")
+	fs.Float64Var(&config.RateConfig.RequestRate, "rate", config.RateConfig.RequestRate, "target arrival rate in requests/sec (rate mode)")
+	fs.StringVar(&config.RateConfig.ArrivalProcess, "arrival", config.RateConfig.ArrivalProcess, "arrival process: constant, poisson or burst (rate modes)")
+	fs.IntVar(&config.RateConfig.BurstSize, "burst-size", config.RateConfig.BurstSize, "requests released together with -arrival burst")
+	fs.IntVar(&config.RateConfig.NumRequests, "num-requests", config.RateConfig.NumRequests, "requests to send per rate (rate modes)")
+	fs.IntVar(&config.RateConfig.MaxInFlight, "max-in-flight", config.RateConfig.MaxInFlight, "cap on outstanding requests, 0 for unlimited (rate modes)")
+	fs.Float64Var(&config.RateConfig.RateStart, "rate-start", config.RateConfig.RateStart, "first arrival rate in requests/sec (rate_step mode)")
+	fs.Float64Var(&config.RateConfig.RateEnd, "rate-end", config.RateConfig.RateEnd, "last arrival rate in requests/sec (rate_step mode)")
+	fs.Float64Var(&config.RateConfig.RateStep, "rate-step", config.RateConfig.RateStep, "arrival rate increment between steps (rate_step mode)")
+	fs.Float64Var(&config.SLO.MaxP95TTFT, "slo-p95-ttft", config.SLO.MaxP95TTFT, "SLO: highest p95 TTFT in ms, 0 to ignore (rate_step mode)")
+	fs.Float64Var(&config.SLO.MaxP95Latency, "slo-p95-latency", config.SLO.MaxP95Latency, "SLO: highest p95 total latency in ms, 0 to ignore (rate_step mode)")
+	fs.Float64Var(&config.SLO.MaxP95TPOT, "slo-p95-tpot", config.SLO.MaxP95TPOT, "SLO: highest p95 TPOT in ms, 0 to ignore (rate_step mode)")
+	fs.Var(optionalFloat64Flag{&config.SLO.MaxErrorRate}, "slo-error-rate", "SLO: highest error rate (0-1), 0 allows no errors (rate_step mode)")
+	fs.IntVar(&config.ConversationConfig.Sessions, "sessions", config.ConversationConfig.Sessions, "conversations to run, -concurrency at a time (conversation mode)")
+	fs.IntVar(&config.ConversationConfig.Turns, "turns", config.ConversationConfig.Turns, "requests per conversation, each resending the history (conversation mode)")
+	fs.IntVar(&config.ConversationConfig.TurnLength, "turn-length", config.ConversationConfig.TurnLength, "tokens of each user message after the first, 0 for -prompt-length (conversation mode)")
+	fs.IntVar(&config.ToolConfig.Count, "tools", config.ToolConfig.Count, fmt.Sprintf("built-in tool definitions to send with every request (0-%d), 0 for none", len(builtinTools)))
+	fs.StringVar(&config.ToolConfig.Path, "tools-file", config.ToolConfig.Path, "JSON array of OpenAI tool definitions to send instead of the built-in ones")
+	fs.StringVar(&config.ToolConfig.ToolChoice, "tool-choice", config.ToolConfig.ToolChoice, "tool_choice: required, auto or a tool name to force (with -tools or -tools-file)")
+	fs.StringVar(&config.StructuredOutput.SchemaPath, "schema", config.StructuredOutput.SchemaPath, "JSON schema every other request's output is constrained to, the rest run unconstrained as a baseline")
+	fs.StringVar(&config.StructuredOutput.Method, "schema-method", config.StructuredOutput.Method, "how the schema is sent: response_format (json_schema) or guided_json (vLLM)")
+	fs.Var((*imagesFlag)(&config.VisionConfig.Images), "image", "local image file sent with requests, used in turn (repeatable; default: generated images)")
+	fs.IntVar(&config.VisionConfig.Count, "image-count", config.VisionConfig.Count, "images sent with every request, 0 for text only")
+	fs.IntVar(&config.VisionConfig.Resolution, "image-resolution", config.VisionConfig.Resolution, "longest image side in pixels, 0 to send files at their original size")
+	fs.StringVar(&config.VisionConfig.Detail, "image-detail", config.VisionConfig.Detail, "image_url detail: auto, low or high (default: server default)")
+	fs.IntVar(&config.TestCount, "rounds", config.TestCount, "number of submission rounds (per step)")
+	fs.IntVar(&config.ConcurrentTests, "concurrency", config.ConcurrentTests, "requests per round")
+	fs.IntVar(&config.Timeout, "timeout", config.Timeout, "request timeout in seconds")
+	fs.Var((*headerFlag)(&config.Headers), "header", "extra HTTP header as 'Name: value' (repeatable)")
+	fs.Int64Var(&config.Seed, "seed", config.Seed, "seed for prompts, dataset sampling and arrivals, 0 for random")
+
+	return fs
+}
+
+// float32Flag adapts a float32 TestConfiguration field to flag.Value.
+type float32Flag float32
+
+func (f *float32Flag) String() string {
+	if f == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(*f), 'g', -1, 32)
+}
+
+func (f *float32Flag) Set(value string) error {
+	v, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return err
+	}
+	*f = float32Flag(v)
+	return nil
+}
+
+// optionalFloat64Flag adapts a *float64 TestConfiguration field, which
+// stays nil unless the flag is passed, to flag.Value.
+type optionalFloat64Flag struct{ target **float64 }
+
+func (f optionalFloat64Flag) String() string {
+	if f.target == nil || *f.target == nil {
+		return ""
+	}
+	return strconv.FormatFloat(**f.target, 'g', -1, 64)
+}
+
+func (f optionalFloat64Flag) Set(value string) error {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	*f.target = &v
+	return nil
+}
+
+// sweepFlag collects repeated -sweep flags into sweep dimensions.
+type sweepFlag []SweepDimension
+
+func (f *sweepFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(*f))
+	for _, dim := range *f {
+		parts = append(parts, dim.Field)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *sweepFlag) Set(value string) error {
+	field, spec, ok := strings.Cut(value, "=")
+	field = strings.TrimSpace(field)
+	if !ok || field == "" || strings.TrimSpace(spec) == "" {
+		return fmt.Errorf("sweep must be in 'field=v1,v2' or 'field=start:end:step' form, got %q", value)
+	}
+
+	dim := SweepDimension{Field: field}
+	if bounds := strings.Split(spec, ":"); len(bounds) > 1 {
+		if len(bounds) != 3 {
+			return fmt.Errorf("sweep range must be start:end:step, got %q", spec)
+		}
+		var numbers [3]float64
+		for i, bound := range bounds {
+			v, err := strconv.ParseFloat(strings.TrimSpace(bound), 64)
+			if err != nil {
+				return fmt.Errorf("invalid sweep value %q", bound)
+			}
+			numbers[i] = v
+		}
+		dim.Start, dim.End, dim.Step = numbers[0], numbers[1], numbers[2]
+	} else {
+		for _, part := range strings.Split(spec, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return fmt.Errorf("invalid sweep value %q", part)
+			}
+			dim.Values = append(dim.Values, v)
+		}
+	}
+
+	*f = append(*f, dim)
+	return nil
+}
+
+// imagesFlag collects repeated -image flags into the vision image files.
+type imagesFlag []string
+
+func (f *imagesFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(*f, ", ")
+}
+
+func (f *imagesFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// headerFlag collects repeated -header flags into the configuration headers.
+type headerFlag map[string]string
+
+func (h *headerFlag) String() string {
+	if h == nil || *h == nil {
+		return ""
+	}
+	parts := make([]string, 0, len(*h))
+	for key, value := range *h {
+		parts = append(parts, key+": "+value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (h *headerFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("header must be in 'Name: value' form, got %q", value)
+	}
+	if *h == nil {
+		*h = make(map[string]string)
+	}
+	(*h)[name] = strings.TrimSpace(val)
+	return nil
+}
+
+// loadCLIConfigFile reads a TestConfiguration from a JSON or YAML file.
+// Missing fields keep their defaults. YAML uses the same keys as the JSON
+// representation (e.g. apiEndpoint, maxTokens, stepConfig).
+func loadCLIConfigFile(path string) (*TestConfiguration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if data, err = yamlToJSON(path, data); err != nil {
+		return nil, err
+	}
+
+	config := defaultTestConfiguration()
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	return &config, nil
+}
+
+// loadMatrixTargets reads the -targets file: a JSON or YAML list of
+// targets with name, apiEndpoint, apiKey, provider and model keys.
+func loadMatrixTargets(path string) ([]MatrixTarget, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read targets file: %w", err)
+	}
+	if data, err = yamlToJSON(path, data); err != nil {
+		return nil, err
+	}
+
+	var targets []MatrixTarget
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to parse targets file: %w", err)
+	}
+	return targets, nil
+}
+
+// yamlToJSON converts data to JSON when path has a YAML extension and
+// returns it unchanged otherwise. Round-tripping through JSON keeps the
+// json struct tags the single source of truth for field names.
+func yamlToJSON(path string, data []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var raw interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML %s: %w", filepath.Base(path), err)
+		}
+		converted, err := json.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert YAML %s: %w", filepath.Base(path), err)
+		}
+		return converted, nil
+	}
+	return data, nil
+}
+
+// validateCLIConfig rejects configurations that RunSpeedTest would only
+// fail on after the warm-up request.
+func validateCLIConfig(config TestConfiguration) error {
+	if config.APIEndpoint == "" {
+		return fmt.Errorf("API endpoint is required (-endpoint)")
+	}
+	if config.Model == "" {
+		return fmt.Errorf("model is required (-model)")
+	}
+	if _, err := NewBackend(config.Provider, config.APIEndpoint, config.APIKey, config.Timeout); err != nil {
+		return err
+	}
+
+	if config.PromptType == "custom" {
+		if config.Prompt == "" {
+			return fmt.Errorf("custom prompt type requires -prompt")
+		}
+	} else if config.PromptType == "dataset" {
+		if config.DatasetConfig.Path == "" {
+			return fmt.Errorf("dataset prompt type requires -dataset")
+		}
+		if config.TestMode == "input_step" {
+			return fmt.Errorf("input_step mode cannot be used with dataset prompts")
+		}
+	} else if err := ValidatePromptConfig(config.PromptType, config.PromptLength); err != nil {
+		return err
+	}
+
+	if len(config.Sweep) > 0 && config.TestMode != "sweep" {
+		return fmt.Errorf("-sweep requires -mode sweep")
+	}
+	promptLength := config.PromptLength
+	if config.TestMode == "input_step" {
+		promptLength = config.StepConfig.Start
+	}
+	if err := validatePrefixConfig(config.PrefixConfig, config.PromptType, promptLength); err != nil {
+		return err
+	}
+	if config.ExactPromptLength && !isSyntheticPromptType(config.PromptType) {
+		return fmt.Errorf("-exact-prompt-length cannot be used with %s prompts", config.PromptType)
+	}
+	if config.Tokenizer != "" {
+		if _, err := loadHFTokenizer(config.Tokenizer); err != nil {
+			return err
+		}
+	}
+	if config.ToolConfig.Count < 0 {
+		return fmt.Errorf("-tools must not be negative, got %d", config.ToolConfig.Count)
+	}
+	if _, err := loadTools(config); err != nil {
+		return err
+	}
+	if _, err := loadStructuredOutput(config); err != nil {
+		return err
+	}
+
+	// Image settings are checked for every point, since image steps and
+	// sweeps can change them.
+	visionSteps := []TestConfiguration{config}
+	switch config.TestMode {
+	case "", "normal", "concurrency_step", "input_step":
+	case "sweep", "image_count_step", "image_resolution_step":
+		dims, err := sweepDimensionsFor(config)
+		if err != nil {
+			return err
+		}
+		points, err := buildSweepPoints(config, dims)
+		if err != nil {
+			return err
+		}
+		visionSteps = visionSteps[:0]
+		for _, point := range points {
+			visionSteps = append(visionSteps, point.config)
+		}
+	case "rate":
+		if config.RateConfig.RequestRate <= 0 {
+			return fmt.Errorf("rate mode requires a positive -rate")
+		}
+		if err := validateRateConfig(config.RateConfig); err != nil {
+			return err
+		}
+	case "rate_step":
+		if err := validateRateSweepConfig(config.RateConfig, config.SLO); err != nil {
+			return err
+		}
+	case "conversation":
+		if err := validateConversationConfig(config); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown test mode: %s", config.TestMode)
+	}
+	if _, err := newVisionWorkload(config, visionSteps); err != nil {
+		return err
+	}
+
+	if config.TestCount <= 0 {
+		return fmt.Errorf("rounds must be positive, got %d", config.TestCount)
+	}
+	if config.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive, got %d", config.Timeout)
+	}
+
+	return nil
+}
+
+// parseExportFormats turns the -format flag into a list of export formats.
+func parseExportFormats(value string) ([]ExportFormat, error) {
+	var formats []ExportFormat
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		switch format := ExportFormat(part); format {
+		case ExportFormatCSV, ExportFormatJSON, ExportFormatPNG:
+			formats = append(formats, format)
+		default:
+			return nil, fmt.Errorf("unsupported export format: %s", part)
+		}
+	}
+	return formats, nil
+}
+
+func describeTestMode(mode string) string {
+	switch mode {
+	case "concurrency_step":
+		return "concurrency step test"
+	case "input_step":
+		return "input length step test"
+	case "image_count_step":
+		return "image count step test"
+	case "image_resolution_step":
+		return "image resolution step test"
+	case "sweep":
+		return "parameter sweep"
+	case "rate":
+		return "open-loop rate test"
+	case "rate_step":
+		return "request rate sweep"
+	case "conversation":
+		return "conversation test"
+	default:
+		return "speed test"
+	}
+}
+
+// streamCLIUpdates prints per-request results and periodic telemetry from
+// sub until the subscription ends. Lines of batches listed in labels are
+// prefixed with their label.
+func streamCLIUpdates(w io.Writer, sub *Subscription, labels map[string]string, quiet bool) {
+	lastTelemetry := make(map[string]time.Time)
+
+	for event := range sub.Events() {
+		if quiet {
+			continue
+		}
+
+		prefix := ""
+		if label := labels[event.BatchID]; label != "" {
+			prefix = "[" + label + "] "
+		}
+
+		switch {
+		case event.Result != nil:
+			result := event.Result
+			if result.Success {
+				fmt.Fprintf(w, "  %s#%-4d ok    ttft=%8.1fms total=%8.1fms output=%7.1f tok/s tokens=%d/%d\n",
+					prefix, result.TestNumber, result.RequestLatency, result.TotalLatency,
+					result.OutputTokensPerSecond, result.PromptTokens, result.CompletionTokens)
+				continue
+			}
+			fmt.Fprintf(w, "  %s#%-4d FAIL  %s\n", prefix, result.TestNumber, result.Error)
+
+		case event.Telemetry != nil:
+			update := event.Telemetry
+			if time.Since(lastTelemetry[event.BatchID]) < cliTelemetryInterval {
+				continue
+			}
+			lastTelemetry[event.BatchID] = time.Now()
+
+			step := ""
+			if update.StepTotal > 1 {
+				step = fmt.Sprintf("step %d/%d ", update.StepCurrent, update.StepTotal)
+			}
+			fmt.Fprintf(w, "%s[%s%d/%d] active=%d tps=%.1f ttft avg=%.1fms p95=%.1fms\n",
+				prefix, step, update.CompletedTests, update.TotalTests, update.ActiveTests,
+				update.InstantTPS, update.AverageTTFT, update.P95TTFT)
+		}
+		// Per-request results already carry the outcome, so progress
+		// events are not printed.
+	}
+}
+
+// printCLISummary writes the headline numbers of a finished batch.
+func printCLISummary(w io.Writer, batch *TestBatch) {
+	s := batch.Summary
+	fmt.Fprintln(w, "")
+	if batch.Name != "" {
+		fmt.Fprintf(w, "Batch %s (%s)\n", batch.ID, batch.Name)
+	} else {
+		fmt.Fprintf(w, "Batch %s\n", batch.ID)
+	}
+	if batch.ReplayOf != "" {
+		fmt.Fprintf(w, "  Replay of:       %s (seed %d)\n", batch.ReplayOf, batch.Seed)
+	} else if batch.Seed != 0 {
+		fmt.Fprintf(w, "  Seed:            %d\n", batch.Seed)
+	}
+	fmt.Fprintf(w, "  Requests:        %d total, %d ok, %d failed (error rate %.2f%%)\n",
+		s.TotalTests, s.SuccessfulTests, s.FailedTests, s.ErrorRate*100)
+	fmt.Fprintf(w, "  TTFT (ms):       avg %.1f  min %.1f  max %.1f  p50 %.1f  p95 %.1f  p99 %.1f\n",
+		s.AveragePrefillLatency, s.MinPrefillLatency, s.MaxPrefillLatency,
+		s.PrefillLatencyDistribution.P50, s.PrefillLatencyDistribution.P95, s.PrefillLatencyDistribution.P99)
+	fmt.Fprintf(w, "  Latency (ms):    avg %.1f  min %.1f  max %.1f  p50 %.1f  p95 %.1f  p99 %.1f\n",
+		s.AverageLatency, s.MinLatency, s.MaxLatency,
+		s.LatencyDistribution.P50, s.LatencyDistribution.P95, s.LatencyDistribution.P99)
+	fmt.Fprintf(w, "  Output tok/s:    avg %.1f  min %.1f  max %.1f  p50 %.1f  p95 %.1f  p99 %.1f\n",
+		s.AverageOutputTokensPerSecond, s.MinOutputTokensPerSecond, s.MaxOutputTokensPerSecond,
+		s.OutputTokensPerSecondDistribution.P50, s.OutputTokensPerSecondDistribution.P95, s.OutputTokensPerSecondDistribution.P99)
+	fmt.Fprintf(w, "  TPOT (ms):       avg %.2f  p50 %.2f  p99 %.2f\n",
+		s.AverageTimePerOutputToken, s.TimePerOutputTokenDistribution.P50, s.TimePerOutputTokenDistribution.P99)
+	fmt.Fprintf(w, "  ITL (ms):        avg %.2f  p50 %.2f  p99 %.2f  max stall %.1f\n",
+		s.AverageInterTokenLatency, s.InterTokenLatencyDistribution.P50, s.InterTokenLatencyDistribution.P99, s.MaxStall)
+	if mode := batch.Configuration.TestMode; mode == "rate" || mode == "rate_step" {
+		fmt.Fprintf(w, "  Queue delay (ms): avg %.1f  p95 %.1f  p99 %.1f\n",
+			s.AverageQueueDelay, s.QueueDelayDistribution.P95, s.QueueDelayDistribution.P99)
+	}
+	fmt.Fprintf(w, "  Round tok/s:     avg %.1f  min %.1f  max %.1f\n",
+		s.AverageRoundThroughput, s.MinRoundThroughput, s.MaxRoundThroughput)
+	if s.UsageDeviation != 0 {
+		fmt.Fprintf(w, "  Usage check:     reported completion tokens %+.1f%% vs local tokenizer\n", s.UsageDeviation*100)
+	}
+	if pc := s.PrefixCache; pc != nil {
+		line := fmt.Sprintf("  Prefix cache:    warm TTFT p50 %.1f (%d req)  cold TTFT p50 %.1f (%d req)",
+			pc.WarmTTFTDistribution.P50, pc.WarmRequests, pc.ColdTTFTDistribution.P50, pc.ColdRequests)
+		if pc.TTFTSpeedup > 0 {
+			line += fmt.Sprintf("  speedup %.2fx", pc.TTFTSpeedup)
+		}
+		fmt.Fprintln(w, line)
+	}
+	if tc := s.ToolCalls; tc != nil {
+		fmt.Fprintf(w, "  Tool calls:      %d/%d req  first call p50 %.1f ms  args tok/s p50 %.1f  malformed %d (%.2f%%)  missing %d\n",
+			tc.RequestsWithCalls, tc.Requests, tc.TimeToFirstToolCallDistribution.P50, tc.ToolCallTokensPerSecondDistribution.P50,
+			tc.MalformedCalls, tc.MalformedRate*100, tc.MissingCalls)
+	}
+	if so := s.StructuredOutput; so != nil {
+		fmt.Fprintf(w, "  Structured:      tok/s p50 %.1f constrained vs %.1f baseline (overhead %.1f%%)  TTFT p50 %.1f vs %.1f  compliant %d/%d (%.2f%%)\n",
+			so.ConstrainedTPSDistribution.P50, so.BaselineTPSDistribution.P50, so.DecodeOverhead*100,
+			so.ConstrainedTTFTDistribution.P50, so.BaselineTTFTDistribution.P50,
+			so.CompliantRequests, so.ConstrainedRequests, so.ComplianceRate*100)
+	}
+	if rs := s.Reasoning; rs != nil {
+		fmt.Fprintf(w, "  Reasoning:       %d req  avg %.0f reasoning + %.0f answer tokens  first reasoning p50 %.1f ms  first answer p50 %.1f ms\n",
+			rs.Requests, rs.AverageReasoningTokens, rs.AverageAnswerTokens,
+			rs.TimeToFirstReasoningTokenDistribution.P50, rs.TimeToFirstAnswerTokenDistribution.P50)
+		fmt.Fprintf(w, "  Reasoning tok/s: avg %.1f  p50 %.1f   Answer tok/s: avg %.1f  p50 %.1f\n",
+			rs.AverageReasoningTokensPerSecond, rs.ReasoningTokensPerSecondDistribution.P50,
+			rs.AverageAnswerTokensPerSecond, rs.AnswerTokensPerSecondDistribution.P50)
+	}
+	if vs := s.Vision; vs != nil {
+		fmt.Fprintf(w, "  Vision:          %d req  avg %.1f images  avg %.0f image tokens (%.1f%% of prompt)  TTFT p50 %.1f ms  p95 %.1f ms\n",
+			vs.Requests, vs.AverageImages, vs.AverageImageTokens, vs.ImageTokenShare*100,
+			vs.TTFTDistribution.P50, vs.TTFTDistribution.P95)
+	}
+	if calibration := batch.PromptCalibration; calibration != nil {
+		parts := make([]string, 0, len(calibration.Lengths))
+		for _, length := range calibration.Lengths {
+			parts = append(parts, fmt.Sprintf("%d->%d (server %d)", length.TargetTokens, length.LocalTokens, length.ServerTokens))
+		}
+		fmt.Fprintf(w, "  Prompt lengths:  template overhead %d tokens; %s\n", calibration.TemplateOverhead, strings.Join(parts, ", "))
+	}
+
+	if sweep := batch.RateSweep; sweep != nil {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Rate sweep")
+		fmt.Fprintf(w, "  %8s %9s %8s %10s %10s %10s %10s  %s\n",
+			"req/s", "achieved", "errors", "p95 ttft", "p95 lat", "p95 tpot", "out tok/s", "slo")
+		for _, step := range sweep.Steps {
+			status := "ok"
+			if !step.MeetsSLO {
+				status = "FAIL " + strings.Join(step.Violations, "; ")
+			}
+			fmt.Fprintf(w, "  %8.2f %9.2f %7.2f%% %10.1f %10.1f %10.2f %10.1f  %s\n",
+				step.RequestRate, step.AchievedRate, step.ErrorRate*100,
+				step.P95TTFT, step.P95Latency, step.P95TPOT, step.TotalOutputTokensPerSecond, status)
+		}
+		if sweep.Goodput > 0 {
+			fmt.Fprintf(w, "  Goodput:         %.2f req/s\n", sweep.Goodput)
+		} else {
+			fmt.Fprintln(w, "  Goodput:         none (the first rate already broke the SLO)")
+		}
+	}
+
+	if len(batch.SweepPoints) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Sweep")
+		fmt.Fprintf(w, "  %-32s %6s %8s %10s %10s %10s %10s %10s\n",
+			"point", "ok", "errors", "p50 ttft", "p95 lat", "p50 tpot", "req tok/s", "total tok/s")
+		for _, point := range batch.SweepPoints {
+			ps := point.Summary
+			fmt.Fprintf(w, "  %-32s %6d %7.2f%% %10.1f %10.1f %10.2f %10.1f %10.1f\n",
+				formatSweepCoordinates(point.Coordinates), ps.SuccessfulTests, ps.ErrorRate*100,
+				ps.PrefillLatencyDistribution.P50, ps.LatencyDistribution.P95, ps.TimePerOutputTokenDistribution.P50,
+				ps.AverageOutputTokensPerSecond, point.TotalOutputTokensPerSecond)
+		}
+	}
+
+	if batch.Summary.Vision != nil && len(batch.SweepPoints) > 0 {
+		// How prefill grows with the images of each point.
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Vision")
+		fmt.Fprintf(w, "  %-32s %8s %10s %10s %10s %10s\n",
+			"point", "images", "img tokens", "img share", "p50 ttft", "p95 ttft")
+		for _, point := range batch.SweepPoints {
+			ps := point.Summary
+			var images, imageTokens, share float64
+			if vs := ps.Vision; vs != nil {
+				images, imageTokens, share = vs.AverageImages, vs.AverageImageTokens, vs.ImageTokenShare
+			}
+			fmt.Fprintf(w, "  %-32s %8.1f %10.0f %9.1f%% %10.1f %10.1f\n",
+				formatSweepCoordinates(point.Coordinates), images, imageTokens, share*100,
+				ps.PrefillLatencyDistribution.P50, ps.PrefillLatencyDistribution.P95)
+		}
+	}
+
+	if len(batch.Turns) > 0 {
+		fmt.Fprintln(w, "")
+		fmt.Fprintln(w, "Turns")
+		fmt.Fprintf(w, "  %6s %10s %6s %8s %10s %10s %10s %10s\n",
+			"turn", "context", "ok", "errors", "p50 ttft", "p95 ttft", "p50 tpot", "out tok/s")
+		for _, turn := range batch.Turns {
+			ts := turn.Summary
+			fmt.Fprintf(w, "  %6d %10.0f %6d %7.2f%% %10.1f %10.1f %10.2f %10.1f\n",
+				turn.Turn, turn.AveragePromptTokens, ts.SuccessfulTests, ts.ErrorRate*100,
+				ts.PrefillLatencyDistribution.P50, ts.PrefillLatencyDistribution.P95,
+				ts.TimePerOutputTokenDistribution.P50, ts.AverageOutputTokensPerSecond)
+		}
+	}
+}
+
+// printMatrixSummary prints one line per matrix target and which target
+// was best on each compared metric.
+func printMatrixSummary(w io.Writer, matrix *ComparisonResult) {
+	fmt.Fprintln(w, "")
+	fmt.Fprintf(w, "Matrix %s\n", matrix.MatrixID)
+	fmt.Fprintf(w, "  %-28s %8s %10s %10s %10s %10s  %s\n",
+		"target", "errors", "p50 ttft", "p95 ttft", "p95 lat", "out tok/s", "status")
+
+	names := make(map[string]string)
+	for _, target := range matrix.Targets {
+		names[target.BatchID] = target.Name
+		if target.Error != "" {
+			fmt.Fprintf(w, "  %-28s %8s %10s %10s %10s %10s  FAIL %s\n",
+				target.Name, "-", "-", "-", "-", "-", target.Error)
+			continue
+		}
+		s := target.Summary
+		fmt.Fprintf(w, "  %-28s %7.2f%% %10.1f %10.1f %10.1f %10.1f  ok\n",
+			target.Name, s.ErrorRate*100, s.PrefillLatencyDistribution.P50, s.PrefillLatencyDistribution.P95,
+			s.LatencyDistribution.P95, s.AverageOutputTokensPerSecond)
+	}
+
+	if len(matrix.Batches) < 2 {
+		return
+	}
+	c := matrix.Comparison
+	fmt.Fprintf(w, "  Best latency:    %s\n", names[c.BestLatencyBatchID])
+	fmt.Fprintf(w, "  Best p95 TTFT:   %s\n", names[c.BestP95TTFTBatchID])
+	fmt.Fprintf(w, "  Best throughput: %s\n", names[c.BestThroughputBatchID])
+	fmt.Fprintf(w, "  Lowest errors:   %s\n", names[c.LowestErrorRateBatchID])
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateCLIConfig(t *testing.T) {
+	cases := []struct {
+		name      string
+		configure func(*TestConfiguration)
+		wantErr   string // empty for a valid configuration
+	}{
+		{"valid", func(c *TestConfiguration) {}, ""},
+		{"missing endpoint", func(c *TestConfiguration) { c.APIEndpoint = "" }, "API endpoint is required"},
+		{"missing model", func(c *TestConfiguration) { c.Model = "" }, "model is required"},
+		{"unknown provider", func(c *TestConfiguration) { c.Provider = "bogus" }, "unsupported provider"},
+		{"custom without prompt", func(c *TestConfiguration) { c.Prompt = "" }, "requires -prompt"},
+		{"dataset without path", func(c *TestConfiguration) { c.PromptType = "dataset" }, "requires -dataset"},
+		{"sweep without sweep mode", func(c *TestConfiguration) {
+			c.Sweep = []SweepDimension{{}}
+		}, "-sweep requires -mode sweep"},
+		{"exact length with custom prompt", func(c *TestConfiguration) { c.ExactPromptLength = true }, "-exact-prompt-length cannot be used"},
+		{"negative tools", func(c *TestConfiguration) { c.ToolConfig.Count = -1 }, "-tools must not be negative"},
+		{"rate mode without rate", func(c *TestConfiguration) { c.TestMode = "rate" }, "requires a positive -rate"},
+		{"unknown mode", func(c *TestConfiguration) { c.TestMode = "bogus" }, "unknown test mode"},
+		{"zero rounds", func(c *TestConfiguration) { c.TestCount = 0 }, "rounds must be positive"},
+		{"zero timeout", func(c *TestConfiguration) { c.Timeout = 0 }, "timeout must be positive"},
+	}
+
+	for _, tc := range cases {
+		config := mockTestConfiguration("http://127.0.0.1:1/v1")
+		tc.configure(&config)
+
+		err := validateCLIConfig(config)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+// isolateCLIHistory points the user config dir at a temporary directory so
+// runCLI never reads or writes the real app history.
+func isolateCLIHistory(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CONFIG_HOME", root)
+}
+
+func TestCLIFlagsOverrideConfigFile(t *testing.T) {
+	isolateCLIHistory(t)
+	server := httptest.NewServer(NewMockServer(MockServerConfig{
+		APIKey:          "test-key",
+		TTFT:            5 * time.Millisecond,
+		TokensPerSecond: 2000,
+		ReportUsage:     true,
+		Seed:            1,
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	configFile := "apiEndpoint: " + server.URL + "/v1\n" +
+		"apiKey: test-key\n" +
+		"model: mock-model\n" +
+		"promptType: custom\n" +
+		"prompt: Say something.\n" +
+		"maxTokens: 8\n" +
+		"concurrentTests: 2\n" +
+		"testCount: 1\n" +
+		"timeout: 10\n"
+	if err := os.WriteFile(configPath, []byte(configFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputDir := filepath.Join(dir, "exports")
+	code := runCLI([]string{
+		"-config", configPath,
+		"-max-tokens", "4",
+		"-rounds", "2",
+		"-format", "json",
+		"-output", outputDir,
+		"-no-save",
+		"-quiet",
+	})
+	if code != cliExitOK {
+		t.Fatalf("expected exit code %d, got %d", cliExitOK, code)
+	}
+
+	exports, err := filepath.Glob(filepath.Join(outputDir, "*.json"))
+	if err != nil || len(exports) != 1 {
+		t.Fatalf("expected one JSON export, got %v (%v)", exports, err)
+	}
+	batch, err := loadStoredBatch(exports[0], "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := batch.Configuration
+	if config.MaxTokens != 4 || config.TestCount != 2 {
+		t.Fatalf("expected flags to override the file, got maxTokens=%d testCount=%d", config.MaxTokens, config.TestCount)
+	}
+	if config.Model != "mock-model" || config.ConcurrentTests != 2 {
+		t.Fatalf("expected unflagged file values to be kept, got model=%q concurrentTests=%d", config.Model, config.ConcurrentTests)
+	}
+	if len(batch.Results) != 4 {
+		t.Fatalf("expected 2 rounds of 2 requests, got %d results", len(batch.Results))
+	}
+}
+
+func TestCLIExitCodes(t *testing.T) {
+	isolateCLIHistory(t)
+	dir := t.TempDir()
+
+	// Baselines far faster and far slower than the mock, so the run
+	// regresses against one and improves on the other.
+	writeBaseline := func(name string, batch TestBatch) string {
+		data, err := json.Marshal(map[string]interface{}{"batch": batch})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	fastBaseline := writeBaseline("fast", regressionBatch("fast", 5, 100000, 0))
+	slowBaseline := writeBaseline("slow", regressionBatch("slow", 5000, 1, 0))
+
+	cases := []struct {
+		name string
+		mock MockServerConfig
+		args []string
+		want int
+	}{
+		{
+			name: "clean run",
+			want: cliExitOK,
+		},
+		{
+			name: "improved on baseline",
+			args: []string{"-baseline", slowBaseline},
+			want: cliExitOK,
+		},
+		{
+			name: "regressed against baseline",
+			args: []string{"-baseline", fastBaseline},
+			want: cliExitRegressed,
+		},
+		{
+			name: "missing baseline",
+			args: []string{"-baseline", filepath.Join(dir, "missing.json")},
+			want: cliExitUsage,
+		},
+		{
+			name: "unknown flag",
+			args: []string{"-bogus"},
+			want: cliExitUsage,
+		},
+		{
+			name: "every request fails",
+			mock: MockServerConfig{ErrorRate: 1},
+			want: cliExitFailed,
+		},
+	}
+
+	for _, tc := range cases {
+		mock := tc.mock
+		mock.APIKey = "test-key"
+		mock.TTFT = 80 * time.Millisecond
+		mock.TokensPerSecond = 200
+		mock.ReportUsage = true
+		mock.Seed = 1
+		server := httptest.NewServer(NewMockServer(mock))
+
+		args := []string{
+			"-endpoint", server.URL + "/v1",
+			"-api-key", "test-key",
+			"-model", "mock-model",
+			"-prompt-type", "custom",
+			"-prompt", "Say something.",
+			"-max-tokens", "8",
+			"-concurrency", "4",
+			"-rounds", "2",
+			"-format", "",
+			"-no-save",
+			"-quiet",
+		}
+		code := runCLI(append(args, tc.args...))
+		server.Close()
+
+		if code != tc.want {
+			t.Errorf("%s: expected exit code %d, got %d", tc.name, tc.want, code)
+		}
+	}
+}
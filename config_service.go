@@ -12,9 +12,9 @@ const (
 	appConfigFileName = "config.json"
 )
 
-// getConfigFilePath returns the full path to the app config file,
-// creating the parent directory if needed.
-func getConfigFilePath() (string, error) {
+// getAppConfigDir returns the per-user application directory,
+// creating it if needed.
+func getAppConfigDir() (string, error) {
 	configRoot, err := os.UserConfigDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to resolve user config dir: %w", err)
@@ -25,6 +25,17 @@ func getConfigFilePath() (string, error) {
 		return "", fmt.Errorf("failed to create app config dir: %w", err)
 	}
 
+	return appDir, nil
+}
+
+// getConfigFilePath returns the full path to the app config file,
+// creating the parent directory if needed.
+func getConfigFilePath() (string, error) {
+	appDir, err := getAppConfigDir()
+	if err != nil {
+		return "", err
+	}
+
 	return filepath.Join(appDir, appConfigFileName), nil
 }
 
@@ -87,7 +98,15 @@ func (a *App) GetAppConfig() (*AppConfig, error) {
 }
 
 // SaveAppConfig persists the given configuration to disk.
+// Sections owned by the backend (the history retention policy, the
+// tokenizer mappings and the metrics address) are carried over from disk
+// because the frontend does not send them.
 func (a *App) SaveAppConfig(cfg AppConfig) error {
+	if existing, err := loadAppConfigFromDisk(); err == nil {
+		cfg.HistoryRetention = existing.HistoryRetention
+		cfg.Tokenizers = existing.Tokenizers
+		cfg.MetricsAddress = existing.MetricsAddress
+	}
 	return saveAppConfigToDisk(&cfg)
 }
 
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestSaveAppConfigKeepsBackendSettings(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CONFIG_HOME", root)
+
+	stored := &AppConfig{
+		HistoryRetention: &HistoryRetentionPolicy{MaxBatches: 10},
+		MetricsAddress:   ":9464",
+		Tokenizers:       []TokenizerMapping{{Model: "llama", Path: "/models/llama/tokenizer.json"}},
+	}
+	if err := saveAppConfigToDisk(stored); err != nil {
+		t.Fatal(err)
+	}
+
+	// The frontend saves its settings without the backend-owned sections.
+	if err := (&App{}).SaveAppConfig(AppConfig{}); err != nil {
+		t.Fatalf("SaveAppConfig: %v", err)
+	}
+
+	cfg, err := loadAppConfigFromDisk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MetricsAddress != ":9464" {
+		t.Fatalf("expected the metrics address to survive a settings save, got %q", cfg.MetricsAddress)
+	}
+	if cfg.HistoryRetention == nil || cfg.HistoryRetention.MaxBatches != 10 || len(cfg.Tokenizers) != 1 {
+		t.Fatalf("expected the retention policy and tokenizers to survive a settings save, got %+v", cfg)
+	}
+}
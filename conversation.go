@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// validateConversationConfig checks a conversation test. Every turn after
+// the first sends the whole history, so workloads that size or shape a
+// single prompt do not apply.
+func validateConversationConfig(config TestConfiguration) error {
+	cc := config.ConversationConfig
+	if cc.Sessions <= 0 {
+		return fmt.Errorf("invalid conversation configuration: sessions must be positive, got %d", cc.Sessions)
+	}
+	if cc.Turns <= 0 {
+		return fmt.Errorf("invalid conversation configuration: turns must be positive, got %d", cc.Turns)
+	}
+	if cc.TurnLength < 0 {
+		return fmt.Errorf("invalid conversation configuration: turnLength must not be negative, got %d", cc.TurnLength)
+	}
+	if config.PromptType == "dataset" {
+		return fmt.Errorf("conversation mode cannot be used with dataset prompts")
+	}
+	if config.PrefixConfig.Mode != "" {
+		return fmt.Errorf("conversation mode cannot be combined with prefix mode %s", config.PrefixConfig.Mode)
+	}
+	if config.ExactPromptLength {
+		return fmt.Errorf("exactPromptLength is not supported in conversation mode")
+	}
+	return nil
+}
+
+// conversation is the history of one session of a conversation test.
+// Turns of a session run one after another, so it needs no locking.
+type conversation struct {
+	session  int
+	turn     int // Turns sent so far
+	messages []Message
+}
+
+// turnLength returns the prompt tokens of the user message of turn (1-based).
+func (cc ConversationConfiguration) turnLength(turn, promptLength int) int {
+	if turn > 1 && cc.TurnLength > 0 {
+		return cc.TurnLength
+	}
+	return promptLength
+}
+
+// summarizeTurns summarizes the results of each turn over all sessions, in
+// turn order. The average prompt tokens show how much context each turn
+// carried.
+func (s *SpeedTestService) summarizeTurns(results []TestResult) []TurnSummary {
+	byTurn := make(map[int][]TestResult)
+	for _, result := range results {
+		if result.Turn > 0 {
+			byTurn[result.Turn] = append(byTurn[result.Turn], result)
+		}
+	}
+
+	turns := make([]int, 0, len(byTurn))
+	for turn := range byTurn {
+		turns = append(turns, turn)
+	}
+	sort.Ints(turns)
+
+	summaries := make([]TurnSummary, 0, len(turns))
+	for _, turn := range turns {
+		turnResults := byTurn[turn]
+		var promptTokens []float64
+		for _, result := range turnResults {
+			if result.Success {
+				promptTokens = append(promptTokens, float64(result.PromptTokens))
+			}
+		}
+		summaries = append(summaries, TurnSummary{
+			Turn:                turn,
+			AveragePromptTokens: mean(promptTokens),
+			Summary:             s.calculateSummary(turnResults),
+		})
+	}
+	return summaries
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateConversationConfig(t *testing.T) {
+	config := defaultTestConfiguration()
+	config.TestMode = "conversation"
+	if err := validateConversationConfig(config); err != nil {
+		t.Fatalf("default configuration rejected: %v", err)
+	}
+
+	config.ConversationConfig.Turns = 0
+	if err := validateConversationConfig(config); err == nil || !strings.Contains(err.Error(), "turns") {
+		t.Fatalf("expected a turns error, got %v", err)
+	}
+
+	config = defaultTestConfiguration()
+	config.PrefixConfig.Mode = prefixModeShared
+	if err := validateConversationConfig(config); err == nil {
+		t.Fatalf("expected prefix mode to be rejected")
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Dataset formats accepted by DatasetConfiguration.Format.
+const (
+	datasetFormatShareGPT = "sharegpt"
+	datasetFormatJSONL    = "jsonl"
+)
+
+// DatasetRecord is one replayable request from a workload file.
+type DatasetRecord struct {
+	Messages     []Message
+	PromptTokens int // Tokens across all message contents (tokenizer count or estimate)
+	OutputTokens int // Expected completion length; 0 falls back to the configured max tokens
+}
+
+// Dataset is a filtered, shuffled set of records. Records are assigned to
+// requests by test number, so a given seed always sends the same prompt
+// for the same request regardless of completion order.
+type Dataset struct {
+	records []DatasetRecord
+}
+
+// LoadDataset reads, filters and shuffles the workload described by config.
+// Prompt lengths are counted with the same tokenizer as PromptGenerator.
+func LoadDataset(config DatasetConfiguration, model string) (*Dataset, error) {
+	return loadDataset(config, newTokenCounter(model))
+}
+
+func loadDataset(config DatasetConfiguration, counter *tokenCounter) (*Dataset, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("dataset path is required")
+	}
+	if config.MinPromptTokens < 0 || config.MaxPromptTokens < 0 {
+		return nil, fmt.Errorf("invalid dataset configuration: token limits cannot be negative")
+	}
+	if config.MaxPromptTokens > 0 && config.MaxPromptTokens < config.MinPromptTokens {
+		return nil, fmt.Errorf("invalid dataset configuration: maxPromptTokens (%d) is below minPromptTokens (%d)",
+			config.MaxPromptTokens, config.MinPromptTokens)
+	}
+
+	file, err := os.Open(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dataset: %w", err)
+	}
+	defer file.Close()
+
+	format := strings.ToLower(config.Format)
+	if format == "" {
+		format = datasetFormatShareGPT
+		if ext := strings.ToLower(filepath.Ext(config.Path)); ext == ".jsonl" || ext == ".ndjson" {
+			format = datasetFormatJSONL
+		}
+	}
+
+	var records []DatasetRecord
+	switch format {
+	case datasetFormatShareGPT:
+		records, err = parseShareGPT(file, counter.count)
+	case datasetFormatJSONL:
+		records, err = parseDatasetJSONL(file, counter.count)
+	default:
+		return nil, fmt.Errorf("unsupported dataset format: %s", config.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := records[:0]
+	for _, record := range records {
+		if config.MinPromptTokens > 0 && record.PromptTokens < config.MinPromptTokens {
+			continue
+		}
+		if config.MaxPromptTokens > 0 && record.PromptTokens > config.MaxPromptTokens {
+			continue
+		}
+		filtered = append(filtered, record)
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("dataset %s has no usable records after filtering (%d parsed)", config.Path, len(records))
+	}
+
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(filtered), func(i, j int) {
+		filtered[i], filtered[j] = filtered[j], filtered[i]
+	})
+
+	return &Dataset{records: filtered}, nil
+}
+
+// Len returns the number of records left after filtering.
+func (d *Dataset) Len() int {
+	return len(d.records)
+}
+
+// RecordFor returns the record replayed by the given 1-based test number.
+// The dataset wraps around when a run sends more requests than it holds;
+// the warm-up request (test number 0) reuses the first record.
+func (d *Dataset) RecordFor(testNumber int) DatasetRecord {
+	index := 0
+	if testNumber > 0 {
+		index = (testNumber - 1) % len(d.records)
+	}
+	return d.records[index]
+}
+
+// shareGPTConversation is one entry of a ShareGPT-style JSON array.
+type shareGPTConversation struct {
+	ID            string `json:"id"`
+	Conversations []struct {
+		From  string `json:"from"`
+		Value string `json:"value"`
+	} `json:"conversations"`
+}
+
+// parseShareGPT converts ShareGPT conversations into records. The final
+// assistant turn is held back as the expected output; everything before it
+// is replayed as the request history.
+func parseShareGPT(r io.Reader, countTokens func(string) int) ([]DatasetRecord, error) {
+	var conversations []shareGPTConversation
+	if err := json.NewDecoder(r).Decode(&conversations); err != nil {
+		return nil, fmt.Errorf("failed to parse ShareGPT dataset: %w", err)
+	}
+
+	records := make([]DatasetRecord, 0, len(conversations))
+	for _, conversation := range conversations {
+		messages := make([]Message, 0, len(conversation.Conversations))
+		for _, turn := range conversation.Conversations {
+			role := shareGPTRole(turn.From)
+			if role == "" || strings.TrimSpace(turn.Value) == "" {
+				continue
+			}
+			messages = append(messages, Message{Role: role, Content: turn.Value})
+		}
+
+		var outputTokens int
+		if n := len(messages); n > 0 && messages[n-1].Role == "assistant" {
+			outputTokens = countTokens(messages[n-1].Content)
+			messages = messages[:n-1]
+		}
+
+		if record, ok := newDatasetRecord(messages, 0, outputTokens, countTokens); ok {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+func shareGPTRole(from string) string {
+	switch strings.ToLower(from) {
+	case "human", "user":
+		return "user"
+	case "gpt", "assistant", "chatgpt", "bard", "bing":
+		return "assistant"
+	case "system":
+		return "system"
+	default:
+		return ""
+	}
+}
+
+// datasetJSONLLine is one line of a generic JSONL workload. Either messages
+// or a plain prompt must be present; prompt_tokens overrides the local
+// count when the file already carries server-side numbers.
+type datasetJSONLLine struct {
+	Messages     []Message `json:"messages"`
+	Prompt       string    `json:"prompt"`
+	PromptTokens int       `json:"prompt_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	MaxTokens    int       `json:"max_tokens"`
+}
+
+// parseDatasetJSONL reads one request per line. Blank lines are skipped.
+func parseDatasetJSONL(r io.Reader, countTokens func(string) int) ([]DatasetRecord, error) {
+	var records []DatasetRecord
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry datasetJSONLLine
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse dataset line %d: %w", lineNumber, err)
+		}
+
+		messages := entry.Messages
+		if len(messages) == 0 && entry.Prompt != "" {
+			messages = []Message{{Role: "user", Content: entry.Prompt}}
+		}
+
+		outputTokens := entry.OutputTokens
+		if outputTokens == 0 {
+			outputTokens = entry.MaxTokens
+		}
+
+		if record, ok := newDatasetRecord(messages, entry.PromptTokens, outputTokens, countTokens); ok {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+
+	return records, nil
+}
+
+// newDatasetRecord builds a record, rejecting conversations without a user
+// turn. promptTokens is counted from the messages when not provided.
+func newDatasetRecord(messages []Message, promptTokens, outputTokens int, countTokens func(string) int) (DatasetRecord, bool) {
+	hasUser := false
+	for _, message := range messages {
+		if message.Role == "user" {
+			hasUser = true
+			break
+		}
+	}
+	if !hasUser {
+		return DatasetRecord{}, false
+	}
+
+	if promptTokens <= 0 {
+		for _, message := range messages {
+			promptTokens += countTokens(message.Content)
+		}
+	}
+
+	return DatasetRecord{
+		Messages:     messages,
+		PromptTokens: promptTokens,
+		OutputTokens: max(outputTokens, 0),
+	}, true
+}
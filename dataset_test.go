@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseShareGPTHoldsBackFinalAnswer(t *testing.T) {
+	data := `[
+		{"id": "a", "conversations": [
+			{"from": "system", "value": "be brief"},
+			{"from": "human", "value": "hi"},
+			{"from": "gpt", "value": "hello there"},
+			{"from": "human", "value": "tell me a story"},
+			{"from": "gpt", "value": "once upon a time"}
+		]},
+		{"id": "b", "conversations": [{"from": "gpt", "value": "orphan answer"}]}
+	]`
+	countWords := func(s string) int { return len(strings.Fields(s)) }
+
+	records, err := parseShareGPT(strings.NewReader(data), countWords)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected the conversation without a user turn to be dropped, got %d records", len(records))
+	}
+
+	record := records[0]
+	if len(record.Messages) != 4 || record.Messages[3].Content != "tell me a story" {
+		t.Fatalf("expected history up to the last user turn, got %+v", record.Messages)
+	}
+	if record.OutputTokens != 4 {
+		t.Fatalf("expected output length of the held-back answer (4), got %d", record.OutputTokens)
+	}
+	if record.PromptTokens != 9 {
+		t.Fatalf("expected 9 prompt tokens, got %d", record.PromptTokens)
+	}
+}
+
+func TestLoadDatasetJSONLFiltersAndIsSeeded(t *testing.T) {
+	var lines []string
+	lines = append(lines, `{"prompt": "short", "max_tokens": 16}`)
+	for i := 0; i < 20; i++ {
+		lines = append(lines, `{"messages": [{"role": "user", "content": "prompt `+strconv.Itoa(i)+`"}], "prompt_tokens": 100, "output_tokens": 64}`)
+	}
+	path := filepath.Join(t.TempDir(), "workload.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := DatasetConfiguration{Path: path, Seed: 7, MinPromptTokens: 50}
+	dataset, err := LoadDataset(config, "")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if dataset.Len() != 20 {
+		t.Fatalf("expected the short record to be filtered out, got %d records", dataset.Len())
+	}
+	if record := dataset.RecordFor(21); record.OutputTokens != 64 || record.PromptTokens != 100 {
+		t.Fatalf("unexpected wrapped record: %+v", record)
+	}
+
+	again, err := LoadDataset(config, "")
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	for i := 1; i <= dataset.Len(); i++ {
+		if dataset.RecordFor(i).Messages[0].Content != again.RecordFor(i).Messages[0].Content {
+			t.Fatalf("expected the same seed to give the same order, differs at test %d", i)
+		}
+	}
+
+	config.MaxPromptTokens = 10
+	config.MinPromptTokens = 5
+	if _, err := LoadDataset(config, ""); err == nil {
+		t.Fatalf("expected an error when no record survives filtering")
+	}
+}
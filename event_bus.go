@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types published on the EventBus.
+const (
+	EventProgress  = "progress"
+	EventResult    = "result"
+	EventTelemetry = "telemetry"
+	EventCompleted = "completed" // last event of a batch; Batch or Error is set
+)
+
+// defaultSubscriptionBuffer is how many telemetry events a subscriber may
+// fall behind by before the oldest ones are dropped.
+const defaultSubscriptionBuffer = 256
+
+// Event is one update of a running batch. Exactly one of the payload
+// fields is set, matching Type.
+type Event struct {
+	Type      string           `json:"type"`
+	BatchID   string           `json:"batchId"`
+	Model     string           `json:"model,omitempty"`
+	Step      int              `json:"step,omitempty"` // 1-based step index
+	Timestamp int64            `json:"timestamp"`      // Unix ms
+	Progress  *ProgressUpdate  `json:"progress,omitempty"`
+	Result    *TestResult      `json:"result,omitempty"`
+	Telemetry *TelemetryUpdate `json:"telemetry,omitempty"`
+	Batch     *TestBatch       `json:"batch,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// EventBus fans batch events out to any number of subscribers. Publishing
+// never blocks: every subscriber has its own queue, drained by its own
+// goroutine, so a slow consumer cannot stall the request workers or the
+// other subscribers.
+type EventBus struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewEventBus creates an empty bus.
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events of one batch, or of every batch when
+// subscribed with an empty batch ID. Progress, result and completion events
+// are never dropped; when more than buffer events are queued, the oldest
+// queued telemetry snapshot is discarded instead, since a newer one
+// supersedes it.
+type Subscription struct {
+	bus     *EventBus
+	batchID string
+	buffer  int
+
+	mu      sync.Mutex
+	queue   []Event
+	dropped int
+	ending  bool // deliver what is queued, then close
+	notify  chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	events  chan Event
+}
+
+// Subscribe registers a subscriber for batchID ("" for all batches). A
+// batch-scoped subscription ends by itself after the batch's completed
+// event. buffer <= 0 selects the default.
+func (b *EventBus) Subscribe(batchID string, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = defaultSubscriptionBuffer
+	}
+
+	sub := &Subscription{
+		bus:     b,
+		batchID: batchID,
+		buffer:  buffer,
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		events:  make(chan Event),
+	}
+	go sub.pump()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.end()
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Publish delivers event to every matching subscriber.
+func (b *EventBus) Publish(event Event) {
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixMilli()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if sub.batchID != "" && sub.batchID != event.BatchID {
+			continue
+		}
+		sub.enqueue(event)
+		if sub.batchID != "" && event.Type == EventCompleted {
+			delete(b.subs, sub)
+			sub.end()
+		}
+	}
+}
+
+// Close ends every subscription after its queued events are delivered.
+// Later subscriptions are closed immediately.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		sub.end()
+	}
+}
+
+// Events returns the channel events are delivered on. It is closed when
+// the subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns how many telemetry events were discarded because the
+// subscriber fell behind.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close unsubscribes and discards anything still queued.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	delete(s.bus.subs, s)
+	s.bus.mu.Unlock()
+
+	s.once.Do(func() { close(s.done) })
+}
+
+func (s *Subscription) enqueue(event Event) {
+	s.mu.Lock()
+	if len(s.queue) >= s.buffer {
+		for i, queued := range s.queue {
+			if queued.Type == EventTelemetry {
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				s.dropped++
+				break
+			}
+		}
+	}
+	if len(s.queue) >= s.buffer && event.Type == EventTelemetry {
+		s.dropped++
+	} else {
+		s.queue = append(s.queue, event)
+	}
+	s.mu.Unlock()
+
+	s.wake()
+}
+
+func (s *Subscription) end() {
+	s.mu.Lock()
+	s.ending = true
+	s.mu.Unlock()
+
+	s.wake()
+}
+
+func (s *Subscription) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pump moves queued events to the subscriber channel.
+func (s *Subscription) pump() {
+	defer close(s.events)
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			ending := s.ending
+			s.mu.Unlock()
+			if ending {
+				return
+			}
+			select {
+			case <-s.notify:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		event := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// writeEventLog writes every event of sub to w as one JSON object per
+// line until the subscription ends.
+func writeEventLog(sub *Subscription, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	var firstErr error
+	for event := range sub.Events() {
+		if firstErr != nil {
+			continue // keep draining so the subscription can finish
+		}
+		if err := encoder.Encode(event); err != nil {
+			firstErr = fmt.Errorf("failed to write event log: %w", err)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEventBusKeepsBatchesApart(t *testing.T) {
+	bus := NewEventBus()
+	subA := bus.Subscribe("a", 0)
+	subB := bus.Subscribe("b", 0)
+	all := bus.Subscribe("", 0)
+
+	bus.Publish(Event{Type: EventResult, BatchID: "a", Result: &TestResult{TestNumber: 1}})
+	bus.Publish(Event{Type: EventResult, BatchID: "b", Result: &TestResult{TestNumber: 2}})
+	bus.Publish(Event{Type: EventCompleted, BatchID: "a"})
+	bus.Publish(Event{Type: EventCompleted, BatchID: "b"})
+
+	// Batch-scoped subscriptions close themselves after their completed event.
+	var gotA []Event
+	for event := range subA.Events() {
+		gotA = append(gotA, event)
+	}
+	if len(gotA) != 2 || gotA[0].Result.TestNumber != 1 || gotA[1].Type != EventCompleted {
+		t.Fatalf("unexpected events for batch a: %+v", gotA)
+	}
+	for event := range subB.Events() {
+		if event.BatchID != "b" {
+			t.Fatalf("batch b subscriber received %+v", event)
+		}
+	}
+
+	bus.Close()
+	count := 0
+	for range all.Events() {
+		count++
+	}
+	if count != 4 {
+		t.Fatalf("expected the unscoped subscriber to see 4 events, got %d", count)
+	}
+}
+
+func TestEventBusSlowSubscriberDropsOnlyTelemetry(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe("x", 4)
+
+	// Nobody reads while publishing; Publish must not block.
+	for i := 0; i < 10; i++ {
+		bus.Publish(Event{Type: EventTelemetry, BatchID: "x", Telemetry: &TelemetryUpdate{CompletedTests: i}})
+		bus.Publish(Event{Type: EventResult, BatchID: "x", Result: &TestResult{TestNumber: i}})
+	}
+	bus.Publish(Event{Type: EventCompleted, BatchID: "x"})
+
+	results, telemetry := 0, 0
+	for event := range sub.Events() {
+		switch event.Type {
+		case EventResult:
+			results++
+		case EventTelemetry:
+			telemetry++
+		}
+	}
+	if results != 10 {
+		t.Fatalf("expected every result to be delivered, got %d", results)
+	}
+	if telemetry+sub.Dropped() != 10 || sub.Dropped() == 0 {
+		t.Fatalf("expected stale telemetry to be dropped, got %d delivered and %d dropped", telemetry, sub.Dropped())
+	}
+}
+
+func TestSubscriptionCloseStopsDelivery(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe("", 0)
+	bus.Publish(Event{Type: EventProgress, BatchID: "x", Progress: &ProgressUpdate{}})
+	sub.Close()
+
+	select {
+	case _, ok := <-sub.Events():
+		if ok {
+			// A queued event may race with Close; the channel must still close.
+			if _, ok := <-sub.Events(); ok {
+				t.Fatalf("expected the channel to close after Close")
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("subscription did not close")
+	}
+	bus.Publish(Event{Type: EventProgress, BatchID: "x", Progress: &ProgressUpdate{}})
+}
+
+func TestRunSpeedTestPublishesEvents(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{TTFT: 5 * time.Millisecond, TokensPerSecond: 1000, ReportUsage: true, Seed: 1}))
+	defer server.Close()
+
+	service := NewSpeedTestService()
+	sub := service.Events().Subscribe("batch-1", 0)
+	other := service.Events().Subscribe("batch-2", 0)
+
+	config := mockTestConfiguration(server.URL + "/v1")
+	config.APIKey = ""
+	if _, err := service.RunSpeedTest(context.Background(), config, "batch-1"); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	counts := map[string]int{}
+	var last Event
+	for event := range sub.Events() {
+		if event.BatchID != "batch-1" || event.Model != "mock-model" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+		counts[event.Type]++
+		last = event
+	}
+	if counts[EventResult] != 4 || counts[EventProgress] != 8 {
+		t.Fatalf("expected 4 results and 8 progress updates, got %v", counts)
+	}
+	if counts[EventTelemetry] == 0 {
+		t.Fatalf("expected at least the final telemetry event")
+	}
+	if last.Type != EventCompleted || last.Batch == nil || last.Batch.Summary.TotalTests != 4 {
+		t.Fatalf("expected the completed event with the batch last, got %+v", last)
+	}
+
+	other.Close()
+}
+
+func TestRunSpeedTestPublishesCompletionOnError(t *testing.T) {
+	service := NewSpeedTestService()
+	sub := service.Events().Subscribe("bad", 0)
+
+	config := defaultTestConfiguration()
+	config.TestMode = "concurrency_step" // with an empty step configuration
+	if _, err := service.RunSpeedTest(context.Background(), config, "bad"); err == nil {
+		t.Fatalf("expected an invalid step configuration to fail")
+	}
+
+	event, ok := <-sub.Events()
+	if !ok || event.Type != EventCompleted || event.Error == "" {
+		t.Fatalf("expected a completed event carrying the error, got %+v", event)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -67,19 +68,137 @@ func (s *ExportService) exportCSV(batch TestBatch, options ExportOptions) (strin
 	// Write configuration header (align with UI header/metadata)
 	writer.Write([]string{"CONFIGURATION"})
 	writer.Write([]string{"Batch ID", batch.ID})
+	if batch.MatrixID != "" {
+		writer.Write([]string{"Matrix ID", batch.MatrixID})
+		writer.Write([]string{"Target", batch.Name})
+		writer.Write([]string{"API Endpoint", batch.Configuration.APIEndpoint})
+	}
 	writer.Write([]string{"Model", batch.Configuration.Model})
+	if batch.Configuration.Provider != "" {
+		writer.Write([]string{"Provider", batch.Configuration.Provider})
+	}
 	writer.Write([]string{"Test Mode", batch.Configuration.TestMode})
-	writer.Write([]string{"Prompt Length (tokens)", strconv.Itoa(batch.Configuration.PromptLength)})
+	if batch.Seed != 0 {
+		writer.Write([]string{"Seed", strconv.FormatInt(batch.Seed, 10)})
+	}
+	if batch.ReplayOf != "" {
+		writer.Write([]string{"Replay Of", batch.ReplayOf})
+	}
+	if batch.Configuration.PromptType == "dataset" {
+		dc := batch.Configuration.DatasetConfig
+		writer.Write([]string{"Dataset", dc.Path})
+		writer.Write([]string{"Dataset Seed", strconv.FormatInt(dc.Seed, 10)})
+		if dc.MinPromptTokens > 0 || dc.MaxPromptTokens > 0 {
+			writer.Write([]string{"Dataset Prompt Tokens", fmt.Sprintf("%d-%d", dc.MinPromptTokens, dc.MaxPromptTokens)})
+		}
+	} else {
+		writer.Write([]string{"Prompt Length (tokens)", strconv.Itoa(batch.Configuration.PromptLength)})
+		if pc := batch.Configuration.PrefixConfig; pc.Mode != "" {
+			writer.Write([]string{"Prefix Mode", pc.Mode})
+			if pc.Mode == prefixModeShared {
+				writer.Write([]string{"Shared Prefix (tokens)", strconv.Itoa(pc.Length)})
+				writer.Write([]string{"Prefix Hit Ratio", strconv.FormatFloat(pc.HitRatio, 'g', -1, 64)})
+			}
+		}
+		if calibration := batch.PromptCalibration; calibration != nil {
+			writer.Write([]string{"Chat Template Overhead (tokens)", strconv.Itoa(calibration.TemplateOverhead)})
+			for _, length := range calibration.Lengths {
+				writer.Write([]string{fmt.Sprintf("Calibrated Prompt %d", length.TargetTokens),
+					fmt.Sprintf("%d local tokens, %d server tokens", length.LocalTokens, length.ServerTokens)})
+			}
+		}
+	}
 	writer.Write([]string{"Max Output Tokens", strconv.Itoa(batch.Configuration.MaxTokens)})
 	writer.Write([]string{"Concurrent Tests (base)", strconv.Itoa(batch.Configuration.ConcurrentTests)})
 	writer.Write([]string{"Test Rounds (per step)", strconv.Itoa(batch.Configuration.TestCount)})
 
-	if batch.Configuration.TestMode == "concurrency_step" || batch.Configuration.TestMode == "input_step" {
+	if _, ok := stepModes[batch.Configuration.TestMode]; ok {
 		writer.Write([]string{"Step Start", strconv.Itoa(batch.Configuration.StepConfig.Start)})
 		writer.Write([]string{"Step End", strconv.Itoa(batch.Configuration.StepConfig.End)})
 		writer.Write([]string{"Step Interval", strconv.Itoa(batch.Configuration.StepConfig.Step)})
 	}
 
+	if batch.Configuration.TestMode == "sweep" {
+		for _, dim := range batch.Configuration.Sweep {
+			values, err := sweepValues(dim)
+			if err != nil {
+				continue
+			}
+			formatted := make([]string, len(values))
+			for i, v := range values {
+				formatted[i] = strconv.FormatFloat(v, 'g', -1, 64)
+			}
+			writer.Write([]string{"Sweep " + dim.Field, strings.Join(formatted, " ")})
+		}
+	}
+
+	if batch.Configuration.TestMode == "conversation" {
+		cc := batch.Configuration.ConversationConfig
+		writer.Write([]string{"Sessions", strconv.Itoa(cc.Sessions)})
+		writer.Write([]string{"Turns Per Session", strconv.Itoa(cc.Turns)})
+		writer.Write([]string{"Turn Length (tokens)", strconv.Itoa(cc.turnLength(2, batch.Configuration.PromptLength))})
+	}
+
+	if tc := batch.Configuration.ToolConfig; tc.enabled() {
+		if tc.Path != "" {
+			writer.Write([]string{"Tool Definitions", tc.Path})
+		} else {
+			writer.Write([]string{"Built-in Tools", strconv.Itoa(tc.Count)})
+		}
+		writer.Write([]string{"Tool Choice", tc.choice()})
+	}
+	if sc := batch.Configuration.StructuredOutput; sc.enabled() {
+		writer.Write([]string{"JSON Schema", sc.SchemaPath})
+		writer.Write([]string{"Schema Method", sc.method()})
+	}
+	if vc := batch.Configuration.VisionConfig; vc.Count > 0 || batch.Summary.Vision != nil {
+		if len(vc.Images) > 0 {
+			writer.Write([]string{"Image Files", strings.Join(vc.Images, " ")})
+		} else {
+			writer.Write([]string{"Image Files", "generated"})
+		}
+		writer.Write([]string{"Images Per Request", strconv.Itoa(vc.Count)})
+		writer.Write([]string{"Image Resolution (px)", strconv.Itoa(vc.Resolution)})
+		if vc.Detail != "" {
+			writer.Write([]string{"Image Detail", vc.Detail})
+		}
+	}
+
+	if batch.Configuration.TestMode == "rate" {
+		rc := batch.Configuration.RateConfig
+		writer.Write([]string{"Request Rate (req/s)", fmt.Sprintf("%.2f", rc.RequestRate)})
+		writer.Write([]string{"Arrival Process", rc.ArrivalProcess})
+		writer.Write([]string{"Requests", strconv.Itoa(rc.NumRequests)})
+		if rc.ArrivalProcess == arrivalBurst {
+			writer.Write([]string{"Burst Size", strconv.Itoa(rc.BurstSize)})
+		}
+		if rc.MaxInFlight > 0 {
+			writer.Write([]string{"Max In Flight", strconv.Itoa(rc.MaxInFlight)})
+		}
+	}
+
+	if batch.Configuration.TestMode == "rate_step" {
+		rc := batch.Configuration.RateConfig
+		slo := batch.Configuration.SLO
+		writer.Write([]string{"Rate Start (req/s)", fmt.Sprintf("%.2f", rc.RateStart)})
+		writer.Write([]string{"Rate End (req/s)", fmt.Sprintf("%.2f", rc.RateEnd)})
+		writer.Write([]string{"Rate Step (req/s)", fmt.Sprintf("%.2f", rc.RateStep)})
+		writer.Write([]string{"Arrival Process", rc.ArrivalProcess})
+		writer.Write([]string{"Requests Per Rate", strconv.Itoa(rc.NumRequests)})
+		if slo.MaxP95TTFT > 0 {
+			writer.Write([]string{"SLO P95 TTFT (ms)", fmt.Sprintf("%.2f", slo.MaxP95TTFT)})
+		}
+		if slo.MaxP95Latency > 0 {
+			writer.Write([]string{"SLO P95 Latency (ms)", fmt.Sprintf("%.2f", slo.MaxP95Latency)})
+		}
+		if slo.MaxP95TPOT > 0 {
+			writer.Write([]string{"SLO P95 TPOT (ms)", fmt.Sprintf("%.2f", slo.MaxP95TPOT)})
+		}
+		if slo.MaxErrorRate != nil {
+			writer.Write([]string{"SLO Error Rate", fmt.Sprintf("%.2f%%", *slo.MaxErrorRate*100)})
+		}
+	}
+
 	if batch.StartTime != "" && batch.EndTime != "" {
 		writer.Write([]string{"Start Time", batch.StartTime})
 		writer.Write([]string{"End Time", batch.EndTime})
@@ -106,11 +225,38 @@ func (s *ExportService) exportCSV(batch TestBatch, options ExportOptions) (strin
 		"Output Time (ms)",
 		"Prompt Tokens",
 		"Completion Tokens",
+		"Local Completion Tokens",
 		"Total Tokens",
 		"Prefill Tokens Per Second",
 		"Output Tokens Per Second",
 		"Throughput",
 		"Actual Concurrency",
+		"TPOT (ms)",
+		"Mean ITL (ms)",
+		"P50 ITL (ms)",
+		"P99 ITL (ms)",
+		"Max Stall (ms)",
+		"Queue Delay (ms)",
+		"Sweep Point",
+		"Prefix Cache",
+		"Session",
+		"Turn",
+		"Tool Calls",
+		"Time To First Tool Call (ms)",
+		"Tool Call Tokens",
+		"Tool Call Tokens Per Second",
+		"Error Kind",
+		"Structured Output",
+		"Schema Error",
+		"Time To First Reasoning Token (ms)",
+		"Time To First Answer Token (ms)",
+		"Reasoning Tokens",
+		"Answer Tokens",
+		"Reasoning Tokens Per Second",
+		"Answer Tokens Per Second",
+		"Images",
+		"Image Resolution",
+		"Image Tokens",
 		"Error",
 	}
 
@@ -131,11 +277,38 @@ func (s *ExportService) exportCSV(batch TestBatch, options ExportOptions) (strin
 			fmt.Sprintf("%.2f", result.OutputLatency),
 			strconv.Itoa(result.PromptTokens),
 			strconv.Itoa(result.CompletionTokens),
+			strconv.Itoa(result.LocalCompletionTokens),
 			strconv.Itoa(result.TotalTokens),
 			fmt.Sprintf("%.2f", result.PrefillTokensPerSecond),
 			fmt.Sprintf("%.2f", result.OutputTokensPerSecond),
 			fmt.Sprintf("%.2f", result.Throughput),
 			strconv.Itoa(result.ActualConcurrency),
+			fmt.Sprintf("%.2f", result.TimePerOutputToken),
+			fmt.Sprintf("%.2f", result.InterTokenLatency),
+			fmt.Sprintf("%.2f", result.InterTokenLatencyP50),
+			fmt.Sprintf("%.2f", result.InterTokenLatencyP99),
+			fmt.Sprintf("%.2f", result.MaxStall),
+			fmt.Sprintf("%.2f", result.QueueDelay),
+			formatSweepCoordinates(result.SweepPoint),
+			result.PrefixCache,
+			strconv.Itoa(result.Session),
+			strconv.Itoa(result.Turn),
+			strconv.Itoa(result.ToolCalls),
+			fmt.Sprintf("%.2f", result.TimeToFirstToolCall),
+			strconv.Itoa(result.ToolCallTokens),
+			fmt.Sprintf("%.2f", result.ToolCallTokensPerSecond),
+			result.ErrorKind,
+			result.StructuredOutput,
+			result.SchemaError,
+			fmt.Sprintf("%.2f", result.TimeToFirstReasoningToken),
+			fmt.Sprintf("%.2f", result.TimeToFirstAnswerToken),
+			strconv.Itoa(result.ReasoningTokens),
+			strconv.Itoa(result.AnswerTokens),
+			fmt.Sprintf("%.2f", result.ReasoningTokensPerSecond),
+			fmt.Sprintf("%.2f", result.AnswerTokensPerSecond),
+			strconv.Itoa(result.Images),
+			strconv.Itoa(result.ImageResolution),
+			strconv.Itoa(result.ImageTokens),
 			result.Error,
 		}
 
@@ -151,6 +324,7 @@ func (s *ExportService) exportCSV(batch TestBatch, options ExportOptions) (strin
 	writer.Write([]string{"Successful Tests", strconv.Itoa(batch.Summary.SuccessfulTests)})
 	writer.Write([]string{"Failed Tests", strconv.Itoa(batch.Summary.FailedTests)})
 	writer.Write([]string{"Error Rate", fmt.Sprintf("%.2f%%", batch.Summary.ErrorRate*100)})
+	writer.Write([]string{"Usage Deviation (reported vs local tokens)", fmt.Sprintf("%+.2f%%", batch.Summary.UsageDeviation*100)})
 	writer.Write([]string{})
 	writer.Write([]string{"LATENCY STATISTICS (ms)"})
 	writer.Write([]string{"Average (Total)", fmt.Sprintf("%.2f", batch.Summary.AverageLatency)})
@@ -182,11 +356,109 @@ func (s *ExportService) exportCSV(batch TestBatch, options ExportOptions) (strin
 	writer.Write([]string{"Average", fmt.Sprintf("%.2f", batch.Summary.AverageOutputTokensPerSecond)})
 	writer.Write([]string{"Minimum", fmt.Sprintf("%.2f", batch.Summary.MinOutputTokensPerSecond)})
 	writer.Write([]string{"Maximum", fmt.Sprintf("%.2f", batch.Summary.MaxOutputTokensPerSecond)})
+	writer.Write([]string{})
+	writer.Write([]string{"PERCENTILES", "P50", "P90", "P95", "P99", "Std Dev"})
+	writeDistributionRow(writer, "TTFT (ms)", batch.Summary.PrefillLatencyDistribution)
+	writeDistributionRow(writer, "Total Latency (ms)", batch.Summary.LatencyDistribution)
+	writeDistributionRow(writer, "Output Latency (ms)", batch.Summary.OutputLatencyDistribution)
+	writeDistributionRow(writer, "Output Tokens/sec", batch.Summary.OutputTokensPerSecondDistribution)
+	writeDistributionRow(writer, "TPOT (ms)", batch.Summary.TimePerOutputTokenDistribution)
+	writeDistributionRow(writer, "ITL (ms)", batch.Summary.InterTokenLatencyDistribution)
+	writeDistributionRow(writer, "Queue Delay (ms)", batch.Summary.QueueDelayDistribution)
+	if pc := batch.Summary.PrefixCache; pc != nil {
+		writeDistributionRow(writer, fmt.Sprintf("Warm TTFT (ms, %d requests)", pc.WarmRequests), pc.WarmTTFTDistribution)
+		writeDistributionRow(writer, fmt.Sprintf("Cold TTFT (ms, %d requests)", pc.ColdRequests), pc.ColdTTFTDistribution)
+	}
+	if tc := batch.Summary.ToolCalls; tc != nil {
+		writeDistributionRow(writer, "Time To First Tool Call (ms)", tc.TimeToFirstToolCallDistribution)
+		writeDistributionRow(writer, "Tool Call Tokens/sec", tc.ToolCallTokensPerSecondDistribution)
+	}
+	if so := batch.Summary.StructuredOutput; so != nil {
+		writeDistributionRow(writer, fmt.Sprintf("Constrained Output Tokens/sec (%d requests)", so.ConstrainedRequests), so.ConstrainedTPSDistribution)
+		writeDistributionRow(writer, fmt.Sprintf("Baseline Output Tokens/sec (%d requests)", so.BaselineRequests), so.BaselineTPSDistribution)
+		writeDistributionRow(writer, "Constrained TTFT (ms)", so.ConstrainedTTFTDistribution)
+		writeDistributionRow(writer, "Baseline TTFT (ms)", so.BaselineTTFTDistribution)
+	}
+	if rs := batch.Summary.Reasoning; rs != nil {
+		writeDistributionRow(writer, "Time To First Reasoning Token (ms)", rs.TimeToFirstReasoningTokenDistribution)
+		writeDistributionRow(writer, "Time To First Answer Token (ms)", rs.TimeToFirstAnswerTokenDistribution)
+		writeDistributionRow(writer, "Reasoning Tokens/sec", rs.ReasoningTokensPerSecondDistribution)
+		writeDistributionRow(writer, "Answer Tokens/sec", rs.AnswerTokensPerSecondDistribution)
+	}
+	if vs := batch.Summary.Vision; vs != nil {
+		writeDistributionRow(writer, fmt.Sprintf("TTFT With Images (ms, %d requests)", vs.Requests), vs.TTFTDistribution)
+	}
+	writer.Write([]string{})
+	writer.Write([]string{"DECODE SMOOTHNESS (ms)"})
+	writer.Write([]string{"Average TPOT", fmt.Sprintf("%.2f", batch.Summary.AverageTimePerOutputToken)})
+	writer.Write([]string{"Average ITL", fmt.Sprintf("%.2f", batch.Summary.AverageInterTokenLatency)})
+	writer.Write([]string{"Max Stall", fmt.Sprintf("%.2f", batch.Summary.MaxStall)})
+
+	if tc := batch.Summary.ToolCalls; tc != nil {
+		writer.Write([]string{})
+		writer.Write([]string{"TOOL CALLS"})
+		writer.Write([]string{"Requests With Tools", strconv.Itoa(tc.Requests)})
+		writer.Write([]string{"Requests With Tool Calls", strconv.Itoa(tc.RequestsWithCalls)})
+		writer.Write([]string{"Malformed Tool Calls", strconv.Itoa(tc.MalformedCalls)})
+		writer.Write([]string{"Malformed Rate", fmt.Sprintf("%.2f%%", tc.MalformedRate*100)})
+		writer.Write([]string{"Missing Tool Calls", strconv.Itoa(tc.MissingCalls)})
+		writer.Write([]string{"Average Time To First Tool Call (ms)", fmt.Sprintf("%.2f", tc.AverageTimeToFirstToolCall)})
+		writer.Write([]string{"Average Tool Call Tokens/sec", fmt.Sprintf("%.2f", tc.AverageToolCallTokensPerSecond)})
+	}
+
+	if so := batch.Summary.StructuredOutput; so != nil {
+		writer.Write([]string{})
+		writer.Write([]string{"STRUCTURED OUTPUT"})
+		writer.Write([]string{"Constrained Requests", strconv.Itoa(so.ConstrainedRequests)})
+		writer.Write([]string{"Baseline Requests", strconv.Itoa(so.BaselineRequests)})
+		writer.Write([]string{"Schema Compliant", strconv.Itoa(so.CompliantRequests)})
+		writer.Write([]string{"Compliance Rate", fmt.Sprintf("%.2f%%", so.ComplianceRate*100)})
+		writer.Write([]string{"Average Constrained Output Tokens/sec", fmt.Sprintf("%.2f", so.AverageConstrainedTPS)})
+		writer.Write([]string{"Average Baseline Output Tokens/sec", fmt.Sprintf("%.2f", so.AverageBaselineTPS)})
+		writer.Write([]string{"Decode Overhead (median)", fmt.Sprintf("%.2f%%", so.DecodeOverhead*100)})
+	}
+
+	if rs := batch.Summary.Reasoning; rs != nil {
+		writer.Write([]string{})
+		writer.Write([]string{"REASONING"})
+		writer.Write([]string{"Requests With Reasoning", strconv.Itoa(rs.Requests)})
+		writer.Write([]string{"Average Reasoning Tokens", fmt.Sprintf("%.2f", rs.AverageReasoningTokens)})
+		writer.Write([]string{"Average Answer Tokens", fmt.Sprintf("%.2f", rs.AverageAnswerTokens)})
+		writer.Write([]string{"Average Time To First Reasoning Token (ms)", fmt.Sprintf("%.2f", rs.AverageTimeToFirstReasoningToken)})
+		writer.Write([]string{"Average Time To First Answer Token (ms)", fmt.Sprintf("%.2f", rs.AverageTimeToFirstAnswerToken)})
+		writer.Write([]string{"Average Reasoning Tokens/sec", fmt.Sprintf("%.2f", rs.AverageReasoningTokensPerSecond)})
+		writer.Write([]string{"Average Answer Tokens/sec", fmt.Sprintf("%.2f", rs.AverageAnswerTokensPerSecond)})
+	}
+
+	if vs := batch.Summary.Vision; vs != nil {
+		writer.Write([]string{})
+		writer.Write([]string{"VISION"})
+		writer.Write([]string{"Requests With Images", strconv.Itoa(vs.Requests)})
+		writer.Write([]string{"Average Images", fmt.Sprintf("%.2f", vs.AverageImages)})
+		writer.Write([]string{"Average Image Tokens", fmt.Sprintf("%.2f", vs.AverageImageTokens)})
+		writer.Write([]string{"Image Token Share", fmt.Sprintf("%.2f%%", vs.ImageTokenShare*100)})
+		writer.Write([]string{"Average TTFT With Images (ms)", fmt.Sprintf("%.2f", vs.AverageTTFT)})
+		if len(batch.SweepPoints) > 0 {
+			writer.Write([]string{"Point", "Avg Images", "Avg Image Tokens", "Image Token Share", "P50 TTFT (ms)", "P95 TTFT (ms)"})
+			for _, point := range batch.SweepPoints {
+				row := []string{formatSweepCoordinates(point.Coordinates), "0", "0", "0.00%"}
+				if pv := point.Summary.Vision; pv != nil {
+					row = []string{formatSweepCoordinates(point.Coordinates),
+						fmt.Sprintf("%.2f", pv.AverageImages),
+						fmt.Sprintf("%.2f", pv.AverageImageTokens),
+						fmt.Sprintf("%.2f%%", pv.ImageTokenShare*100)}
+				}
+				writer.Write(append(row,
+					fmt.Sprintf("%.2f", point.Summary.PrefillLatencyDistribution.P50),
+					fmt.Sprintf("%.2f", point.Summary.PrefillLatencyDistribution.P95)))
+			}
+		}
+	}
 
 	if len(batch.RoundSummaries) > 0 {
 		writer.Write([]string{})
 		writer.Write([]string{"ROUND SUMMARIES"})
-		writer.Write([]string{"Round #", "Requests", "Successes", "Success Rate", "Avg Output TPS", "Total Output TPS", "Avg TTFT (ms)", "Avg Decode (ms)", "Avg Total (ms)"})
+		writer.Write([]string{"Round #", "Requests", "Successes", "Success Rate", "Avg Output TPS", "Total Output TPS", "Avg TTFT (ms)", "Avg Decode (ms)", "Avg Total (ms)", "P50 TTFT (ms)", "P95 TTFT (ms)", "P99 TTFT (ms)", "P95 Total (ms)"})
 		for _, round := range batch.RoundSummaries {
 			writer.Write([]string{
 				strconv.Itoa(round.RoundNumber),
@@ -198,6 +470,90 @@ func (s *ExportService) exportCSV(batch TestBatch, options ExportOptions) (strin
 				fmt.Sprintf("%.2f", round.AveragePrefillLatency),
 				fmt.Sprintf("%.2f", round.AverageOutputLatency),
 				fmt.Sprintf("%.2f", round.AverageTotalLatency),
+				fmt.Sprintf("%.2f", round.PrefillLatencyDistribution.P50),
+				fmt.Sprintf("%.2f", round.PrefillLatencyDistribution.P95),
+				fmt.Sprintf("%.2f", round.PrefillLatencyDistribution.P99),
+				fmt.Sprintf("%.2f", round.TotalLatencyDistribution.P95),
+			})
+		}
+	}
+
+	if sweep := batch.RateSweep; sweep != nil {
+		writer.Write([]string{})
+		writer.Write([]string{"RATE SWEEP"})
+		writer.Write([]string{"Goodput (req/s)", fmt.Sprintf("%.2f", sweep.Goodput)})
+		writer.Write([]string{"Request Rate (req/s)", "Achieved Rate (req/s)", "Requests", "Successes", "Error Rate", "P95 TTFT (ms)", "P95 Total (ms)", "P95 TPOT (ms)", "Avg Queue Delay (ms)", "Total Output TPS", "Meets SLO", "Violations"})
+		for _, step := range sweep.Steps {
+			writer.Write([]string{
+				fmt.Sprintf("%.2f", step.RequestRate),
+				fmt.Sprintf("%.2f", step.AchievedRate),
+				strconv.Itoa(step.TotalRequests),
+				strconv.Itoa(step.SuccessfulRequests),
+				fmt.Sprintf("%.2f%%", step.ErrorRate*100),
+				fmt.Sprintf("%.2f", step.P95TTFT),
+				fmt.Sprintf("%.2f", step.P95Latency),
+				fmt.Sprintf("%.2f", step.P95TPOT),
+				fmt.Sprintf("%.2f", step.AverageQueueDelay),
+				fmt.Sprintf("%.2f", step.TotalOutputTokensPerSecond),
+				strconv.FormatBool(step.MeetsSLO),
+				strings.Join(step.Violations, "; "),
+			})
+		}
+	}
+
+	// One row per sweep point with a column per swept field, so the
+	// section can be pivoted directly in a spreadsheet.
+	if len(batch.SweepPoints) > 0 {
+		fields := make([]string, 0, len(batch.SweepPoints[0].Coordinates))
+		for field := range batch.SweepPoints[0].Coordinates {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		writer.Write([]string{})
+		writer.Write([]string{"SWEEP POINTS"})
+		writer.Write(append(append([]string{}, fields...),
+			"Requests", "Successes", "Error Rate", "P50 TTFT (ms)", "P95 TTFT (ms)", "P95 Total (ms)", "P50 TPOT (ms)", "Avg Output TPS", "Total Output TPS", "Duration (ms)"))
+		for _, point := range batch.SweepPoints {
+			row := make([]string, 0, len(fields)+10)
+			for _, field := range fields {
+				row = append(row, strconv.FormatFloat(point.Coordinates[field], 'g', -1, 64))
+			}
+			ps := point.Summary
+			row = append(row,
+				strconv.Itoa(ps.TotalTests),
+				strconv.Itoa(ps.SuccessfulTests),
+				fmt.Sprintf("%.2f%%", ps.ErrorRate*100),
+				fmt.Sprintf("%.2f", ps.PrefillLatencyDistribution.P50),
+				fmt.Sprintf("%.2f", ps.PrefillLatencyDistribution.P95),
+				fmt.Sprintf("%.2f", ps.LatencyDistribution.P95),
+				fmt.Sprintf("%.2f", ps.TimePerOutputTokenDistribution.P50),
+				fmt.Sprintf("%.2f", ps.AverageOutputTokensPerSecond),
+				fmt.Sprintf("%.2f", point.TotalOutputTokensPerSecond),
+				fmt.Sprintf("%.2f", point.Duration),
+			)
+			writer.Write(row)
+		}
+	}
+
+	// One row per conversation turn, to plot latency against the context
+	// carried by the turn.
+	if len(batch.Turns) > 0 {
+		writer.Write([]string{})
+		writer.Write([]string{"TURNS"})
+		writer.Write([]string{"Turn", "Avg Prompt Tokens", "Requests", "Successes", "Error Rate", "P50 TTFT (ms)", "P95 TTFT (ms)", "P50 TPOT (ms)", "Avg Output TPS"})
+		for _, turn := range batch.Turns {
+			ts := turn.Summary
+			writer.Write([]string{
+				strconv.Itoa(turn.Turn),
+				fmt.Sprintf("%.1f", turn.AveragePromptTokens),
+				strconv.Itoa(ts.TotalTests),
+				strconv.Itoa(ts.SuccessfulTests),
+				fmt.Sprintf("%.2f%%", ts.ErrorRate*100),
+				fmt.Sprintf("%.2f", ts.PrefillLatencyDistribution.P50),
+				fmt.Sprintf("%.2f", ts.PrefillLatencyDistribution.P95),
+				fmt.Sprintf("%.2f", ts.TimePerOutputTokenDistribution.P50),
+				fmt.Sprintf("%.2f", ts.AverageOutputTokensPerSecond),
 			})
 		}
 	}
@@ -230,6 +586,18 @@ func (s *ExportService) exportCSV(batch TestBatch, options ExportOptions) (strin
 	return filepath, nil
 }
 
+// writeDistributionRow writes one metric's percentile statistics as a CSV row.
+func writeDistributionRow(writer *csv.Writer, label string, stats DistributionStats) {
+	writer.Write([]string{
+		label,
+		fmt.Sprintf("%.2f", stats.P50),
+		fmt.Sprintf("%.2f", stats.P90),
+		fmt.Sprintf("%.2f", stats.P95),
+		fmt.Sprintf("%.2f", stats.P99),
+		fmt.Sprintf("%.2f", stats.StdDev),
+	})
+}
+
 // exportJSON exports test results as JSON
 func (s *ExportService) exportJSON(batch TestBatch, options ExportOptions) (string, error) {
 	timestamp := time.Now().Format("20060102_150405")
@@ -350,6 +718,9 @@ func (s *ExportService) exportCharts(batch TestBatch, options ExportOptions) (st
 	chartTypes := normalizeChartTypes(options.ChartTypes)
 	if len(chartTypes) == 0 {
 		chartTypes = []string{"latency", "throughput", "roundThroughput"}
+		if batch.Summary.Reasoning != nil {
+			chartTypes = append(chartTypes, "reasoningThroughput", "answerThroughput")
+		}
 	}
 
 	const (
@@ -525,9 +896,11 @@ func normalizeChartTypes(chartTypes []string) []string {
 	}
 
 	allowed := map[string]struct{}{
-		"latency":         {},
-		"throughput":      {},
-		"roundThroughput": {},
+		"latency":             {},
+		"throughput":          {},
+		"roundThroughput":     {},
+		"reasoningThroughput": {},
+		"answerThroughput":    {},
 	}
 
 	seen := make(map[string]struct{})
@@ -563,6 +936,18 @@ func extractMetricSeries(batch TestBatch, metric string) []float64 {
 				series = append(series, result.Throughput)
 			}
 		}
+	case "reasoningThroughput":
+		for _, result := range batch.Results {
+			if result.Success && result.ReasoningTokensPerSecond > 0 {
+				series = append(series, result.ReasoningTokensPerSecond)
+			}
+		}
+	case "answerThroughput":
+		for _, result := range batch.Results {
+			if result.Success && result.ReasoningTokens > 0 && result.AnswerTokensPerSecond > 0 {
+				series = append(series, result.AnswerTokensPerSecond)
+			}
+		}
 	case "roundThroughput":
 		for _, round := range batch.RoundSummaries {
 			if round.TotalRequests > 0 && !math.IsNaN(round.TotalOutputTokensPerSecond) && round.TotalOutputTokensPerSecond > 0 {
@@ -585,6 +970,12 @@ func chartColor(metric string) color.RGBA {
 	case "roundThroughput":
 		// Purple
 		return color.RGBA{R: 139, G: 92, B: 246, A: 255}
+	case "reasoningThroughput":
+		// Pink
+		return color.RGBA{R: 236, G: 72, B: 153, A: 255}
+	case "answerThroughput":
+		// Green
+		return color.RGBA{R: 16, G: 185, B: 129, A: 255}
 	default:
 		return color.RGBA{R: 248, G: 250, B: 252, A: 255}
 	}
@@ -765,6 +1156,8 @@ func (s *ExportService) exportComparisonCSV(comparison ComparisonResult, filepat
 	writer.Write([]string{"Best Throughput Batch ID", comparison.Comparison.BestThroughputBatchID})
 	writer.Write([]string{"Best Round Throughput Batch ID", comparison.Comparison.BestRoundThroughputBatchID})
 	writer.Write([]string{"Lowest Error Rate Batch ID", comparison.Comparison.LowestErrorRateBatchID})
+	writer.Write([]string{"Best P95 TTFT Batch ID", comparison.Comparison.BestP95TTFTBatchID})
+	writer.Write([]string{"Best P95 Latency Batch ID", comparison.Comparison.BestP95LatencyBatchID})
 	writer.Write([]string{})
 
 	// Write batch comparison data
@@ -783,6 +1176,13 @@ func (s *ExportService) exportComparisonCSV(comparison ComparisonResult, filepat
 		"Avg Round Throughput",
 		"Min Round Throughput",
 		"Max Round Throughput",
+		"P50 TTFT (ms)",
+		"P95 TTFT (ms)",
+		"P99 TTFT (ms)",
+		"P95 Latency (ms)",
+		"P99 Latency (ms)",
+		"P50 Output TPS",
+		"P95 Output TPS",
 	}
 
 	writer.Write(header)
@@ -803,6 +1203,13 @@ func (s *ExportService) exportComparisonCSV(comparison ComparisonResult, filepat
 			fmt.Sprintf("%.2f", batch.Summary.AverageRoundThroughput),
 			fmt.Sprintf("%.2f", batch.Summary.MinRoundThroughput),
 			fmt.Sprintf("%.2f", batch.Summary.MaxRoundThroughput),
+			fmt.Sprintf("%.2f", batch.Summary.PrefillLatencyDistribution.P50),
+			fmt.Sprintf("%.2f", batch.Summary.PrefillLatencyDistribution.P95),
+			fmt.Sprintf("%.2f", batch.Summary.PrefillLatencyDistribution.P99),
+			fmt.Sprintf("%.2f", batch.Summary.LatencyDistribution.P95),
+			fmt.Sprintf("%.2f", batch.Summary.LatencyDistribution.P99),
+			fmt.Sprintf("%.2f", batch.Summary.OutputTokensPerSecondDistribution.P50),
+			fmt.Sprintf("%.2f", batch.Summary.OutputTokensPerSecondDistribution.P95),
 		}
 
 		writer.Write(row)
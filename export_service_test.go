@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"testing"
+)
+
+func TestCSVExportReportsITLPercentiles(t *testing.T) {
+	batch := TestBatch{
+		ID: "itl-batch",
+		Results: []TestResult{{
+			ID: "r1", Success: true,
+			InterTokenLatency: 12.5, InterTokenLatencyP50: 10, InterTokenLatencyP99: 40, MaxStall: 55,
+		}},
+	}
+	path, err := NewExportService(t.TempDir()).Export(batch, ExportRequest{BatchID: batch.ID, Format: ExportFormatCSV})
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The per-result table follows the configuration section.
+	var header, row []string
+	for i, record := range records {
+		if len(record) > 0 && record[0] == "Test ID" && i+1 < len(records) {
+			header, row = record, records[i+1]
+			break
+		}
+	}
+	want := map[string]string{"Mean ITL (ms)": "12.50", "P50 ITL (ms)": "10.00", "P99 ITL (ms)": "40.00", "Max Stall (ms)": "55.00"}
+	for i, column := range header {
+		if value, ok := want[column]; ok {
+			if row[i] != value {
+				t.Errorf("%s: expected %s, got %s", column, value, row[i])
+			}
+			delete(want, column)
+		}
+	}
+	if len(want) != 0 {
+		t.Fatalf("missing columns: %v", want)
+	}
+}
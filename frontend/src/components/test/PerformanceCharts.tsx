@@ -38,7 +38,9 @@ const PerformanceCharts: React.FC<PerformanceChartsProps> = ({ batch, onExport,
     try {
       const options: ExportOptions = {
         includeCharts: true,
-        chartTypes: ['latency', 'throughput', 'roundThroughput'],
+        chartTypes: batch.summary.reasoning
+          ? ['latency', 'throughput', 'roundThroughput', 'reasoningThroughput', 'answerThroughput']
+          : ['latency', 'throughput', 'roundThroughput'],
         dateFormat: 'YYYY-MM-DD HH:mm',
       };
       await onExport(exportFormat, options);
@@ -133,6 +135,12 @@ const PerformanceCharts: React.FC<PerformanceChartsProps> = ({ batch, onExport,
             <ResultsChart batch={batch} chartType="throughput" />
           </div>
           <ResultsChart batch={batch} chartType="roundThroughput" />
+          {batch.summary.reasoning && (
+            <div className="grid grid-cols-1 2xl:grid-cols-2 gap-6">
+              <ResultsChart batch={batch} chartType="reasoningThroughput" />
+              <ResultsChart batch={batch} chartType="answerThroughput" />
+            </div>
+          )}
         </>
       )}
     </div>
@@ -320,6 +320,18 @@ const ResultsDashboard: React.FC<ResultsDashboardProps> = ({ batch, allBatches,
                   {formatDuration(summary.minLatency)} / {formatDuration(summary.maxLatency)}
                 </dd>
               </div>
+              <div className="flex items-center justify-between">
+                <dt>TTFT P50 / P95 / P99</dt>
+                <dd className="font-mono">
+                  {formatDuration(summary.prefillLatencyDistribution.p50)} / {formatDuration(summary.prefillLatencyDistribution.p95)} / {formatDuration(summary.prefillLatencyDistribution.p99)}
+                </dd>
+              </div>
+              <div className="flex items-center justify-between">
+                <dt>总生成时间 P95 / P99</dt>
+                <dd className="font-mono">
+                  {formatDuration(summary.latencyDistribution.p95)} / {formatDuration(summary.latencyDistribution.p99)}
+                </dd>
+              </div>
             </dl>
           </div>
 
@@ -2,7 +2,7 @@ import { useMemo } from 'react';
 import { RoundSummary, TestBatch } from '../types';
 import { computeRoundSummaries } from '../utils/roundSummary';
 
-export type ChartMetric = 'latency' | 'throughput' | 'roundThroughput';
+export type ChartMetric = 'latency' | 'throughput' | 'roundThroughput' | 'reasoningThroughput' | 'answerThroughput';
 
 type BatchResult = TestBatch['results'][number];
 type BatchSummary = TestBatch['summary'];
@@ -13,6 +13,7 @@ interface ChartConfig {
   accessor?: (result: BatchResult) => number;
   roundAccessor?: (round: RoundSummary) => number;
   summary: (summary: BatchSummary) => { average?: number; min?: number; max?: number };
+  include?: (result: BatchResult) => boolean;
   better: 'higher' | 'lower';
   chipLabel: string;
   bestLabel: string;
@@ -82,6 +83,42 @@ const CHART_CONFIG: Record<ChartMetric, ChartConfig> = {
       gradientTo: 'rgba(139, 92, 246, 0.05)',
     },
   },
+  reasoningThroughput: {
+    label: '思考阶段吞吐趋势',
+    description: '展示推理模型思考（reasoning）部分的解码速度（tokens/sec），数值越高越好',
+    accessor: (result) => result.reasoningTokensPerSecond,
+    include: (result) => result.reasoningTokensPerSecond > 0,
+    summary: (summary) => ({
+      average: summary.reasoning?.averageReasoningTokensPerSecond,
+    }),
+    better: 'higher',
+    chipLabel: '最新思考吞吐',
+    bestLabel: '最高思考吞吐',
+    color: {
+      line: '#ec4899',
+      dot: '#db2777',
+      gradientFrom: 'rgba(236, 72, 153, 0.5)',
+      gradientTo: 'rgba(236, 72, 153, 0.05)',
+    },
+  },
+  answerThroughput: {
+    label: '回答阶段吞吐趋势',
+    description: '展示推理模型思考结束后正式回答部分的解码速度（tokens/sec），数值越高越好',
+    accessor: (result) => result.answerTokensPerSecond,
+    include: (result) => result.reasoningTokens > 0 && result.answerTokensPerSecond > 0,
+    summary: (summary) => ({
+      average: summary.reasoning?.averageAnswerTokensPerSecond,
+    }),
+    better: 'higher',
+    chipLabel: '最新回答吞吐',
+    bestLabel: '最高回答吞吐',
+    color: {
+      line: '#10b981',
+      dot: '#059669',
+      gradientFrom: 'rgba(16, 185, 129, 0.5)',
+      gradientTo: 'rgba(16, 185, 129, 0.05)',
+    },
+  },
 };
 
 export const formatDisplayValue = (type: ChartMetric, value?: number | null): string => {
@@ -135,13 +172,22 @@ export const useResultsChartData = (batch: TestBatch, chartType: ChartMetric): R
       .map((result, index) => ({
         label: `#${index + 1}`,
         value: config.accessor ? config.accessor(result) : 0,
-        success: result.success,
+        success: result.success && (!config.include || config.include(result)),
       }))
       .filter(point => point.success && Number.isFinite(point.value))
       .map(point => ({ label: point.label, value: point.value }));
   }, [batch, config, roundSummaries]);
 
-  const summary = useMemo(() => config.summary(batch.summary), [batch.summary, config]);
+  // Summaries without their own min/max fall back to the plotted points.
+  const summary = useMemo(() => {
+    const base = config.summary(batch.summary);
+    const values = points.map(point => point.value);
+    return {
+      average: base.average,
+      min: base.min ?? (values.length > 0 ? Math.min(...values) : undefined),
+      max: base.max ?? (values.length > 0 ? Math.max(...values) : undefined),
+    };
+  }, [batch.summary, config, points]);
 
   return {
     config,
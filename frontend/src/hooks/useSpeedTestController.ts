@@ -5,9 +5,11 @@ import {
   TestBatch,
   TestResult,
   TelemetryUpdate,
+  SpeedTestEvent,
 } from '../types';
 import { useTestProgress, TestProgress } from './useTestProgress';
-import { GetTestProgress, GetTestResults, GetTestBatch, StartSpeedTest, ExportTestData, GetTelemetryUpdates } from '../wailsjs/go/main/App';
+import { StartSpeedTest, ExportTestData } from '../wailsjs/go/main/App';
+import { EventsOn } from '../../wailsjs/runtime/runtime';
 
 const MAX_COMPLETED_BATCHES = 50;
 const MAX_REALTIME_RESULTS = 500;
@@ -41,7 +43,6 @@ export const useSpeedTestController = (): SpeedTestControllerState => {
   // Live data state
   const [realtimeResults, setRealtimeResults] = useState<TestResult[]>([]);
   const [telemetryHistory, setTelemetryHistory] = useState<TelemetryUpdate[]>([]);
-  const [runningBatchId, setRunningBatchId] = useState<string | null>(null);
 
   // Auto-step testing state
   const [testQueue, setTestQueue] = useState<TestConfigType[]>([]);
@@ -52,133 +53,120 @@ export const useSpeedTestController = (): SpeedTestControllerState => {
   const { testStatus, startTest, updateProgress, completeTest, failTest } = useTestProgress();
   const completedTestsRef = useRef<Set<string>>(new Set());
 
-  // Poll for progress updates when test is running
+  // Follow the pushed events of the batch this hook started. Events that
+  // arrive before StartSpeedTest has returned the batch ID are held until it
+  // is known; events of other batches are ignored.
+  const runningBatchIdRef = useRef<string | null>(null);
+  const pendingEventsRef = useRef<SpeedTestEvent[] | null>(null);
+  const anyFailedRef = useRef(false);
+  const handleEventRef = useRef<(event: SpeedTestEvent) => void>(() => {});
+
   useEffect(() => {
-    if (!testStatus.isRunning) {
-      completedTestsRef.current.clear();
-      return;
-    }
+    const onEvent = (event: SpeedTestEvent) => {
+      if (runningBatchIdRef.current === null) {
+        pendingEventsRef.current?.push(event);
+        return;
+      }
+      if (event.batchId === runningBatchIdRef.current) {
+        handleEventRef.current(event);
+      }
+    };
 
-    const interval = setInterval(async () => {
-      try {
-        // Parallelize calls
-        const [progress, newResults, newTelemetry] = await Promise.all([
-          GetTestProgress(),
-          GetTestResults(),
-          GetTelemetryUpdates()
-        ]);
+    const unsubscribers = ['progress', 'result', 'telemetry', 'completed'].map(type =>
+      EventsOn(`speedtest:${type}`, onEvent)
+    );
+    return () => unsubscribers.forEach(unsubscribe => unsubscribe());
+  }, []);
 
-        // Update telemetry
-        if (newTelemetry && newTelemetry.length > 0) {
-          setTelemetryHistory(prev => {
-            const merged = [...prev, ...newTelemetry];
-            if (merged.length > MAX_TELEMETRY_POINTS) {
-              return merged.slice(merged.length - MAX_TELEMETRY_POINTS);
-            }
-            return merged;
-          });
+  const handleEvent = (event: SpeedTestEvent) => {
+    if (event.telemetry) {
+      const update = event.telemetry;
+      setTelemetryHistory(prev => {
+        const merged = [...prev, update];
+        if (merged.length > MAX_TELEMETRY_POINTS) {
+          return merged.slice(merged.length - MAX_TELEMETRY_POINTS);
         }
+        return merged;
+      });
+    }
 
-        // Update realtime results with an upper bound to avoid
-        // unbounded growth for very large test runs.
-        if (newResults && newResults.length > 0) {
-          setRealtimeResults(prev => {
-            const merged = [...prev, ...newResults];
-            if (merged.length > MAX_REALTIME_RESULTS) {
-              return merged.slice(merged.length - MAX_REALTIME_RESULTS);
-            }
-            return merged;
-          });
+    // Keep an upper bound on realtime results to avoid unbounded growth
+    // for very large test runs.
+    if (event.result) {
+      const result = event.result;
+      setRealtimeResults(prev => {
+        const merged = [...prev, result];
+        if (merged.length > MAX_REALTIME_RESULTS) {
+          return merged.slice(merged.length - MAX_REALTIME_RESULTS);
         }
+        return merged;
+      });
+    }
 
-        if (progress.length > 0) {
-          let anyFailed = false;
-          let hasRunningUpdates = false;
-          const lastUpdate = progress[progress.length - 1];
-
-          progress.forEach(update => {
-            if (update.status === 'running') {
-              hasRunningUpdates = true;
-              return;
-            }
-
-            completedTestsRef.current.add(update.testId);
-            if (update.status === 'failed') {
-              anyFailed = true;
-            }
-          });
-
-          const totalTests = lastUpdate.totalTests;
-          const completedCount = Math.min(completedTestsRef.current.size, totalTests);
-          const runningStatus = anyFailed ? '测试中 (部分失败)' : '测试进行中...';
-          const completionStatus = anyFailed ? '测试完成 (有错误)' : '测试完成';
-          const isComplete = completedCount >= totalTests;
-
-          // Format status for auto-testing
-          let displayStatus = isComplete && !hasRunningUpdates ? completionStatus : runningStatus;
-          if (isAutoTesting) {
-            const stepNum = autoTestTotal - testQueue.length;
-            displayStatus = `${displayStatus} (步骤 ${stepNum}/${autoTestTotal})`;
-          }
-
-          updateProgress(
-            completedCount,
-            totalTests,
-            displayStatus
-          );
-
-          if (isComplete) {
-            completeTest(completionStatus);
+    if (event.progress) {
+      const update = event.progress;
+      if (update.status !== 'running') {
+        completedTestsRef.current.add(update.testId);
+        if (update.status === 'failed') {
+          anyFailedRef.current = true;
+        }
+      }
 
-            // Get the final results
-            setTimeout(async () => {
-              try {
-                const batch = await GetTestBatch(lastUpdate.batchId);
-                if (batch) {
-                  setCurrentBatch(batch);
-                  setCurrentRunBatches(prev => [...prev, batch]);
-                  setCompletedBatches(prev => {
-                    const updated = [...prev, batch];
-                    if (updated.length > MAX_COMPLETED_BATCHES) {
-                      return updated.slice(updated.length - MAX_COMPLETED_BATCHES);
-                    }
-                    return updated;
-                  });
+      const completedCount = Math.min(completedTestsRef.current.size, update.totalTests);
+      let displayStatus = anyFailedRef.current ? '测试中 (部分失败)' : '测试进行中...';
+      if (isAutoTesting) {
+        const stepNum = autoTestTotal - testQueue.length;
+        displayStatus = `${displayStatus} (步骤 ${stepNum}/${autoTestTotal})`;
+      }
+      updateProgress(completedCount, update.totalTests, displayStatus);
+    }
 
-                  // Check if we have more tests in the queue
-                  if (testQueue.length > 0) {
-                    const nextConfig = testQueue[0];
-                    const remainingQueue = testQueue.slice(1);
-                    setTestQueue(remainingQueue);
+    if (event.type !== 'completed') {
+      return;
+    }
+    runningBatchIdRef.current = null;
+    completedTestsRef.current.clear();
 
-                    setTimeout(() => {
-                      runTest(nextConfig);
-                    }, 1000);
-                  } else {
-                    // All tests completed
-                    setIsAutoTesting(false);
-                    setAutoTestTotal(0);
-                    setActiveTab('charts');
-                  }
-                }
-              } catch (error) {
-                console.error('Failed to get test batch:', error);
-                setIsAutoTesting(false);
-              }
-            }, 500);
+    const batch = event.batch;
+    if (!batch) {
+      failTest(event.error || 'Unknown error');
+      setIsAutoTesting(false);
+      setTestQueue([]);
+      return;
+    }
 
-            completedTestsRef.current.clear();
-          }
-        }
-      } catch (error) {
-        console.error('Failed to get progress:', error);
+    completeTest(anyFailedRef.current ? '测试完成 (有错误)' : '测试完成');
+    setCurrentBatch(batch);
+    setCurrentRunBatches(prev => [...prev, batch]);
+    setCompletedBatches(prev => {
+      const updated = [...prev, batch];
+      if (updated.length > MAX_COMPLETED_BATCHES) {
+        return updated.slice(updated.length - MAX_COMPLETED_BATCHES);
       }
-    }, 500); // Increased polling rate for smoother UI
+      return updated;
+    });
 
-    return () => clearInterval(interval);
-  }, [testStatus.isRunning, updateProgress, completeTest, testQueue, isAutoTesting, autoTestTotal]);
+    // Check if we have more tests in the queue
+    if (testQueue.length > 0) {
+      const nextConfig = testQueue[0];
+      setTestQueue(testQueue.slice(1));
+
+      setTimeout(() => {
+        runTest(nextConfig);
+      }, 1000);
+    } else {
+      // All tests completed
+      setIsAutoTesting(false);
+      setAutoTestTotal(0);
+      setActiveTab('charts');
+    }
+  };
+  handleEventRef.current = handleEvent;
 
   const runTest = async (config: TestConfigType) => {
+    runningBatchIdRef.current = null;
+    pendingEventsRef.current = [];
+    anyFailedRef.current = false;
     try {
       completedTestsRef.current.clear();
       setRealtimeResults([]);
@@ -214,10 +202,16 @@ export const useSpeedTestController = (): SpeedTestControllerState => {
       }
       startTest(totalPlannedTests);
       const batch = await StartSpeedTest(config);
+      const pending = pendingEventsRef.current ?? [];
+      pendingEventsRef.current = null;
       if (batch && batch.id) {
-        setRunningBatchId(batch.id);
+        runningBatchIdRef.current = batch.id;
+        pending
+          .filter(event => event.batchId === batch.id)
+          .forEach(event => handleEventRef.current(event));
       }
     } catch (error) {
+      pendingEventsRef.current = null;
       failTest(error instanceof Error ? error.message : 'Unknown error');
       setIsAutoTesting(false);
       setTestQueue([]);
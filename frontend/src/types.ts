@@ -1,4 +1,4 @@
-export type TestMode = 'normal' | 'concurrency_step' | 'input_step';
+export type TestMode = 'normal' | 'concurrency_step' | 'input_step' | 'image_count_step' | 'image_resolution_step' | 'sweep' | 'rate' | 'rate_step' | 'conversation';
 
 export interface StepConfiguration {
   start: number;
@@ -6,6 +6,83 @@ export interface StepConfiguration {
   step: number;
 }
 
+// One swept numeric field (by JSON path), over explicit values or an inclusive range
+export interface SweepDimension {
+  field: string;
+  values?: number[];
+  start?: number;
+  end?: number;
+  step?: number;
+}
+
+export type ArrivalProcess = 'constant' | 'poisson' | 'burst';
+
+export interface RateConfiguration {
+  requestRate: number;             // Target arrival rate (req/s)
+  arrivalProcess: ArrivalProcess;
+  burstSize: number;               // Requests released together in burst mode
+  numRequests: number;             // Requests sent per rate
+  maxInFlight: number;             // 0 = unlimited
+  rateStart?: number;              // rate_step: first rate (req/s)
+  rateEnd?: number;                // rate_step: last rate (req/s)
+  rateStep?: number;               // rate_step: increment (req/s)
+}
+
+// Thresholds checked at every rate_step step, at least one must be set.
+// A latency of 0 disables its check; maxErrorRate is checked whenever
+// present, so 0 allows no errors.
+export interface SLOConfiguration {
+  maxP95Ttft: number;     // ms
+  maxP95Latency: number;  // ms
+  maxP95Tpot: number;     // ms
+  maxErrorRate?: number;  // 0-1
+}
+
+export interface DatasetConfiguration {
+  path: string;
+  format: '' | 'sharegpt' | 'jsonl'; // '' detects from the file extension
+  seed: number;                      // 0 = derived from the test seed
+  minPromptTokens: number;           // 0 = no limit
+  maxPromptTokens: number;           // 0 = no limit
+}
+
+export interface ConversationConfiguration {
+  sessions: number;   // run concurrentTests at a time
+  turns: number;      // requests per session, each resending the history
+  turnLength: number; // tokens of each user message after the first, 0 = promptLength
+}
+
+export interface PrefixConfiguration {
+  mode: '' | 'shared' | 'unique'; // '' = plain random prompts
+  length: number;                 // shared prefix tokens, part of promptLength
+  hitRatio: number;               // 0-1 share of requests reusing the shared prefix
+  systemPrompt: boolean;          // send the shared prefix as a system message
+}
+
+// Tools sent with every request; path replaces the first `count` built-in ones
+export interface ToolConfiguration {
+  count: number;      // built-in tool definitions, 0 = none unless path is set
+  path: string;       // JSON array of OpenAI tool definitions
+  toolChoice: string; // 'required' (default), 'auto' or a tool name to force
+}
+
+// Every other request is constrained to the schema; the rest are the baseline
+export interface StructuredOutputConfiguration {
+  schemaPath: string;                         // JSON schema file, empty = unconstrained
+  method: '' | 'response_format' | 'guided_json'; // '' = response_format
+}
+
+// Images attached to every request as base64 data URLs; generated noise
+// images are used when no files are given
+export interface VisionConfiguration {
+  images?: string[];  // local image files, sent in turn
+  count: number;      // images per request, 0 = text only
+  resolution: number; // longest side in pixels, 0 = original size (files only)
+  detail: '' | 'auto' | 'low' | 'high'; // '' = server default
+}
+
+export type Provider = 'openai' | 'openai_completions' | 'anthropic' | 'ollama' | 'tgi';
+
 export interface SavedApiConfig {
   id: string;
   name: string;
@@ -16,10 +93,16 @@ export interface SavedApiConfig {
 export interface TestConfiguration {
   apiEndpoint: string;
   apiKey: string;
+  provider?: Provider;     // defaults to 'openai'
   model: string;
-  promptType: string;      // "fixed" or "custom"
+  promptType: string;      // "fixed", "simple", "complex", "custom" or "dataset"
   promptLength: number;    // Length of prompt in tokens
   prompt: string;          // Custom prompt content (if enabled)
+  datasetConfig?: DatasetConfiguration; // Workload file (if promptType is "dataset")
+  prefixConfig?: PrefixConfiguration;   // Prefix sharing of synthetic prompts
+  toolConfig?: ToolConfiguration;       // Tool definitions sent with every request
+  structuredOutput?: StructuredOutputConfiguration; // JSON schema half of the requests are constrained to
+  visionConfig?: VisionConfiguration;   // Images sent with every request
   maxTokens: number;
   temperature: number;
   topP: number;
@@ -29,11 +112,18 @@ export interface TestConfiguration {
   // Test mode & step configuration
   testMode: TestMode;
   stepConfig: StepConfiguration;
+  sweep?: SweepDimension[]; // sweep mode: every combination of the values is run
+  rateConfig?: RateConfiguration;
+  slo?: SLOConfiguration;
+  conversationConfig?: ConversationConfiguration;
 
   testCount: number;       // Number of submission rounds (per step when stepped)
   concurrentTests: number; // Requests per round (base/fixed concurrency)
   timeout: number;         // Timeout in seconds
   headers?: Record<string, string>;
+  exactPromptLength?: boolean; // calibrate synthetic prompts against the server's reported prompt tokens
+  tokenizer?: string; // HuggingFace tokenizer.json or its directory; empty = mapped or tiktoken
+  seed?: number;      // drives prompts, dataset sampling and arrivals; 0 = random
 }
 
 export interface PersistedTestState {
@@ -45,11 +135,25 @@ export interface PersistedTestState {
   inputStepCount: number;
 }
 
+export interface HistoryRetentionPolicy {
+  maxBatches: number;  // 0 = unlimited
+  maxAgeDays: number;  // 0 = never expire
+  keepTagged: boolean; // tagged batches are never pruned
+}
+
 export interface PersistedAppConfig {
   testState?: PersistedTestState;
   savedApiConfigs?: SavedApiConfig[];
   lastValidApiEndpoint?: string;
   lastValidApiKey?: string;
+  historyRetention?: HistoryRetentionPolicy; // unset = keep the last 50 batches
+  metricsAddress?: string; // Prometheus /metrics listen address, empty = disabled
+  tokenizers?: TokenizerMapping[];
+}
+
+export interface TokenizerMapping {
+  model: string; // model name or pattern such as "Qwen/*"; first match wins
+  path: string;  // tokenizer.json or a directory containing it
 }
 
 export interface TestResult {
@@ -62,6 +166,7 @@ export interface TestResult {
   actualConcurrency: number;
   promptTokens: number;
   completionTokens: number;
+  localCompletionTokens?: number; // counted from the response text with the local tokenizer
   totalTokens: number;
   requestLatency: number; // in milliseconds
   totalLatency: number;   // in milliseconds
@@ -69,7 +174,33 @@ export interface TestResult {
   prefillTokensPerSecond: number;
   outputTokensPerSecond: number;
   throughput: number;
+  timePerOutputToken: number;   // ms, output latency / (completion tokens - 1)
+  interTokenLatency: number;    // ms, mean gap between streamed chunks
+  interTokenLatencyP50: number; // ms
+  interTokenLatencyP99: number; // ms
+  maxStall: number;             // ms, longest gap between streamed chunks
+  queueDelay: number;           // ms between scheduled arrival and dispatch (rate mode)
+  sweepPoint?: Record<string, number>; // swept field values (step and sweep modes)
+  session?: number; // conversation mode: session the request belongs to
+  turn?: number;    // conversation mode: 1-based turn within the session
+  prefixCache?: 'warm' | 'cold'; // shared prefix mode: whether the prompt reused the shared prefix
+  toolCalls?: number;              // tool tests: calls in the response
+  timeToFirstToolCall: number;     // ms
+  toolCallTokens: number;          // tokens of all call arguments
+  toolCallTokensPerSecond: number; // argument tokens over the time they streamed in
+  structuredOutput?: 'constrained' | 'baseline'; // structured output tests: whether the output was held to the schema
+  schemaError?: string;                          // why a constrained output does not follow the schema
+  timeToFirstReasoningToken: number; // ms, 0 when the model did not reason
+  timeToFirstAnswerToken: number;    // ms until the first answer token after the reasoning
+  reasoningTokens: number;           // server-reported when available, else counted locally
+  answerTokens: number;
+  reasoningTokensPerSecond: number;
+  answerTokensPerSecond: number;
+  images?: number;          // vision tests: images sent with the prompt
+  imageResolution?: number; // longest side in pixels of the largest image
+  imageTokens?: number;     // server prompt tokens beyond the text
   error?: string;
+  errorKind?: 'malformed_tool_call' | 'missing_tool_call';
   success: boolean;
   response?: string;
 }
@@ -100,6 +231,31 @@ export interface TestSummary {
   minRoundThroughput: number;
   maxRoundThroughput: number;
   errorRate: number;
+  latencyDistribution: DistributionStats;               // total latency (ms)
+  prefillLatencyDistribution: DistributionStats;        // TTFT (ms)
+  outputLatencyDistribution: DistributionStats;         // output latency (ms)
+  outputTokensPerSecondDistribution: DistributionStats; // decode tokens/sec
+  averageTimePerOutputToken: number;                    // ms
+  timePerOutputTokenDistribution: DistributionStats;
+  averageInterTokenLatency: number;                     // ms
+  interTokenLatencyDistribution: DistributionStats;     // pooled over all chunk gaps
+  maxStall: number;                                     // ms
+  averageQueueDelay: number;                            // ms (rate mode)
+  queueDelayDistribution: DistributionStats;
+  usageDeviation?: number; // mean (reported - local) / local completion tokens
+  prefixCache?: PrefixCacheSummary; // warm vs cold TTFT in shared prefix mode
+  toolCalls?: ToolCallSummary;      // tool call timing and failures in tool tests
+  structuredOutput?: StructuredOutputSummary; // constrained vs baseline decoding
+  reasoning?: ReasoningSummary;               // thinking vs answer of reasoning models
+  vision?: VisionSummary;                     // image share of the prompt in vision tests
+}
+
+export interface DistributionStats {
+  p50: number;
+  p90: number;
+  p95: number;
+  p99: number;
+  stdDev: number;
 }
 
 export interface RoundSummary {
@@ -118,16 +274,135 @@ export interface RoundSummary {
   totalPrefillTokensPerSecond: number;
   averageOutputTokensPerSecond: number;
   totalOutputTokensPerSecond: number;
+  totalLatencyDistribution?: DistributionStats;
+  prefillLatencyDistribution?: DistributionStats;
+  outputLatencyDistribution?: DistributionStats;
+  outputTokensPerSecondDistribution?: DistributionStats;
 }
 
 export interface TestBatch {
   id: string;
+  name?: string;
+  tags?: string[];
+  baseline?: boolean; // reference run for regression checks
+  matrixId?: string; // set when the batch is one target of a matrix run
+  replayOf?: string; // ID of the batch this one replays
+  seed?: number;     // seed the prompts and arrivals were generated from
   startTime: string;
   endTime: string;
   configuration: TestConfiguration;
   results: TestResult[];
   roundSummaries?: RoundSummary[];
   summary: TestSummary;
+  rateSweep?: RateSweepResult;
+  sweepPoints?: SweepPointSummary[];
+  turns?: TurnSummary[]; // conversation mode: one entry per turn over all sessions
+  promptCalibration?: PromptCalibration; // set when exactPromptLength is enabled
+}
+
+export interface PromptCalibration {
+  templateOverhead: number; // server prompt tokens minus local tokens for the first probe
+  lengths: PromptLengthCalibration[];
+}
+
+export interface PromptLengthCalibration {
+  targetTokens: number;
+  localTokens: number;  // local tokenizer length prompts are generated at
+  serverTokens: number; // prompt tokens the server reported for the closest probe
+  probes: number;
+}
+
+export interface PrefixCacheSummary {
+  warmRequests: number;
+  coldRequests: number;
+  averageWarmTtft: number; // ms
+  averageColdTtft: number; // ms
+  warmTtftDistribution: DistributionStats;
+  coldTtftDistribution: DistributionStats;
+  ttftSpeedup: number;     // median cold / median warm TTFT, 0 without both
+}
+
+export interface ToolCallSummary {
+  requests: number;          // requests sent with tools
+  requestsWithCalls: number; // successful requests that returned a tool call
+  malformedCalls: number;    // calls with unparseable arguments
+  missingCalls: number;      // text answers although a call was required
+  malformedRate: number;     // malformedCalls / requests
+  averageTimeToFirstToolCall: number; // ms
+  timeToFirstToolCallDistribution: DistributionStats;
+  averageToolCallTokensPerSecond: number;
+  toolCallTokensPerSecondDistribution: DistributionStats;
+}
+
+export interface StructuredOutputSummary {
+  constrainedRequests: number; // successful constrained requests
+  baselineRequests: number;
+  compliantRequests: number;   // constrained outputs following the schema
+  complianceRate: number;      // compliantRequests / constrainedRequests
+  averageConstrainedTps: number;
+  averageBaselineTps: number;
+  constrainedTpsDistribution: DistributionStats;
+  baselineTpsDistribution: DistributionStats;
+  constrainedTtftDistribution: DistributionStats; // ms
+  baselineTtftDistribution: DistributionStats;    // ms
+  decodeOverhead: number;      // 1 - median constrained / median baseline tok/s
+}
+
+export interface ReasoningSummary {
+  requests: number; // successful requests with reasoning tokens
+  averageReasoningTokens: number;
+  averageAnswerTokens: number;
+  averageTimeToFirstReasoningToken: number; // ms
+  timeToFirstReasoningTokenDistribution: DistributionStats;
+  averageTimeToFirstAnswerToken: number;    // ms
+  timeToFirstAnswerTokenDistribution: DistributionStats;
+  averageReasoningTokensPerSecond: number;
+  reasoningTokensPerSecondDistribution: DistributionStats;
+  averageAnswerTokensPerSecond: number;
+  answerTokensPerSecondDistribution: DistributionStats;
+}
+
+// TTFT here includes the server's image preprocessing and vision encoding
+export interface VisionSummary {
+  requests: number; // successful requests with images
+  averageImages: number;
+  averageImageTokens: number;
+  imageTokenShare: number; // image tokens / prompt tokens (0-1)
+  averageTTFT: number;     // ms
+  ttftDistribution: DistributionStats;
+}
+
+export interface SweepPointSummary {
+  coordinates: Record<string, number>;
+  summary: TestSummary;
+  duration: number;                   // ms
+  totalOutputTokensPerSecond: number;
+}
+
+export interface TurnSummary {
+  turn: number;
+  averagePromptTokens: number; // context sent with the turn, history included
+  summary: TestSummary;
+}
+
+export interface RateStepSummary {
+  requestRate: number;                // target arrival rate (req/s)
+  achievedRate: number;               // completed requests per second
+  totalRequests: number;
+  successfulRequests: number;
+  errorRate: number;
+  p95Ttft: number;                    // ms
+  p95Latency: number;                 // ms
+  p95Tpot: number;                    // ms
+  averageQueueDelay: number;          // ms
+  totalOutputTokensPerSecond: number;
+  meetsSlo: boolean;
+  violations?: string[];
+}
+
+export interface RateSweepResult {
+  goodput: number; // highest rate (req/s) that met every SLO
+  steps: RateStepSummary[];
 }
 
 export interface ProgressUpdate {
@@ -139,9 +414,48 @@ export interface ProgressUpdate {
   message?: string;
 }
 
+// Payload of the Wails events speedtest:progress, speedtest:result,
+// speedtest:telemetry and speedtest:completed.
+export interface SpeedTestEvent {
+  type: 'progress' | 'result' | 'telemetry' | 'completed';
+  batchId: string;
+  model?: string;
+  step?: number;     // 1-based step index
+  timestamp: number; // Unix ms
+  progress?: ProgressUpdate;
+  result?: TestResult;
+  telemetry?: TelemetryUpdate;
+  batch?: TestBatch; // completed events of successful runs
+  error?: string;    // completed events of failed runs
+}
+
 export interface ComparisonResult {
   batches: TestBatch[];
   comparison: ComparisonSummary;
+  matrixId?: string;
+  targets?: MatrixTargetSummary[];
+}
+
+// One endpoint/model pair of a matrix run
+export interface MatrixTarget {
+  id?: string;
+  name: string;
+  apiEndpoint: string;
+  apiKey: string;
+  provider?: string;
+  model: string;
+  batchId?: string;
+  tokenizer?: string;
+}
+
+export interface MatrixTargetSummary {
+  name: string;
+  apiEndpoint: string;
+  provider?: string;
+  model: string;
+  batchId: string;
+  summary: TestSummary;
+  error?: string;
 }
 
 export interface ComparisonSummary {
@@ -149,11 +463,43 @@ export interface ComparisonSummary {
   bestThroughputBatchId: string;
   bestRoundThroughputBatchId: string;
   lowestErrorRateBatchId: string;
+  bestP95TtftBatchId: string;
+  bestP95LatencyBatchId: string;
+}
+
+export interface RegressionTolerances {
+  maxTtftIncrease: number;      // relative, 0.1 = +10% median TTFT
+  maxLatencyIncrease: number;
+  maxTpotIncrease: number;
+  maxOutputTpsDecrease: number; // relative, 0.1 = -10% median output tok/s
+  maxErrorRateIncrease: number; // absolute, 0.01 = +1 point
+  significance: number;         // p-value threshold, 0 = 0.05
+}
+
+export interface RegressionCheck {
+  metric: string;
+  unit: string;
+  baseline: number;
+  candidate: number;
+  change: number; // positive = worse
+  tolerance: number;
+  pValue: number;
+  verdict: 'pass' | 'regressed' | 'improved' | 'skipped';
+}
+
+export interface RegressionReport {
+  baselineId: string;
+  candidateId: string;
+  tolerances: RegressionTolerances;
+  passed: boolean;
+  checks: RegressionCheck[];
+  warnings?: string[];
+  report: string; // plain-text diff
 }
 
 export interface ExportOptions {
   includeCharts?: boolean;
-  chartTypes?: ('latency' | 'throughput' | 'roundThroughput')[];
+  chartTypes?: ('latency' | 'throughput' | 'roundThroughput' | 'reasoningThroughput' | 'answerThroughput')[];
   dateFormat?: string;
 }
 
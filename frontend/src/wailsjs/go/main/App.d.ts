@@ -7,6 +7,7 @@ import type {
   ProgressUpdate,
   ComparisonResult,
   ExportOptions,
+  HistoryRetentionPolicy,
 } from '../../../types';
 
 export function GetAppVersion():Promise<string>;
@@ -20,6 +21,11 @@ export function GetTestResults():Promise<Array<TestResult>>;
 export function GetTelemetryUpdates():Promise<Array<TelemetryUpdate>>;
 export function GetTestBatch(arg1:string):Promise<TestBatch>;
 export function GetAllTestBatches():Promise<Array<TestBatch>>;
+export function DeleteTestBatch(arg1:string):Promise<void>;
+export function RenameTestBatch(arg1:string,arg2:string):Promise<void>;
+export function SetTestBatchTags(arg1:string,arg2:Array<string>):Promise<void>;
+export function GetHistoryRetention():Promise<HistoryRetentionPolicy>;
+export function SetHistoryRetention(arg1:HistoryRetentionPolicy):Promise<void>;
 export function CompareTestBatches(arg1:Array<string>):Promise<ComparisonResult>;
 export function ExportTestData(arg1:string,arg2:string,arg3:ExportOptions):Promise<string>;
 export function GetExportDirectory():Promise<string>;
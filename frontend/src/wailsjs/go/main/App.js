@@ -88,6 +88,52 @@ export function GetAllTestBatches() {
   return window.go.main.App.GetAllTestBatches();
 }
 
+/**
+ * Delete a stored test batch
+ * @param {string} batchId - Batch ID
+ * @returns {Promise<void>}
+ */
+export function DeleteTestBatch(batchId) {
+  return window.go.main.App.DeleteTestBatch(batchId);
+}
+
+/**
+ * Rename a stored test batch
+ * @param {string} batchId - Batch ID
+ * @param {string} name - New display name (empty clears it)
+ * @returns {Promise<void>}
+ */
+export function RenameTestBatch(batchId, name) {
+  return window.go.main.App.RenameTestBatch(batchId, name);
+}
+
+/**
+ * Replace the tags of a stored test batch
+ * @param {string} batchId - Batch ID
+ * @param {Array<string>} tags - Tags
+ * @returns {Promise<void>}
+ */
+export function SetTestBatchTags(batchId, tags) {
+  return window.go.main.App.SetTestBatchTags(batchId, tags);
+}
+
+/**
+ * Get the history retention policy
+ * @returns {Promise<import('../../../types').HistoryRetentionPolicy>}
+ */
+export function GetHistoryRetention() {
+  return window.go.main.App.GetHistoryRetention();
+}
+
+/**
+ * Update the history retention policy
+ * @param {import('../../../types').HistoryRetentionPolicy} policy - Retention policy
+ * @returns {Promise<void>}
+ */
+export function SetHistoryRetention(policy) {
+  return window.go.main.App.SetHistoryRetention(policy);
+}
+
 /**
  * Compare test batches
  * @param {Array<string>} batchIds - Array of batch IDs
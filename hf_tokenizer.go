@@ -0,0 +1,675 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dlclark/regexp2"
+)
+
+// gpt2SplitPattern is the pre-tokenization regex ByteLevel applies when
+// use_regex is set.
+const gpt2SplitPattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+
+// maxHFWordCacheSize bounds the per-tokenizer cache of word token counts.
+// Streamed output keeps adding new words, so the cache is reset rather
+// than allowed to grow without limit.
+const maxHFWordCacheSize = 100000
+
+// hfTokenizer counts tokens with a HuggingFace tokenizer.json using a BPE
+// model, which covers the byte-level tokenizers of Qwen, Llama 3 and
+// DeepSeek as well as the SentencePiece-style tokenizers of Llama 2 and
+// Mistral. Tokens a post-processor would add (e.g. BOS) and Unicode
+// normalization forms are not applied. Counting only reads the loaded
+// tables, so one tokenizer can serve concurrent requests.
+type hfTokenizer struct {
+	addedTokens *regexp2.Regexp
+	normalizers []hfNormalizer
+	preTokenize []hfPreTokenizer
+	byteLevel   bool
+
+	vocab        map[string]int
+	merges       map[[2]string]int
+	ignoreMerges bool
+	byteFallback bool
+	fuseUnknown  bool
+
+	mu    sync.Mutex
+	words map[string]int
+}
+
+// hfNormalizer rewrites the text of one section before it is split.
+type hfNormalizer func(text string) string
+
+// hfPreTokenizer splits pieces into smaller pieces.
+type hfPreTokenizer func(pieces []hfPiece) []hfPiece
+
+// hfPiece is a span of text that BPE is applied to on its own. first
+// reports whether it starts the input, for Metaspace's "first" prepend
+// scheme.
+type hfPiece struct {
+	text  string
+	first bool
+}
+
+type hfTokenizerFile struct {
+	AddedTokens []struct {
+		Content string `json:"content"`
+	} `json:"added_tokens"`
+	Normalizer   *hfComponent `json:"normalizer"`
+	PreTokenizer *hfComponent `json:"pre_tokenizer"`
+	Model        struct {
+		Type                    string            `json:"type"`
+		Vocab                   map[string]int    `json:"vocab"`
+		Merges                  []json.RawMessage `json:"merges"`
+		UnkToken                *string           `json:"unk_token"`
+		ContinuingSubwordPrefix *string           `json:"continuing_subword_prefix"`
+		EndOfWordSuffix         *string           `json:"end_of_word_suffix"`
+		FuseUnk                 bool              `json:"fuse_unk"`
+		ByteFallback            bool              `json:"byte_fallback"`
+		IgnoreMerges            bool              `json:"ignore_merges"`
+	} `json:"model"`
+}
+
+// hfComponent is a normalizer or pre-tokenizer entry. Only the fields of
+// the supported types are decoded.
+type hfComponent struct {
+	Type          string         `json:"type"`
+	Normalizers   []*hfComponent `json:"normalizers"`
+	PreTokenizers []*hfComponent `json:"pretokenizers"`
+
+	// Split and Replace
+	Pattern  *hfPattern `json:"pattern"`
+	Behavior string     `json:"behavior"`
+	Invert   bool       `json:"invert"`
+	Content  string     `json:"content"`
+
+	// Prepend
+	Prepend string `json:"prepend"`
+
+	// ByteLevel and Metaspace
+	AddPrefixSpace *bool  `json:"add_prefix_space"`
+	UseRegex       *bool  `json:"use_regex"`
+	Replacement    string `json:"replacement"`
+	PrependScheme  string `json:"prepend_scheme"`
+	Split          *bool  `json:"split"`
+
+	// Digits
+	IndividualDigits bool `json:"individual_digits"`
+}
+
+type hfPattern struct {
+	String *string `json:"String"`
+	Regex  *string `json:"Regex"`
+}
+
+var (
+	hfTokenizerCacheMu sync.Mutex
+	hfTokenizerCache   = make(map[string]*hfTokenizer)
+)
+
+// loadHFTokenizer loads the tokenizer.json at path, or inside path when it
+// is a directory. Tokenizers are cached by path, since a large vocabulary
+// takes a noticeable time to parse.
+func loadHFTokenizer(path string) (*hfTokenizer, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "tokenizer.json")
+	}
+
+	hfTokenizerCacheMu.Lock()
+	defer hfTokenizerCacheMu.Unlock()
+
+	if tok, exists := hfTokenizerCache[path]; exists {
+		return tok, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer: %w", err)
+	}
+	tok, err := parseHFTokenizer(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tokenizer %s: %w", path, err)
+	}
+	hfTokenizerCache[path] = tok
+	return tok, nil
+}
+
+func parseHFTokenizer(data []byte) (*hfTokenizer, error) {
+	var file hfTokenizerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	model := file.Model
+	if model.Type != "BPE" {
+		return nil, fmt.Errorf("unsupported tokenizer model %q: only BPE is supported", model.Type)
+	}
+	if len(model.Vocab) == 0 {
+		return nil, fmt.Errorf("tokenizer has an empty vocabulary")
+	}
+	if (model.ContinuingSubwordPrefix != nil && *model.ContinuingSubwordPrefix != "") ||
+		(model.EndOfWordSuffix != nil && *model.EndOfWordSuffix != "") {
+		return nil, fmt.Errorf("BPE subword prefixes and suffixes are not supported")
+	}
+
+	tok := &hfTokenizer{
+		vocab:        model.Vocab,
+		merges:       make(map[[2]string]int, len(model.Merges)),
+		ignoreMerges: model.IgnoreMerges,
+		byteFallback: model.ByteFallback,
+		fuseUnknown:  model.FuseUnk,
+		words:        make(map[string]int),
+	}
+
+	// Merges are either "a b" strings or ["a", "b"] pairs depending on the
+	// tokenizers version that saved the file.
+	for rank, raw := range model.Merges {
+		var pair [2]string
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			left, right, ok := strings.Cut(text, " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid merge %q", text)
+			}
+			pair = [2]string{left, right}
+		} else if err := json.Unmarshal(raw, &pair); err != nil {
+			return nil, fmt.Errorf("invalid merge %s", raw)
+		}
+		if _, exists := tok.merges[pair]; !exists {
+			tok.merges[pair] = rank
+		}
+	}
+
+	if len(file.AddedTokens) > 0 {
+		contents := make([]string, 0, len(file.AddedTokens))
+		for _, added := range file.AddedTokens {
+			if added.Content != "" {
+				contents = append(contents, added.Content)
+			}
+		}
+		// Longest first, so a token is not matched by one of its prefixes.
+		sort.Slice(contents, func(i, j int) bool { return len(contents[i]) > len(contents[j]) })
+		for i, content := range contents {
+			contents[i] = regexp2.Escape(content)
+		}
+		if len(contents) > 0 {
+			re, err := regexp2.Compile(strings.Join(contents, "|"), regexp2.None)
+			if err != nil {
+				return nil, fmt.Errorf("invalid added tokens: %w", err)
+			}
+			tok.addedTokens = re
+		}
+	}
+
+	if err := tok.addNormalizer(file.Normalizer); err != nil {
+		return nil, err
+	}
+	if err := tok.addPreTokenizer(file.PreTokenizer); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
+
+func (t *hfTokenizer) addNormalizer(c *hfComponent) error {
+	if c == nil {
+		return nil
+	}
+
+	switch c.Type {
+	case "Sequence":
+		for _, child := range c.Normalizers {
+			if err := t.addNormalizer(child); err != nil {
+				return err
+			}
+		}
+	case "Prepend":
+		prepend := c.Prepend
+		t.normalizers = append(t.normalizers, func(text string) string {
+			if text == "" {
+				return text
+			}
+			return prepend + text
+		})
+	case "Replace":
+		replace, err := hfPatternReplacer(c.Pattern, c.Content)
+		if err != nil {
+			return err
+		}
+		t.normalizers = append(t.normalizers, replace)
+	case "Lowercase":
+		t.normalizers = append(t.normalizers, strings.ToLower)
+	case "Strip":
+		t.normalizers = append(t.normalizers, strings.TrimSpace)
+	case "NFC", "NFD", "NFKC", "NFKD":
+		// Generated prompts and most model output are already normalized.
+	default:
+		return fmt.Errorf("unsupported normalizer %q", c.Type)
+	}
+	return nil
+}
+
+func (t *hfTokenizer) addPreTokenizer(c *hfComponent) error {
+	if c == nil {
+		return nil
+	}
+
+	switch c.Type {
+	case "Sequence":
+		for _, child := range c.PreTokenizers {
+			if err := t.addPreTokenizer(child); err != nil {
+				return err
+			}
+		}
+	case "Split":
+		re, err := hfPatternRegexp(c.Pattern)
+		if err != nil {
+			return err
+		}
+		behavior, invert := c.Behavior, c.Invert
+		switch behavior {
+		case "Isolated", "Removed", "MergedWithPrevious", "MergedWithNext", "Contiguous":
+		default:
+			return fmt.Errorf("unsupported split behavior %q", behavior)
+		}
+		t.preTokenize = append(t.preTokenize, func(pieces []hfPiece) []hfPiece {
+			return splitHFPieces(pieces, re, behavior, invert)
+		})
+	case "ByteLevel":
+		t.byteLevel = true
+		addPrefixSpace := c.AddPrefixSpace != nil && *c.AddPrefixSpace
+		if c.UseRegex == nil || *c.UseRegex {
+			re := regexp2.MustCompile(gpt2SplitPattern, regexp2.None)
+			t.preTokenize = append(t.preTokenize, func(pieces []hfPiece) []hfPiece {
+				if addPrefixSpace {
+					pieces = prefixHFPieces(pieces)
+				}
+				return splitHFPieces(pieces, re, "Isolated", false)
+			})
+		} else if addPrefixSpace {
+			t.preTokenize = append(t.preTokenize, prefixHFPieces)
+		}
+	case "Metaspace":
+		replacement := c.Replacement
+		if replacement == "" {
+			replacement = "▁"
+		}
+		scheme := c.PrependScheme
+		if scheme == "" {
+			scheme = "always"
+			if c.AddPrefixSpace != nil && !*c.AddPrefixSpace {
+				scheme = "never"
+			}
+		}
+		split := c.Split == nil || *c.Split
+		t.preTokenize = append(t.preTokenize, func(pieces []hfPiece) []hfPiece {
+			return metaspaceHFPieces(pieces, replacement, scheme, split)
+		})
+	case "Digits":
+		pattern := `\p{N}+`
+		if c.IndividualDigits {
+			pattern = `\p{N}`
+		}
+		re := regexp2.MustCompile(pattern, regexp2.None)
+		t.preTokenize = append(t.preTokenize, func(pieces []hfPiece) []hfPiece {
+			return splitHFPieces(pieces, re, "Isolated", false)
+		})
+	case "Punctuation":
+		re := regexp2.MustCompile(`\p{P}`, regexp2.None)
+		t.preTokenize = append(t.preTokenize, func(pieces []hfPiece) []hfPiece {
+			return splitHFPieces(pieces, re, "Isolated", false)
+		})
+	case "WhitespaceSplit":
+		re := regexp2.MustCompile(`\s+`, regexp2.None)
+		t.preTokenize = append(t.preTokenize, func(pieces []hfPiece) []hfPiece {
+			return splitHFPieces(pieces, re, "Removed", false)
+		})
+	default:
+		return fmt.Errorf("unsupported pre-tokenizer %q", c.Type)
+	}
+	return nil
+}
+
+func hfPatternRegexp(p *hfPattern) (*regexp2.Regexp, error) {
+	switch {
+	case p == nil:
+		return nil, fmt.Errorf("pattern is missing")
+	case p.Regex != nil:
+		re, err := regexp2.Compile(*p.Regex, regexp2.None)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", *p.Regex, err)
+		}
+		return re, nil
+	case p.String != nil:
+		return regexp2.MustCompile(regexp2.Escape(*p.String), regexp2.None), nil
+	}
+	return nil, fmt.Errorf("pattern is missing")
+}
+
+func hfPatternReplacer(p *hfPattern, content string) (hfNormalizer, error) {
+	if p != nil && p.String != nil {
+		old := *p.String
+		return func(text string) string { return strings.ReplaceAll(text, old, content) }, nil
+	}
+	re, err := hfPatternRegexp(p)
+	if err != nil {
+		return nil, err
+	}
+	return func(text string) string {
+		if replaced, err := re.Replace(text, content, -1, -1); err == nil {
+			return replaced
+		}
+		return text
+	}, nil
+}
+
+// splitHFPieces splits every piece on the matches of re, following the
+// HuggingFace Split behaviours. With invert the matches are the pieces to
+// keep and the text between them is what gets split off.
+func splitHFPieces(pieces []hfPiece, re *regexp2.Regexp, behavior string, invert bool) []hfPiece {
+	var out []hfPiece
+	for _, piece := range pieces {
+		type span struct {
+			text  string
+			match bool
+		}
+		var spans []span
+		runes := []rune(piece.text)
+		last := 0
+		m, _ := re.FindStringMatch(piece.text)
+		for m != nil {
+			if m.Length == 0 {
+				m, _ = re.FindNextMatch(m)
+				continue
+			}
+			if m.Index > last {
+				spans = append(spans, span{string(runes[last:m.Index]), invert})
+			}
+			spans = append(spans, span{m.String(), !invert})
+			last = m.Index + m.Length
+			m, _ = re.FindNextMatch(m)
+		}
+		if last < len(runes) {
+			spans = append(spans, span{string(runes[last:]), invert})
+		}
+
+		var parts []string
+		switch behavior {
+		case "Removed":
+			for _, s := range spans {
+				if !s.match {
+					parts = append(parts, s.text)
+				}
+			}
+		case "MergedWithPrevious":
+			for _, s := range spans {
+				if s.match && len(parts) > 0 {
+					parts[len(parts)-1] += s.text
+				} else {
+					parts = append(parts, s.text)
+				}
+			}
+		case "MergedWithNext":
+			pending := ""
+			for _, s := range spans {
+				if s.match {
+					if pending != "" {
+						parts = append(parts, pending)
+					}
+					pending = s.text
+					continue
+				}
+				parts = append(parts, pending+s.text)
+				pending = ""
+			}
+			if pending != "" {
+				parts = append(parts, pending)
+			}
+		case "Contiguous":
+			for i, s := range spans {
+				if i > 0 && s.match && spans[i-1].match {
+					parts[len(parts)-1] += s.text
+				} else {
+					parts = append(parts, s.text)
+				}
+			}
+		default: // Isolated
+			for _, s := range spans {
+				parts = append(parts, s.text)
+			}
+		}
+
+		for i, part := range parts {
+			out = append(out, hfPiece{text: part, first: piece.first && i == 0})
+		}
+	}
+	return out
+}
+
+// prefixHFPieces adds the leading space ByteLevel's add_prefix_space asks
+// for.
+func prefixHFPieces(pieces []hfPiece) []hfPiece {
+	for i := range pieces {
+		if !strings.HasPrefix(pieces[i].text, " ") {
+			pieces[i].text = " " + pieces[i].text
+		}
+	}
+	return pieces
+}
+
+// metaspaceHFPieces replaces spaces with the replacement character, adds
+// one in front of the text according to scheme and, with split, starts a
+// new piece at every replacement.
+func metaspaceHFPieces(pieces []hfPiece, replacement, scheme string, split bool) []hfPiece {
+	var out []hfPiece
+	for _, piece := range pieces {
+		text := strings.ReplaceAll(piece.text, " ", replacement)
+		if !strings.HasPrefix(text, replacement) && (scheme == "always" || (scheme == "first" && piece.first)) {
+			text = replacement + text
+		}
+		if !split {
+			out = append(out, hfPiece{text: text, first: piece.first})
+			continue
+		}
+
+		first, start := piece.first, 0
+		for start < len(text) {
+			end := len(text)
+			if next := strings.Index(text[start+1:], replacement); next >= 0 {
+				end = start + 1 + next
+			}
+			out = append(out, hfPiece{text: text[start:end], first: first})
+			first, start = false, end
+		}
+	}
+	return out
+}
+
+// count returns the number of tokens text encodes to.
+func (t *hfTokenizer) count(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	sections := []string{text}
+	tokens := 0
+	if t.addedTokens != nil {
+		sections = sections[:0]
+		last := 0
+		runes := []rune(text)
+		m, _ := t.addedTokens.FindStringMatch(text)
+		for m != nil {
+			sections = append(sections, string(runes[last:m.Index]))
+			tokens++
+			last = m.Index + m.Length
+			m, _ = t.addedTokens.FindNextMatch(m)
+		}
+		sections = append(sections, string(runes[last:]))
+	}
+
+	for i, section := range sections {
+		if section == "" {
+			continue
+		}
+		for _, normalize := range t.normalizers {
+			section = normalize(section)
+		}
+		pieces := []hfPiece{{text: section, first: i == 0}}
+		for _, split := range t.preTokenize {
+			pieces = split(pieces)
+		}
+		for _, piece := range pieces {
+			tokens += t.countWord(piece.text)
+		}
+	}
+	return tokens
+}
+
+// countWord applies BPE to a single pre-tokenized word.
+func (t *hfTokenizer) countWord(word string) int {
+	if word == "" {
+		return 0
+	}
+
+	t.mu.Lock()
+	n, cached := t.words[word]
+	t.mu.Unlock()
+	if cached {
+		return n
+	}
+
+	n = t.encodeWord(word)
+
+	t.mu.Lock()
+	if len(t.words) >= maxHFWordCacheSize {
+		t.words = make(map[string]int)
+	}
+	t.words[word] = n
+	t.mu.Unlock()
+	return n
+}
+
+func (t *hfTokenizer) encodeWord(word string) int {
+	if t.byteLevel {
+		word = byteLevelEncode(word)
+	}
+	if t.ignoreMerges {
+		if _, exists := t.vocab[word]; exists {
+			return 1
+		}
+	}
+
+	symbols := make([]string, 0, utf8.RuneCountInString(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+
+	// Repeatedly merge the adjacent pair with the lowest rank, leftmost
+	// first, until no known merge remains.
+	for len(symbols) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i+1 < len(symbols); i++ {
+			rank, exists := t.merges[[2]string{symbols[i], symbols[i+1]}]
+			if exists && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		symbols[best] += symbols[best+1]
+		symbols = append(symbols[:best+1], symbols[best+2:]...)
+	}
+
+	tokens := 0
+	previousUnknown := false
+	for _, symbol := range symbols {
+		if _, exists := t.vocab[symbol]; exists {
+			tokens++
+			previousUnknown = false
+			continue
+		}
+		if t.byteFallback {
+			tokens += len(symbol)
+			continue
+		}
+		if !(t.fuseUnknown && previousUnknown) {
+			tokens++
+		}
+		previousUnknown = true
+	}
+	return tokens
+}
+
+var (
+	byteLevelOnce  sync.Once
+	byteLevelRunes [256]rune
+)
+
+// byteLevelEncode maps every byte of text to the printable character the
+// GPT-2 byte-level alphabet uses for it.
+func byteLevelEncode(text string) string {
+	byteLevelOnce.Do(func() {
+		n := 0
+		for b := 0; b < 256; b++ {
+			if (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF) {
+				byteLevelRunes[b] = rune(b)
+				continue
+			}
+			byteLevelRunes[b] = rune(256 + n)
+			n++
+		}
+	})
+
+	var builder strings.Builder
+	builder.Grow(len(text) * 2)
+	for i := 0; i < len(text); i++ {
+		builder.WriteRune(byteLevelRunes[text[i]])
+	}
+	return builder.String()
+}
+
+// resolveTokenizer returns the tokenizer path of the first mapping whose
+// model pattern matches model, or "" when none does. Patterns use
+// path.Match syntax, so "Qwen/*" covers every model under that prefix, and
+// are compared case-insensitively.
+func resolveTokenizer(mappings []TokenizerMapping, model string) string {
+	model = strings.ToLower(model)
+	for _, mapping := range mappings {
+		pattern := strings.ToLower(strings.TrimSpace(mapping.Model))
+		if pattern == "" || mapping.Path == "" {
+			continue
+		}
+		if pattern == model {
+			return mapping.Path
+		}
+		if matched, err := path.Match(pattern, model); err == nil && matched {
+			return mapping.Path
+		}
+	}
+	return ""
+}
+
+// validateTokenizerMappings checks that every mapping names a model pattern
+// and a tokenizer that loads.
+func validateTokenizerMappings(mappings []TokenizerMapping) error {
+	for i, mapping := range mappings {
+		if strings.TrimSpace(mapping.Model) == "" {
+			return fmt.Errorf("tokenizer mapping %d has no model", i+1)
+		}
+		if _, err := path.Match(strings.ToLower(mapping.Model), ""); err != nil {
+			return fmt.Errorf("tokenizer mapping %d: invalid model pattern %q", i+1, mapping.Model)
+		}
+		if _, err := loadHFTokenizer(mapping.Path); err != nil {
+			return fmt.Errorf("tokenizer mapping %d (%s): %w", i+1, mapping.Model, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// byteLevelTokenizerJSON is a tiny Qwen-style tokenizer: added tokens, a
+// regex Split followed by ByteLevel, and merges saved as strings.
+const byteLevelTokenizerJSON = `{
+  "added_tokens": [{"id": 100, "content": "<|im_end|>", "special": true}],
+  "normalizer": {"type": "NFC"},
+  "pre_tokenizer": {"type": "Sequence", "pretokenizers": [
+    {"type": "Split", "pattern": {"Regex": " ?\\p{L}+|\\s+|[^\\s\\p{L}]+"}, "behavior": "Isolated", "invert": false},
+    {"type": "ByteLevel", "add_prefix_space": false, "use_regex": false}
+  ]},
+  "model": {
+    "type": "BPE", "byte_fallback": false,
+    "vocab": {"h": 0, "e": 1, "l": 2, "o": 3, "w": 4, "r": 5, "d": 6, "Ġ": 7,
+      "he": 8, "ll": 9, "hell": 10, "hello": 11, "or": 12, "Ġw": 13, "Ġwor": 14, "Ġworl": 15, "Ġworld": 16},
+    "merges": ["h e", "l l", "he ll", "hell o", "o r", "Ġ w", "Ġw or", "Ġwor l", "Ġworl d"]
+  }
+}`
+
+// metaspaceTokenizerJSON is a tiny Llama 2-style tokenizer: Metaspace with
+// byte fallback and merges saved as pairs.
+const metaspaceTokenizerJSON = `{
+  "added_tokens": [],
+  "normalizer": null,
+  "pre_tokenizer": {"type": "Metaspace", "replacement": "▁", "prepend_scheme": "first", "split": true},
+  "model": {
+    "type": "BPE", "byte_fallback": true,
+    "vocab": {"▁": 0, "h": 1, "i": 2, "▁h": 3, "▁hi": 4, "<0x21>": 5},
+    "merges": [["▁", "h"], ["▁h", "i"]]
+  }
+}`
+
+func writeTokenizer(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tokenizer.json"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestHFTokenizerByteLevelBPE(t *testing.T) {
+	tok, err := parseHFTokenizer([]byte(byteLevelTokenizerJSON))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	cases := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 2},     // "hello" + "Ġworld"
+		{"hello hello", 3},     // "Ġhello" has no merge with Ġ
+		{"hello<|im_end|>", 2}, // added tokens count once
+		{"hello!", 2},          // "!" is unknown
+		{"world world", 5},     // "w" "or" "l" "d" + "Ġworld"
+	}
+	for _, tc := range cases {
+		if got := tok.count(tc.text); got != tc.want {
+			t.Errorf("count(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestHFTokenizerMetaspaceByteFallback(t *testing.T) {
+	tok, err := loadHFTokenizer(writeTokenizer(t, metaspaceTokenizerJSON))
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	// "hi hi!" becomes "▁hi" and "▁hi!"; "!" falls back to one byte token.
+	if got := tok.count("hi hi!"); got != 3 {
+		t.Fatalf("expected 3 tokens, got %d", got)
+	}
+}
+
+func TestHFTokenizerRejectsUnsupportedModels(t *testing.T) {
+	_, err := parseHFTokenizer([]byte(`{"model": {"type": "WordPiece", "vocab": {"a": 0}}}`))
+	if err == nil || !strings.Contains(err.Error(), "only BPE") {
+		t.Fatalf("expected an unsupported model error, got %v", err)
+	}
+	_, err = parseHFTokenizer([]byte(`{"pre_tokenizer": {"type": "Nope"}, "model": {"type": "BPE", "vocab": {"a": 0}}}`))
+	if err == nil || !strings.Contains(err.Error(), "unsupported pre-tokenizer") {
+		t.Fatalf("expected an unsupported pre-tokenizer error, got %v", err)
+	}
+}
+
+func TestResolveTokenizer(t *testing.T) {
+	mappings := []TokenizerMapping{
+		{Model: "Qwen/Qwen2.5-7B-Instruct", Path: "exact"},
+		{Model: "qwen/*", Path: "qwen"},
+		{Model: "*llama-3*", Path: "llama"},
+	}
+	cases := map[string]string{
+		"Qwen/Qwen2.5-7B-Instruct": "exact",
+		"Qwen/Qwen2.5-72B":         "qwen",
+		"meta/llama-3-8b":          "",
+		"llama-3-8b":               "llama",
+		"gpt-4o":                   "",
+	}
+	for model, want := range cases {
+		if got := resolveTokenizer(mappings, model); got != want {
+			t.Errorf("resolveTokenizer(%q) = %q, want %q", model, got, want)
+		}
+	}
+}
+
+func TestRunSpeedTestUsesMappedTokenizer(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{TTFT: time.Millisecond, TokensPerSecond: 2000, ReportUsage: true, Seed: 1}))
+	defer server.Close()
+
+	dir := writeTokenizer(t, byteLevelTokenizerJSON)
+	service := NewSpeedTestService()
+	service.SetTokenizers([]TokenizerMapping{{Model: "mock-*", Path: dir}})
+
+	config := mockTestConfiguration(server.URL + "/v1")
+	config.Model = "mock-model"
+	batch, err := service.RunSpeedTest(context.Background(), config, "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if batch.Configuration.Tokenizer != dir {
+		t.Fatalf("expected the batch to record the mapped tokenizer, got %q", batch.Configuration.Tokenizer)
+	}
+
+	config.Tokenizer = filepath.Join(t.TempDir(), "missing.json")
+	if _, err := service.RunSpeedTest(context.Background(), config, ""); err == nil {
+		t.Fatalf("expected an error for a missing tokenizer")
+	}
+}
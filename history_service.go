@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const historyDirName = "history"
+
+// defaultMaxStoredBatches is how many batches are kept until the user
+// picks a retention policy.
+const defaultMaxStoredBatches = 50
+
+// historyRetentionFor returns the retention policy of cfg, which may be
+// nil. Only an explicitly saved policy can lift the batch limit.
+func historyRetentionFor(cfg *AppConfig) HistoryRetentionPolicy {
+	if cfg == nil || cfg.HistoryRetention == nil {
+		return HistoryRetentionPolicy{MaxBatches: defaultMaxStoredBatches}
+	}
+	return *cfg.HistoryRetention
+}
+
+// HistoryService persists completed test batches on disk so they survive
+// restarts. Each batch is stored as its own JSON file under the user
+// config directory, which keeps rename/tag/delete a single-file operation.
+type HistoryService struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewHistoryService creates a history store rooted at dir.
+func NewHistoryService(dir string) (*HistoryService, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("history directory path cannot be empty")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history dir: %w", err)
+	}
+
+	return &HistoryService{dir: dir}, nil
+}
+
+// getHistoryDir returns the default history directory inside the app config dir.
+func getHistoryDir() (string, error) {
+	appDir, err := getAppConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(appDir, historyDirName), nil
+}
+
+// Save writes the batch to disk, replacing any previous copy.
+func (h *HistoryService) Save(batch TestBatch) error {
+	path, err := h.batchPath(batch.ID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal test batch: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Write to a temporary file first so a crash never leaves a
+	// half-written batch behind. 0600 because configurations carry API keys.
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write test batch: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to store test batch: %w", err)
+	}
+
+	return nil
+}
+
+// LoadAll reads every stored batch, oldest first. Files that cannot be
+// parsed are skipped so one corrupt entry does not hide the rest.
+func (h *HistoryService) LoadAll() ([]TestBatch, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history dir: %w", err)
+	}
+
+	batches := make([]TestBatch, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(h.dir, entry.Name()))
+		if err != nil {
+			log.Printf("history: failed to read %s: %v", entry.Name(), err)
+			continue
+		}
+
+		var batch TestBatch
+		if err := json.Unmarshal(data, &batch); err != nil {
+			log.Printf("history: failed to parse %s: %v", entry.Name(), err)
+			continue
+		}
+		if batch.ID == "" {
+			continue
+		}
+
+		batches = append(batches, batch)
+	}
+
+	sort.SliceStable(batches, func(i, j int) bool {
+		return batchTime(batches[i]).Before(batchTime(batches[j]))
+	})
+
+	return batches, nil
+}
+
+// Delete removes a stored batch. Deleting a batch that is not on disk is not an error.
+func (h *HistoryService) Delete(batchID string) error {
+	path, err := h.batchPath(batchID)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete test batch: %w", err)
+	}
+
+	return nil
+}
+
+// Prune deletes the stored batches that fall outside policy. The app prunes
+// its in-memory list instead; this is for runs that only touch the disk.
+func (h *HistoryService) Prune(policy HistoryRetentionPolicy, now time.Time) error {
+	batches, err := h.LoadAll()
+	if err != nil {
+		return err
+	}
+	for _, id := range selectBatchesToPrune(batches, policy, now) {
+		if err := h.Delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HistoryService) batchPath(batchID string) (string, error) {
+	if batchID == "" || strings.ContainsAny(batchID, `/\`) || batchID == "." || batchID == ".." {
+		return "", fmt.Errorf("invalid test batch ID: %q", batchID)
+	}
+
+	return filepath.Join(h.dir, batchID+".json"), nil
+}
+
+// batchTime returns the time a batch finished, falling back to its start time.
+func batchTime(batch TestBatch) time.Time {
+	for _, value := range []string{batch.EndTime, batch.StartTime} {
+		if value == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// selectBatchesToPrune returns the IDs that fall outside the retention policy.
+// Baseline batches are always kept. batches must be ordered oldest first.
+func selectBatchesToPrune(batches []TestBatch, policy HistoryRetentionPolicy, now time.Time) []string {
+	var prune []string
+	kept := make([]TestBatch, 0, len(batches))
+
+	for _, batch := range batches {
+		protected := batch.Baseline || (policy.KeepTagged && len(batch.Tags) > 0)
+		if !protected && policy.MaxAgeDays > 0 {
+			finished := batchTime(batch)
+			if !finished.IsZero() && now.Sub(finished) > time.Duration(policy.MaxAgeDays)*24*time.Hour {
+				prune = append(prune, batch.ID)
+				continue
+			}
+		}
+		kept = append(kept, batch)
+	}
+
+	if policy.MaxBatches <= 0 || len(kept) <= policy.MaxBatches {
+		return prune
+	}
+
+	// Drop the oldest unprotected batches until the count fits.
+	overflow := len(kept) - policy.MaxBatches
+	for _, batch := range kept {
+		if overflow == 0 {
+			break
+		}
+		if batch.Baseline || (policy.KeepTagged && len(batch.Tags) > 0) {
+			continue
+		}
+		prune = append(prune, batch.ID)
+		overflow--
+	}
+
+	return prune
+}
+
+// normalizeTags trims, drops empty entries and de-duplicates tags while
+// preserving the order the user entered them in.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if _, exists := seen[tag]; exists {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	return result
+}
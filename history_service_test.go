@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistoryServiceRoundTrip(t *testing.T) {
+	history, err := NewHistoryService(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewHistoryService: %v", err)
+	}
+
+	older := TestBatch{ID: "older", EndTime: "2026-01-01T10:00:00Z"}
+	newer := TestBatch{ID: "newer", EndTime: "2026-01-02T10:00:00Z", Name: "vllm nightly", Tags: []string{"baseline"}}
+
+	for _, batch := range []TestBatch{newer, older} {
+		if err := history.Save(batch); err != nil {
+			t.Fatalf("Save(%s): %v", batch.ID, err)
+		}
+	}
+
+	batches, err := history.LoadAll()
+	if err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(batches) != 2 || batches[0].ID != "older" || batches[1].ID != "newer" {
+		t.Fatalf("expected batches ordered oldest first, got %+v", batches)
+	}
+	if batches[1].Name != "vllm nightly" || len(batches[1].Tags) != 1 {
+		t.Fatalf("expected name and tags to be persisted, got %+v", batches[1])
+	}
+
+	if err := history.Delete("older"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	batches, _ = history.LoadAll()
+	if len(batches) != 1 || batches[0].ID != "newer" {
+		t.Fatalf("expected only the newer batch after delete, got %+v", batches)
+	}
+
+	if err := history.Save(TestBatch{ID: "../escape"}); err == nil {
+		t.Fatalf("expected batch IDs with path separators to be rejected")
+	}
+}
+
+func TestSelectBatchesToPrune(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	batches := []TestBatch{
+		{ID: "a", EndTime: "2026-01-01T00:00:00Z", Tags: []string{"keep"}},
+		{ID: "b", EndTime: "2026-01-02T00:00:00Z"},
+		{ID: "c", EndTime: "2026-02-27T00:00:00Z"},
+		{ID: "d", EndTime: "2026-02-28T00:00:00Z"},
+	}
+
+	pruned := selectBatchesToPrune(batches, HistoryRetentionPolicy{MaxBatches: 2}, now)
+	if len(pruned) != 2 || pruned[0] != "a" || pruned[1] != "b" {
+		t.Fatalf("expected oldest two batches pruned, got %v", pruned)
+	}
+
+	pruned = selectBatchesToPrune(batches, HistoryRetentionPolicy{MaxBatches: 2, KeepTagged: true}, now)
+	if len(pruned) != 2 || pruned[0] != "b" || pruned[1] != "c" {
+		t.Fatalf("expected tagged batch to survive, got %v", pruned)
+	}
+
+	pruned = selectBatchesToPrune(batches, HistoryRetentionPolicy{MaxAgeDays: 30}, now)
+	if len(pruned) != 2 || pruned[0] != "a" || pruned[1] != "b" {
+		t.Fatalf("expected batches older than 30 days pruned, got %v", pruned)
+	}
+
+	if pruned := selectBatchesToPrune(batches, HistoryRetentionPolicy{}, now); len(pruned) != 0 {
+		t.Fatalf("expected zero policy to keep everything, got %v", pruned)
+	}
+
+	batches[1].Baseline = true
+	pruned = selectBatchesToPrune(batches, HistoryRetentionPolicy{MaxBatches: 2, MaxAgeDays: 30}, now)
+	if len(pruned) != 2 || pruned[0] != "a" || pruned[1] != "c" {
+		t.Fatalf("expected baseline batch to survive, got %v", pruned)
+	}
+}
+
+func TestHistoryRetentionDefaultsToFiftyBatches(t *testing.T) {
+	if policy := historyRetentionFor(nil); policy.MaxBatches != defaultMaxStoredBatches {
+		t.Fatalf("expected the default limit without a config, got %+v", policy)
+	}
+
+	var cfg AppConfig
+	if err := json.Unmarshal([]byte(`{"savedApiConfigs": []}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if policy := historyRetentionFor(&cfg); policy.MaxBatches != defaultMaxStoredBatches {
+		t.Fatalf("expected the default limit for a config without a policy, got %+v", policy)
+	}
+
+	// An explicit 0 is the user's choice of unlimited history.
+	if err := json.Unmarshal([]byte(`{"historyRetention": {"maxBatches": 0, "maxAgeDays": 30}}`), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if policy := historyRetentionFor(&cfg); policy.MaxBatches != 0 || policy.MaxAgeDays != 30 {
+		t.Fatalf("expected the saved policy, got %+v", policy)
+	}
+}
+
+func TestCLIBatchesAreSavedToHistory(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CONFIG_HOME", root)
+
+	var batches []*TestBatch
+	for i := 0; i <= defaultMaxStoredBatches; i++ {
+		end := time.Date(2026, 1, 1, 0, i, 0, 0, time.UTC).Format(time.RFC3339)
+		batches = append(batches, &TestBatch{ID: fmt.Sprintf("cli-%02d", i), EndTime: end})
+	}
+	if err := saveCLIBatches(io.Discard, batches); err != nil {
+		t.Fatalf("saveCLIBatches: %v", err)
+	}
+
+	// The saved ID is what -baseline and -replay resolve.
+	if batch, err := loadStoredBatch("cli-50", "baseline"); err != nil || batch.ID != "cli-50" {
+		t.Fatalf("expected the saved batch to resolve by ID, got %v (%v)", batch, err)
+	}
+	// Without a saved policy the oldest batch falls outside the default limit.
+	if _, err := loadStoredBatch("cli-00", "baseline"); err == nil {
+		t.Fatalf("expected the oldest batch to be pruned")
+	}
+}
+
+func TestLoadHistoryKeepsDefaultRetentionWithoutStore(t *testing.T) {
+	// A config root below a regular file cannot be created.
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", blocker)
+	t.Setenv("XDG_CONFIG_HOME", blocker)
+
+	app := NewApp()
+	app.loadHistory()
+	if app.historyService != nil || app.retention.MaxBatches != defaultMaxStoredBatches {
+		t.Fatalf("expected the default limit without a history store, got %+v", app.retention)
+	}
+}
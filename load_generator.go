@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Arrival processes supported by open-loop ("rate") tests.
+const (
+	arrivalConstant = "constant"
+	arrivalPoisson  = "poisson"
+	arrivalBurst    = "burst"
+)
+
+// validateRateConfig checks the open-loop settings used by rate-based modes.
+func validateRateConfig(rc RateConfiguration) error {
+	if rc.NumRequests <= 0 {
+		return fmt.Errorf("invalid rate configuration: numRequests must be positive, got %d", rc.NumRequests)
+	}
+	if rc.MaxInFlight < 0 {
+		return fmt.Errorf("invalid rate configuration: maxInFlight cannot be negative, got %d", rc.MaxInFlight)
+	}
+
+	switch rc.ArrivalProcess {
+	case "", arrivalConstant, arrivalPoisson:
+	case arrivalBurst:
+		if rc.BurstSize <= 0 {
+			return fmt.Errorf("invalid rate configuration: burst arrivals need a positive burstSize, got %d", rc.BurstSize)
+		}
+	default:
+		return fmt.Errorf("invalid rate configuration: unknown arrival process %q", rc.ArrivalProcess)
+	}
+
+	return nil
+}
+
+// buildArrivalSchedule returns the send offset of each request relative to
+// the start of a step so that the average arrival rate equals rate (req/s).
+//
+//   - constant: evenly spaced arrivals
+//   - poisson:  exponentially distributed gaps (memoryless traffic)
+//   - burst:    groups of burstSize requests released together, with the
+//     gap between groups chosen to keep the same average rate
+func buildArrivalSchedule(process string, rate float64, burstSize int, count int, rng *rand.Rand) []time.Duration {
+	if count <= 0 || rate <= 0 {
+		return nil
+	}
+
+	schedule := make([]time.Duration, count)
+	secondsToDuration := func(seconds float64) time.Duration {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	switch process {
+	case arrivalPoisson:
+		var elapsed float64
+		for i := range schedule {
+			if i > 0 {
+				elapsed += rng.ExpFloat64() / rate
+			}
+			schedule[i] = secondsToDuration(elapsed)
+		}
+	case arrivalBurst:
+		if burstSize <= 0 {
+			burstSize = 1
+		}
+		interval := float64(burstSize) / rate
+		for i := range schedule {
+			schedule[i] = secondsToDuration(float64(i/burstSize) * interval)
+		}
+	default:
+		for i := range schedule {
+			schedule[i] = secondsToDuration(float64(i) / rate)
+		}
+	}
+
+	return schedule
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBuildArrivalSchedule(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	constant := buildArrivalSchedule(arrivalConstant, 4, 0, 5, rng)
+	for i, offset := range constant {
+		if want := time.Duration(i) * 250 * time.Millisecond; offset != want {
+			t.Fatalf("constant arrival %d: expected %v, got %v", i, want, offset)
+		}
+	}
+
+	burst := buildArrivalSchedule(arrivalBurst, 4, 2, 5, rng)
+	want := []time.Duration{0, 0, 500 * time.Millisecond, 500 * time.Millisecond, time.Second}
+	for i := range want {
+		if burst[i] != want[i] {
+			t.Fatalf("burst arrival %d: expected %v, got %v", i, want[i], burst[i])
+		}
+	}
+
+	poisson := buildArrivalSchedule(arrivalPoisson, 10, 0, 2000, rng)
+	for i := 1; i < len(poisson); i++ {
+		if poisson[i] < poisson[i-1] {
+			t.Fatalf("poisson arrivals must be non-decreasing")
+		}
+	}
+	// 2000 arrivals at 10 req/s should span roughly 200s.
+	if span := poisson[len(poisson)-1].Seconds(); span < 180 || span > 220 {
+		t.Fatalf("expected poisson schedule to average 10 req/s, spanned %.1fs", span)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,6 +13,16 @@ import (
 var assets embed.FS
 
 func main() {
+	// Headless mode: `llm-speed-test run ...` drives the same speed test
+	// service from the terminal without opening the Wails window.
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runCLI(os.Args[2:]))
+	}
+	// `llm-speed-test mock ...` serves a fake OpenAI-compatible API.
+	if len(os.Args) > 1 && os.Args[1] == "mock" {
+		os.Exit(runMockServer(os.Args[2:]))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// validateMatrixTargets checks that a matrix run has something to compare
+// and that every target names a model and a usable provider.
+func validateMatrixTargets(targets []MatrixTarget) error {
+	if len(targets) < 2 {
+		return fmt.Errorf("a matrix run needs at least 2 targets, got %d", len(targets))
+	}
+
+	for i, target := range targets {
+		if target.Model == "" {
+			return fmt.Errorf("matrix target %d (%s) has no model", i+1, matrixTargetName(target))
+		}
+		if _, err := NewBackend(target.Provider, target.APIEndpoint, target.APIKey, 1); err != nil {
+			return fmt.Errorf("matrix target %d (%s): %w", i+1, matrixTargetName(target), err)
+		}
+	}
+	return nil
+}
+
+// matrixTargetName returns the label of a target: its saved name, or
+// model@endpoint when it has none.
+func matrixTargetName(target MatrixTarget) string {
+	if target.Name != "" {
+		return target.Name
+	}
+	return target.Model + "@" + target.APIEndpoint
+}
+
+// applyMatrixTarget points the shared workload configuration at target.
+// An empty target API key keeps the shared key. The tokenizer always comes
+// from the target, since the targets may run different models.
+func applyMatrixTarget(config TestConfiguration, target MatrixTarget) TestConfiguration {
+	config.APIEndpoint = target.APIEndpoint
+	config.Provider = target.Provider
+	config.Model = target.Model
+	config.Tokenizer = target.Tokenizer
+	if target.APIKey != "" {
+		config.APIKey = target.APIKey
+	}
+	return config
+}
+
+// RunMatrix runs the same workload against every target at the same time,
+// so all of them see the same network and load conditions. The targets
+// share the generated prompts, the dataset sample and the open-loop arrival
+// schedule, and their measured requests start together once every target
+// has finished its warm-up. Each target becomes its own batch, labelled
+// with the target name, tagged with matrixID and recording the shared seed.
+func (s *SpeedTestService) RunMatrix(ctx context.Context, config TestConfiguration, targets []MatrixTarget, matrixID string) (*ComparisonResult, error) {
+	if err := validateMatrixTargets(targets); err != nil {
+		return nil, err
+	}
+	if matrixID == "" {
+		matrixID = uuid.New().String()
+	}
+
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	shared := runOptions{
+		prompts:  newPromptCache(),
+		matrixID: matrixID,
+	}
+
+	gate := &startGate{}
+	gate.remaining.Add(len(targets))
+
+	summaries := make([]MatrixTargetSummary, len(targets))
+	batches := make([]*TestBatch, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		batchID := target.BatchID
+		if batchID == "" {
+			batchID = uuid.New().String()
+		}
+		summaries[i] = MatrixTargetSummary{
+			Name:        matrixTargetName(target),
+			APIEndpoint: target.APIEndpoint,
+			Provider:    target.Provider,
+			Model:       target.Model,
+			BatchID:     batchID,
+		}
+
+		opts := shared
+		opts.batchName = summaries[i].Name
+		opts.gate = &gateTicket{gate: gate}
+
+		wg.Add(1)
+		go func(i int, targetConfig TestConfiguration, batchID string, opts runOptions) {
+			defer wg.Done()
+			batch, err := s.runSpeedTest(ctx, targetConfig, batchID, opts)
+			if err != nil {
+				summaries[i].Error = err.Error()
+				return
+			}
+			batches[i] = batch
+		}(i, applyMatrixTarget(config, target), batchID, opts)
+	}
+	wg.Wait()
+
+	result := &ComparisonResult{MatrixID: matrixID}
+	for i, batch := range batches {
+		if batch == nil {
+			continue
+		}
+		summaries[i].Summary = batch.Summary
+		result.Batches = append(result.Batches, *batch)
+	}
+	result.Targets = summaries
+
+	if len(result.Batches) == 0 {
+		return result, fmt.Errorf("every matrix target failed: %s", summaries[0].Error)
+	}
+	if len(result.Batches) >= 2 {
+		comparison, err := s.CompareBatches(result.Batches)
+		if err != nil {
+			return result, err
+		}
+		result.Comparison = comparison.Comparison
+	}
+	return result, nil
+}
+
+// matrixComparison rebuilds the comparison of a finished matrix run from
+// its stored batches.
+func (s *SpeedTestService) matrixComparison(matrixID string, batches []TestBatch) (*ComparisonResult, error) {
+	result := &ComparisonResult{MatrixID: matrixID}
+	for _, batch := range batches {
+		if batch.MatrixID != matrixID {
+			continue
+		}
+		result.Batches = append(result.Batches, batch)
+		result.Targets = append(result.Targets, MatrixTargetSummary{
+			Name:        batch.Name,
+			APIEndpoint: batch.Configuration.APIEndpoint,
+			Provider:    batch.Configuration.Provider,
+			Model:       batch.Configuration.Model,
+			BatchID:     batch.ID,
+			Summary:     batch.Summary,
+		})
+	}
+
+	if len(result.Batches) == 0 {
+		return nil, fmt.Errorf("matrix run not found: %s", matrixID)
+	}
+	if len(result.Batches) >= 2 {
+		comparison, err := s.CompareBatches(result.Batches)
+		if err != nil {
+			return nil, err
+		}
+		result.Comparison = comparison.Comparison
+	}
+	return result, nil
+}
+
+// promptCache hands out one generated prompt per test number and length,
+// so every target of a matrix run sends identical prompts.
+type promptCache struct {
+	mu      sync.Mutex
+	prompts map[[2]int]string
+}
+
+func newPromptCache() *promptCache {
+	return &promptCache{prompts: make(map[[2]int]string)}
+}
+
+// get returns the prompt for testNumber and length, calling generate the
+// first time it is asked for.
+func (c *promptCache) get(testNumber, length int, generate func() string) string {
+	key := [2]int{testNumber, length}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if prompt, exists := c.prompts[key]; exists {
+		return prompt
+	}
+	prompt := generate()
+	c.prompts[key] = prompt
+	return prompt
+}
+
+// prefix returns the shared prompt prefix of length tokens, calling
+// generate the first time it is asked for.
+func (c *promptCache) prefix(length int, generate func() string) string {
+	return c.get(-1, length, generate)
+}
+
+// startGate holds the batches of a matrix run until all of them are ready.
+type startGate struct {
+	remaining sync.WaitGroup
+}
+
+// gateTicket is one batch's place at a startGate.
+type gateTicket struct {
+	gate *startGate
+	once sync.Once
+}
+
+// leave marks the batch as no longer holding up the others, e.g. because
+// it failed before its warm-up finished. Calling it again is a no-op.
+func (t *gateTicket) leave() {
+	t.once.Do(t.gate.remaining.Done)
+}
+
+// wait marks the batch as ready and blocks until every batch is.
+func (t *gateTicket) wait() {
+	t.leave()
+	t.gate.remaining.Wait()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunMatrixComparesTargets(t *testing.T) {
+	fast := httptest.NewServer(NewMockServer(MockServerConfig{TTFT: 5 * time.Millisecond, TokensPerSecond: 2000, ReportUsage: true, Seed: 1}))
+	defer fast.Close()
+	slow := httptest.NewServer(NewMockServer(MockServerConfig{TTFT: 80 * time.Millisecond, TokensPerSecond: 2000, ReportUsage: true, Seed: 1}))
+	defer slow.Close()
+
+	targets := []MatrixTarget{
+		{SavedAPIConfig: SavedAPIConfig{Name: "fast", APIEndpoint: fast.URL + "/v1"}, Model: "mock-a"},
+		{SavedAPIConfig: SavedAPIConfig{APIEndpoint: slow.URL + "/v1"}, Model: "mock-b", BatchID: "slow-batch"},
+	}
+
+	result, err := NewSpeedTestService().RunMatrix(context.Background(), mockTestConfiguration(""), targets, "")
+	if err != nil {
+		t.Fatalf("matrix run failed: %v", err)
+	}
+
+	if result.MatrixID == "" || len(result.Batches) != 2 || len(result.Targets) != 2 {
+		t.Fatalf("expected 2 batches and targets under a matrix ID, got %+v", result)
+	}
+	if result.Targets[1].Name != "mock-b@"+slow.URL+"/v1" || result.Targets[1].BatchID != "slow-batch" {
+		t.Fatalf("unexpected second target summary: %+v", result.Targets[1])
+	}
+	for i, batch := range result.Batches {
+		if batch.MatrixID != result.MatrixID || batch.Name != result.Targets[i].Name {
+			t.Fatalf("batch %d not labelled with its matrix target: %q %q", i, batch.MatrixID, batch.Name)
+		}
+		if batch.Summary.SuccessfulTests != 4 {
+			t.Fatalf("batch %d: expected 4 successful requests, got %+v", i, batch.Summary)
+		}
+	}
+	if result.Comparison.BestP95TTFTBatchID != result.Targets[0].BatchID {
+		t.Fatalf("expected the fast target to have the best TTFT, got %s", result.Comparison.BestP95TTFTBatchID)
+	}
+}
+
+func TestRunMatrixDoesNotWaitForUnreachableTarget(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{ReportUsage: true}))
+	defer server.Close()
+
+	targets := []MatrixTarget{
+		{SavedAPIConfig: SavedAPIConfig{Name: "ok", APIEndpoint: server.URL + "/v1"}, Model: "mock"},
+		{SavedAPIConfig: SavedAPIConfig{Name: "down", APIEndpoint: "http://127.0.0.1:1/v1"}, Model: "mock"},
+	}
+
+	result, err := NewSpeedTestService().RunMatrix(context.Background(), mockTestConfiguration(""), targets, "m1")
+	if err != nil {
+		t.Fatalf("matrix run failed: %v", err)
+	}
+	if result.MatrixID != "m1" || len(result.Batches) != 2 {
+		t.Fatalf("expected both targets to produce a batch, got %d", len(result.Batches))
+	}
+	if result.Targets[0].Summary.SuccessfulTests != 4 || result.Targets[1].Summary.FailedTests != 4 {
+		t.Fatalf("expected only the unreachable target's requests to fail, got %+v / %+v",
+			result.Targets[0].Summary, result.Targets[1].Summary)
+	}
+}
+
+func TestValidateMatrixTargets(t *testing.T) {
+	target := MatrixTarget{SavedAPIConfig: SavedAPIConfig{APIEndpoint: "http://localhost:8000/v1"}, Model: "m"}
+
+	cases := []struct {
+		name    string
+		targets []MatrixTarget
+		wantErr string
+	}{
+		{"single target", []MatrixTarget{target}, "at least 2 targets"},
+		{"missing model", []MatrixTarget{target, {SavedAPIConfig: target.SavedAPIConfig}}, "has no model"},
+		{"unknown provider", []MatrixTarget{target, {SavedAPIConfig: target.SavedAPIConfig, Model: "m", Provider: "nope"}}, "target 2"},
+		{"valid", []MatrixTarget{target, target}, ""},
+	}
+	for _, tc := range cases {
+		err := validateMatrixTargets(tc.targets)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestPromptCacheSharesPrompts(t *testing.T) {
+	cache := newPromptCache()
+	calls := 0
+	generate := func() string {
+		calls++
+		return strings.Repeat("x", calls)
+	}
+
+	first := cache.get(1, 100, generate)
+	if again := cache.get(1, 100, generate); again != first {
+		t.Fatalf("expected the cached prompt %q, got %q", first, again)
+	}
+	if other := cache.get(2, 100, generate); other == first {
+		t.Fatalf("expected a new prompt for another test number")
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 generations, got %d", calls)
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsMaxBatches bounds how many batches the exporter keeps series for,
+// so a long-running desktop session does not grow the scrape without limit.
+const metricsMaxBatches = 10
+
+// Histogram buckets, in Prometheus base units.
+var (
+	ttftBuckets      = []float64{0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+	latencyBuckets   = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	outputTPSBuckets = []float64{1, 5, 10, 25, 50, 100, 200, 500, 1000}
+)
+
+// MetricsExporter exposes live telemetry and per-request histograms in the
+// Prometheus text exposition format. Gauges are labelled by batch and model;
+// histograms and request counters additionally by step (1-based step index).
+type MetricsExporter struct {
+	mu         sync.Mutex
+	live       map[metricsBatchKey]TelemetryUpdate
+	series     map[metricsSeriesKey]*metricsSeries
+	batchOrder []string
+}
+
+type metricsBatchKey struct {
+	batch string
+	model string
+}
+
+type metricsSeriesKey struct {
+	batch string
+	model string
+	step  int
+}
+
+type metricsSeries struct {
+	ttft      *metricsHistogram
+	latency   *metricsHistogram
+	outputTPS *metricsHistogram
+	succeeded uint64
+	failed    uint64
+}
+
+type metricsHistogram struct {
+	buckets []float64
+	counts  []uint64 // cumulative is computed at write time
+	sum     float64
+	count   uint64
+}
+
+// NewMetricsExporter creates an empty exporter.
+func NewMetricsExporter() *MetricsExporter {
+	return &MetricsExporter{
+		live:   make(map[metricsBatchKey]TelemetryUpdate),
+		series: make(map[metricsSeriesKey]*metricsSeries),
+	}
+}
+
+// Subscribe feeds the exporter from every batch published on bus until
+// the bus is closed or the returned subscription is.
+func (m *MetricsExporter) Subscribe(bus *EventBus) *Subscription {
+	sub := bus.Subscribe("", 0)
+	go func() {
+		for event := range sub.Events() {
+			switch {
+			case event.Type == EventTelemetry && event.Telemetry != nil:
+				m.ObserveTelemetry(event.BatchID, event.Model, *event.Telemetry)
+			case event.Type == EventResult && event.Result != nil:
+				m.ObserveResult(event.BatchID, event.Model, event.Step, *event.Result)
+			}
+		}
+	}()
+	return sub
+}
+
+// ObserveTelemetry records the latest telemetry snapshot of a batch.
+func (m *MetricsExporter) ObserveTelemetry(batchID, model string, update TelemetryUpdate) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.trackBatchLocked(batchID)
+	m.live[metricsBatchKey{batchID, model}] = update
+}
+
+// ObserveResult adds a finished request to the histograms of its step.
+// Failed requests only increment the request counter.
+func (m *MetricsExporter) ObserveResult(batchID, model string, step int, result TestResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.trackBatchLocked(batchID)
+	key := metricsSeriesKey{batchID, model, step}
+	series, exists := m.series[key]
+	if !exists {
+		series = &metricsSeries{
+			ttft:      newMetricsHistogram(ttftBuckets),
+			latency:   newMetricsHistogram(latencyBuckets),
+			outputTPS: newMetricsHistogram(outputTPSBuckets),
+		}
+		m.series[key] = series
+	}
+
+	if !result.Success {
+		series.failed++
+		return
+	}
+	series.succeeded++
+	series.ttft.observe(result.RequestLatency / 1000)
+	series.latency.observe(result.TotalLatency / 1000)
+	if result.OutputTokensPerSecond > 0 {
+		series.outputTPS.observe(result.OutputTokensPerSecond)
+	}
+}
+
+// trackBatchLocked remembers batchID and evicts the oldest batch once more
+// than metricsMaxBatches are known. Caller must hold m.mu.
+func (m *MetricsExporter) trackBatchLocked(batchID string) {
+	for _, id := range m.batchOrder {
+		if id == batchID {
+			return
+		}
+	}
+
+	m.batchOrder = append(m.batchOrder, batchID)
+	if len(m.batchOrder) <= metricsMaxBatches {
+		return
+	}
+
+	evicted := m.batchOrder[0]
+	m.batchOrder = m.batchOrder[1:]
+	for key := range m.live {
+		if key.batch == evicted {
+			delete(m.live, key)
+		}
+	}
+	for key := range m.series {
+		if key.batch == evicted {
+			delete(m.series, key)
+		}
+	}
+}
+
+// ServeHTTP writes every metric in the text exposition format.
+func (m *MetricsExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes every metric in the text exposition format.
+func (m *MetricsExporter) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+
+	liveKeys := make([]metricsBatchKey, 0, len(m.live))
+	for key := range m.live {
+		liveKeys = append(liveKeys, key)
+	}
+	sort.Slice(liveKeys, func(i, j int) bool {
+		if liveKeys[i].batch != liveKeys[j].batch {
+			return liveKeys[i].batch < liveKeys[j].batch
+		}
+		return liveKeys[i].model < liveKeys[j].model
+	})
+
+	gauges := []struct {
+		name, kind, help string
+		value            func(TelemetryUpdate) float64
+	}{
+		{"llm_speed_test_active_requests", "gauge", "Requests currently in flight.",
+			func(u TelemetryUpdate) float64 { return float64(u.ActiveTests) }},
+		{"llm_speed_test_completed_requests", "gauge", "Requests finished so far in the batch.",
+			func(u TelemetryUpdate) float64 { return float64(u.CompletedTests) }},
+		{"llm_speed_test_planned_requests", "gauge", "Requests the batch will send in total.",
+			func(u TelemetryUpdate) float64 { return float64(u.TotalTests) }},
+		{"llm_speed_test_generated_tokens_total", "counter", "Output tokens streamed so far (estimated from chunk text).",
+			func(u TelemetryUpdate) float64 { return float64(u.GeneratedTokens) }},
+		{"llm_speed_test_instant_tokens_per_second", "gauge", "Output tokens per second over the last telemetry interval.",
+			func(u TelemetryUpdate) float64 { return u.InstantTPS }},
+		{"llm_speed_test_ttft_average_seconds", "gauge", "Average time to first token so far.",
+			func(u TelemetryUpdate) float64 { return u.AverageTTFT / 1000 }},
+		{"llm_speed_test_ttft_p95_seconds", "gauge", "95th percentile time to first token so far.",
+			func(u TelemetryUpdate) float64 { return u.P95TTFT / 1000 }},
+		{"llm_speed_test_step_current", "gauge", "1-based index of the running step.",
+			func(u TelemetryUpdate) float64 { return float64(u.StepCurrent) }},
+		{"llm_speed_test_step_total", "gauge", "Number of steps in the batch.",
+			func(u TelemetryUpdate) float64 { return float64(u.StepTotal) }},
+	}
+	for _, gauge := range gauges {
+		writeMetricHeader(&b, gauge.name, gauge.kind, gauge.help)
+		for _, key := range liveKeys {
+			labels := formatMetricLabels("batch", key.batch, "model", key.model)
+			fmt.Fprintf(&b, "%s%s %s\n", gauge.name, labels, formatMetricValue(gauge.value(m.live[key])))
+		}
+	}
+
+	seriesKeys := make([]metricsSeriesKey, 0, len(m.series))
+	for key := range m.series {
+		seriesKeys = append(seriesKeys, key)
+	}
+	sort.Slice(seriesKeys, func(i, j int) bool {
+		a, c := seriesKeys[i], seriesKeys[j]
+		if a.batch != c.batch {
+			return a.batch < c.batch
+		}
+		if a.model != c.model {
+			return a.model < c.model
+		}
+		return a.step < c.step
+	})
+
+	writeMetricHeader(&b, "llm_speed_test_requests_total", "counter", "Finished requests by outcome.")
+	for _, key := range seriesKeys {
+		series := m.series[key]
+		step := strconv.Itoa(key.step)
+		fmt.Fprintf(&b, "llm_speed_test_requests_total%s %d\n",
+			formatMetricLabels("batch", key.batch, "model", key.model, "step", step, "status", "success"), series.succeeded)
+		fmt.Fprintf(&b, "llm_speed_test_requests_total%s %d\n",
+			formatMetricLabels("batch", key.batch, "model", key.model, "step", step, "status", "error"), series.failed)
+	}
+
+	histograms := []struct {
+		name, help string
+		get        func(*metricsSeries) *metricsHistogram
+	}{
+		{"llm_speed_test_ttft_seconds", "Time to first token of successful requests.",
+			func(s *metricsSeries) *metricsHistogram { return s.ttft }},
+		{"llm_speed_test_request_duration_seconds", "Total latency of successful requests.",
+			func(s *metricsSeries) *metricsHistogram { return s.latency }},
+		{"llm_speed_test_output_tokens_per_second", "Per-request output token rate of successful requests.",
+			func(s *metricsSeries) *metricsHistogram { return s.outputTPS }},
+	}
+	for _, histogram := range histograms {
+		writeMetricHeader(&b, histogram.name, "histogram", histogram.help)
+		for _, key := range seriesKeys {
+			histogram.get(m.series[key]).write(&b, histogram.name, key)
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func newMetricsHistogram(buckets []float64) *metricsHistogram {
+	return &metricsHistogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *metricsHistogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (h *metricsHistogram) write(b *strings.Builder, name string, key metricsSeriesKey) {
+	step := strconv.Itoa(key.step)
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(b, "%s_bucket%s %d\n", name,
+			formatMetricLabels("batch", key.batch, "model", key.model, "step", step, "le", formatMetricValue(bound)), cumulative)
+	}
+	fmt.Fprintf(b, "%s_bucket%s %d\n", name,
+		formatMetricLabels("batch", key.batch, "model", key.model, "step", step, "le", "+Inf"), h.count)
+
+	labels := formatMetricLabels("batch", key.batch, "model", key.model, "step", step)
+	fmt.Fprintf(b, "%s_sum%s %s\n", name, labels, formatMetricValue(h.sum))
+	fmt.Fprintf(b, "%s_count%s %d\n", name, labels, h.count)
+}
+
+func writeMetricHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// formatMetricLabels renders name/value pairs as {a="x",b="y"}.
+func formatMetricLabels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(metricLabelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatMetricValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// startMetricsServer serves exporter under /metrics on addr. The listener
+// is opened before returning so an address in use fails immediately.
+func startMetricsServer(addr string, exporter *MetricsExporter) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("metrics server stopped: %v", err)
+		}
+	}()
+
+	return server, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsExporterExposition(t *testing.T) {
+	exporter := NewMetricsExporter()
+	exporter.ObserveTelemetry("b1", "my-model", TelemetryUpdate{ActiveTests: 3, InstantTPS: 42.5, P95TTFT: 250, StepCurrent: 1, StepTotal: 2})
+	exporter.ObserveResult("b1", "my-model", 1, TestResult{Success: true, RequestLatency: 80, TotalLatency: 900, OutputTokensPerSecond: 30})
+	exporter.ObserveResult("b1", "my-model", 1, TestResult{Success: true, RequestLatency: 300, TotalLatency: 1200, OutputTokensPerSecond: 20})
+	exporter.ObserveResult("b1", "my-model", 2, TestResult{Error: "boom"})
+
+	server := httptest.NewServer(exporter)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	text := string(body)
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	for _, want := range []string{
+		"# TYPE llm_speed_test_active_requests gauge",
+		`llm_speed_test_active_requests{batch="b1",model="my-model"} 3`,
+		`llm_speed_test_instant_tokens_per_second{batch="b1",model="my-model"} 42.5`,
+		`llm_speed_test_ttft_p95_seconds{batch="b1",model="my-model"} 0.25`,
+		`llm_speed_test_requests_total{batch="b1",model="my-model",step="1",status="success"} 2`,
+		`llm_speed_test_requests_total{batch="b1",model="my-model",step="2",status="error"} 1`,
+		"# TYPE llm_speed_test_ttft_seconds histogram",
+		`llm_speed_test_ttft_seconds_bucket{batch="b1",model="my-model",step="1",le="0.1"} 1`,
+		`llm_speed_test_ttft_seconds_bucket{batch="b1",model="my-model",step="1",le="0.5"} 2`,
+		`llm_speed_test_ttft_seconds_bucket{batch="b1",model="my-model",step="1",le="+Inf"} 2`,
+		`llm_speed_test_ttft_seconds_sum{batch="b1",model="my-model",step="1"} 0.38`,
+		`llm_speed_test_request_duration_seconds_count{batch="b1",model="my-model",step="2"} 0`,
+	} {
+		if !strings.Contains(text, want+"\n") {
+			t.Errorf("missing %q in scrape:\n%s", want, text)
+		}
+	}
+}
+
+func TestMetricsExporterEvictsOldBatches(t *testing.T) {
+	exporter := NewMetricsExporter()
+	for i := 0; i <= metricsMaxBatches; i++ {
+		exporter.ObserveTelemetry(string(rune('a'+i)), "m", TelemetryUpdate{})
+	}
+
+	var b strings.Builder
+	exporter.WriteTo(&b)
+	if strings.Contains(b.String(), `batch="a"`) {
+		t.Fatalf("expected the oldest batch to be evicted")
+	}
+	if !strings.Contains(b.String(), `batch="b"`) {
+		t.Fatalf("expected newer batches to be kept")
+	}
+}
+
+func TestFormatMetricLabelsEscapes(t *testing.T) {
+	got := formatMetricLabels("model", "a\"b\\c\nd")
+	if want := `{model="a\"b\\c\nd"}`; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,667 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MockServerConfig describes the latency profile of the mock
+// OpenAI-compatible server.
+type MockServerConfig struct {
+	Models              []string      // Served by /v1/models; the first one is echoed in responses
+	APIKey              string        // Required Bearer token (empty = no auth)
+	TTFT                time.Duration // Time before the first token
+	TokensPerSecond     float64       // Decode rate per request (0 = no delay between tokens)
+	Jitter              float64       // Relative random spread applied to every delay (0.1 = ±10%)
+	ConcurrencySlowdown float64       // Extra delay per additional in-flight request (0.05 = +5% each)
+	ErrorRate           float64       // Fraction of requests answered with HTTP 500
+	OutputTokens        int           // Tokens generated when the request sets no max_tokens
+	ReportUsage         bool          // Include usage in responses (and in streams when include_usage is set)
+	ContinuousUsage     bool          // Also attach running usage to every streamed chunk, like vLLM's continuous usage stats
+	PromptOverhead      int           // Tokens added to the prompt usage of chat requests, like a chat template
+	MessageOverhead     int           // Tokens added to the prompt usage per chat message, like the role headers of a chat template
+	Tokenizer           string        // HuggingFace tokenizer prompts are counted with (empty = the model's tiktoken encoding)
+	CachedTTFT          time.Duration // TTFT when the prompt's first mockPrefixCacheBytes were seen before, like automatic prefix caching (0 = no caching)
+	MalformedToolCalls  float64       // Fraction of tool calls whose arguments are cut off before the JSON closes
+	StructuredSlowdown  float64       // Extra delay between tokens of schema-constrained requests (0.3 = +30%), like guided decoding
+	ReasoningTokens     int           // Thinking tokens streamed as reasoning_content before the answer of chat requests, out of max_tokens
+	ImageTokenTime      time.Duration // Extra time to first token per image token, like a vision encoder (0 = images are free)
+	Seed                int64         // Seed for jitter and error injection (0 = random)
+}
+
+// MockServer is an http.Handler that imitates an OpenAI-compatible model
+// server, so the whole pipeline can run without a GPU.
+type MockServer struct {
+	config   MockServerConfig
+	inFlight int32
+	counter  *tokenCounter
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	cacheMu  sync.Mutex
+	prefixes map[string]bool
+}
+
+// mockPrefixCacheBytes is how much of a prompt the mock's prefix cache
+// compares: prompts that start with the same bytes count as cache hits.
+const mockPrefixCacheBytes = 256
+
+// NewMockServer creates a mock server. Zero values fall back to a small,
+// fast profile suitable for tests.
+func NewMockServer(config MockServerConfig) *MockServer {
+	if len(config.Models) == 0 {
+		config.Models = []string{"mock-model"}
+	}
+	if config.OutputTokens <= 0 {
+		config.OutputTokens = 64
+	}
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	counter, err := loadTokenCounter(config.Models[0], config.Tokenizer)
+	if err != nil {
+		log.Printf("mock server: %v; counting prompts with the model's tiktoken encoding", err)
+		counter = newTokenCounter(config.Models[0])
+	}
+
+	return &MockServer{
+		config:   config,
+		counter:  counter,
+		rng:      rand.New(rand.NewSource(seed)),
+		prefixes: make(map[string]bool),
+	}
+}
+
+// ServeHTTP routes /v1/models, /v1/chat/completions and /v1/completions.
+func (m *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.config.APIKey != "" && r.Header.Get("Authorization") != "Bearer "+m.config.APIKey {
+		writeMockError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/v1/models":
+		m.serveModels(w)
+	case "/v1/chat/completions":
+		m.serveCompletion(w, r, false)
+	case "/v1/completions":
+		m.serveCompletion(w, r, true)
+	default:
+		writeMockError(w, http.StatusNotFound, "unknown path "+r.URL.Path)
+	}
+}
+
+func (m *MockServer) serveModels(w http.ResponseWriter) {
+	type model struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+	}
+	list := struct {
+		Object string  `json:"object"`
+		Data   []model `json:"data"`
+	}{Object: "list"}
+	for _, id := range m.config.Models {
+		list.Data = append(list.Data, model{ID: id, Object: "model", OwnedBy: "mock"})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// mockRequest holds the request fields shared by chat and text completions.
+type mockRequest struct {
+	Model          string          `json:"model"`
+	Messages       []Message       `json:"messages"`
+	Prompt         string          `json:"prompt"`
+	MaxTokens      int             `json:"max_tokens"`
+	Stream         bool            `json:"stream"`
+	StreamOptions  *StreamOptions  `json:"stream_options"`
+	Tools          []Tool          `json:"tools"`
+	ToolChoice     json.RawMessage `json:"tool_choice"`
+	ResponseFormat *ResponseFormat `json:"response_format"`
+	GuidedJSON     json.RawMessage `json:"guided_json"`
+}
+
+// schema returns the JSON schema the output is constrained to, or nil.
+func (r mockRequest) schema() *jsonSchema {
+	raw := r.GuidedJSON
+	if f := r.ResponseFormat; f != nil && f.Type == "json_schema" && f.JSONSchema != nil {
+		raw = f.JSONSchema.Schema
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	var schema jsonSchema
+	if json.Unmarshal(raw, &schema) != nil {
+		return nil
+	}
+	return &schema
+}
+
+// toolCall returns the function the mock calls instead of answering, or ""
+// when the request offers no tools or forbids calling them. A named tool
+// choice is honoured; otherwise the first tool is called.
+func (r mockRequest) toolCall() string {
+	if len(r.Tools) == 0 {
+		return ""
+	}
+	var choice string
+	if json.Unmarshal(r.ToolChoice, &choice) == nil && choice == "none" {
+		return ""
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if json.Unmarshal(r.ToolChoice, &named) == nil && named.Function.Name != "" {
+		return named.Function.Name
+	}
+	return r.Tools[0].Function.Name
+}
+
+// mockToolArguments splits the JSON arguments of a mock tool call into one
+// fragment per token. Malformed arguments never close the string or object.
+func mockToolArguments(tokens int, malformed bool) []string {
+	fragments := make([]string, tokens)
+	for i := range fragments {
+		fragments[i] = " tok"
+	}
+	fragments[0] = `{"input": "tok`
+	if !malformed {
+		fragments[tokens-1] += `"}`
+	}
+	return fragments
+}
+
+// mockImagePatch is the side in pixels of the square each image token
+// covers, as in vision encoders that merge 14-pixel patches 2x2.
+const mockImagePatch = 28
+
+// mockImageTokens returns the prompt tokens of the images in messages: one
+// per mockImagePatch square, rounding partial squares up. Only base64 data
+// URLs are accepted.
+func mockImageTokens(messages []Message) (int, error) {
+	tokens := 0
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if part.Type != "image_url" || part.ImageURL == nil {
+				continue
+			}
+			header, data, ok := strings.Cut(part.ImageURL.URL, ",")
+			if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+				return 0, fmt.Errorf("image_url must be a base64 data URL")
+			}
+			raw, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return 0, fmt.Errorf("invalid image data: %v", err)
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+			if err != nil {
+				return 0, fmt.Errorf("invalid image: %v", err)
+			}
+			tokens += ((cfg.Width + mockImagePatch - 1) / mockImagePatch) * ((cfg.Height + mockImagePatch - 1) / mockImagePatch)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *MockServer) serveCompletion(w http.ResponseWriter, r *http.Request, text bool) {
+	if r.Method != http.MethodPost {
+		writeMockError(w, http.StatusMethodNotAllowed, "POST required")
+		return
+	}
+
+	var request mockRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeMockError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	inFlight := atomic.AddInt32(&m.inFlight, 1)
+	defer atomic.AddInt32(&m.inFlight, -1)
+
+	if m.config.ErrorRate > 0 && m.random() < m.config.ErrorRate {
+		writeMockError(w, http.StatusInternalServerError, "injected error")
+		return
+	}
+
+	// Prompts are counted with the same tokenizer the client uses for the
+	// served model, so tests can expect exact prompt lengths.
+	promptTokens := m.counter.count(request.Prompt)
+	for _, message := range request.Messages {
+		promptTokens += m.counter.count(message.Content)
+	}
+	imageTokens, err := mockImageTokens(request.Messages)
+	if err != nil {
+		writeMockError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	promptTokens += imageTokens
+	if !text {
+		promptTokens += m.config.PromptOverhead + m.config.MessageOverhead*len(request.Messages)
+	}
+	completionTokens := request.MaxTokens
+	if completionTokens <= 0 {
+		completionTokens = m.config.OutputTokens
+	}
+	usage := Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+
+	model := request.Model
+	if model == "" {
+		model = m.config.Models[0]
+	}
+	id := fmt.Sprintf("mock-%d", time.Now().UnixNano())
+
+	// Requests offering tools are answered with a call whose arguments
+	// take the place of the completion tokens.
+	var tool string
+	var arguments []string
+	if !text {
+		tool = request.toolCall()
+	}
+	if tool != "" {
+		malformed := m.config.MalformedToolCalls > 0 && m.random() < m.config.MalformedToolCalls
+		arguments = mockToolArguments(completionTokens, malformed)
+	}
+	// Constrained requests are answered with a document following the
+	// schema, cut into one fragment per token; short documents generate
+	// fewer tokens.
+	var structured []string
+	if schema := request.schema(); schema != nil && !text && tool == "" {
+		structured = mockStructuredOutput(schema, completionTokens)
+		completionTokens = len(structured)
+		usage.CompletionTokens = completionTokens
+		usage.TotalTokens = promptTokens + completionTokens
+	}
+	// Reasoning takes its tokens out of max_tokens, as on real servers,
+	// but always leaves one for the answer.
+	reasoning := 0
+	if m.config.ReasoningTokens > 0 && !text && tool == "" && structured == nil {
+		reasoning = min(m.config.ReasoningTokens, completionTokens-1)
+		usage.CompletionTokensDetails = &CompletionTokensDetails{ReasoningTokens: reasoning}
+	}
+	finishReason := "length"
+	if tool != "" {
+		finishReason = "tool_calls"
+	} else if structured != nil {
+		finishReason = "stop"
+	}
+
+	// Every delay is stretched by the number of concurrent requests, so
+	// concurrency sweeps see throughput saturate as on a real server.
+	slowdown := 1 + m.config.ConcurrencySlowdown*float64(inFlight-1)
+	var tokenGap time.Duration
+	if m.config.TokensPerSecond > 0 {
+		tokenGap = time.Duration(float64(time.Second) / m.config.TokensPerSecond)
+	}
+	if structured != nil {
+		tokenGap = time.Duration(float64(tokenGap) * (1 + m.config.StructuredSlowdown))
+	}
+
+	ttft := m.config.TTFT
+	if m.config.CachedTTFT > 0 && m.cachedPrefix(request) {
+		ttft = m.config.CachedTTFT
+	}
+	ttft += time.Duration(imageTokens) * m.config.ImageTokenTime
+	if !m.sleep(r, ttft, slowdown) {
+		return
+	}
+
+	if !request.Stream {
+		for i := 1; i < completionTokens; i++ {
+			if !m.sleep(r, tokenGap, slowdown) {
+				return
+			}
+		}
+
+		content := strings.TrimSpace(strings.Repeat("tok ", completionTokens-reasoning))
+		if structured != nil {
+			content = strings.Join(structured, "")
+		}
+		choice := map[string]interface{}{"index": 0, "finish_reason": finishReason}
+		if text {
+			choice["text"] = content
+		} else if tool != "" {
+			choice["message"] = Message{Role: "assistant", ToolCalls: []ToolCall{{
+				ID: id + "-call", Type: "function",
+				Function: ToolCallFunction{Name: tool, Arguments: strings.Join(arguments, "")},
+			}}}
+		} else {
+			thinking := strings.TrimSpace(strings.Repeat("think ", reasoning))
+			choice["message"] = Message{Role: "assistant", Content: content, ReasoningContent: thinking}
+		}
+		response := map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []interface{}{choice},
+		}
+		if m.config.ReportUsage {
+			response["usage"] = usage
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(payload interface{}) {
+		data, _ := json.Marshal(payload)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	for i := 0; i < completionTokens; i++ {
+		if i > 0 && !m.sleep(r, tokenGap, slowdown) {
+			return
+		}
+
+		choice := map[string]interface{}{"index": 0, "finish_reason": nil}
+		if i == completionTokens-1 {
+			choice["finish_reason"] = finishReason
+		}
+		if text {
+			choice["text"] = "tok "
+		} else if tool != "" {
+			// The first fragment also names the function, as OpenAI does.
+			call := ToolCall{Function: ToolCallFunction{Arguments: arguments[i]}}
+			if i == 0 {
+				call.ID, call.Type, call.Function.Name = id+"-call", "function", tool
+			}
+			choice["delta"] = streamChoiceDelta{ToolCalls: []ToolCall{call}}
+		} else if structured != nil {
+			choice["delta"] = streamChoiceDelta{Content: structured[i]}
+		} else if i < reasoning {
+			choice["delta"] = streamChoiceDelta{ReasoningContent: "think "}
+		} else {
+			choice["delta"] = streamChoiceDelta{Content: "tok "}
+		}
+		chunk := map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []interface{}{choice},
+		}
+		if m.config.ContinuousUsage {
+			chunk["usage"] = Usage{PromptTokens: promptTokens, CompletionTokens: i + 1, TotalTokens: promptTokens + i + 1}
+		}
+		writeEvent(chunk)
+	}
+
+	if m.config.ReportUsage && request.StreamOptions != nil && request.StreamOptions.IncludeUsage {
+		writeEvent(map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []interface{}{},
+			"usage":   usage,
+		})
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// mockStructuredOutput builds a document following schema and splits it
+// into at most tokens fragments. The first unbounded string is filled with
+// one word per token, so the document is long enough to cut that often.
+func mockStructuredOutput(schema *jsonSchema, tokens int) []string {
+	filled := false
+	data, _ := json.Marshal(mockSchemaInstance(schema, tokens, &filled))
+	runes := []rune(string(data))
+	n := min(tokens, len(runes))
+	fragments := make([]string, n)
+	for i := range fragments {
+		fragments[i] = string(runes[i*len(runes)/n : (i+1)*len(runes)/n])
+	}
+	return fragments
+}
+
+// mockSchemaInstance returns a value valid against schema. Only the first
+// choice of enums, combinators and type lists is used.
+func mockSchemaInstance(schema *jsonSchema, words int, filled *bool) interface{} {
+	if schema == nil {
+		return "tok"
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+	if len(schema.AnyOf) > 0 {
+		return mockSchemaInstance(schema.AnyOf[0], words, filled)
+	}
+	if len(schema.OneOf) > 0 {
+		return mockSchemaInstance(schema.OneOf[0], words, filled)
+	}
+
+	types := schema.types()
+	if len(types) == 0 {
+		if schema.Properties != nil {
+			types = []string{"object"}
+		} else {
+			types = []string{"string"}
+		}
+	}
+	switch types[0] {
+	case "object":
+		object := make(map[string]interface{})
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			object[name] = mockSchemaInstance(schema.Properties[name], words, filled)
+		}
+		return object
+	case "array":
+		count := 1
+		if schema.MinItems != nil {
+			count = max(*schema.MinItems, count)
+		}
+		if schema.MaxItems != nil {
+			count = min(*schema.MaxItems, count)
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			items[i] = mockSchemaInstance(schema.Items, words, filled)
+		}
+		return items
+	case "integer", "number":
+		value := 1.0
+		if schema.Minimum != nil {
+			value = math.Ceil(*schema.Minimum)
+		}
+		if schema.Maximum != nil && value > *schema.Maximum {
+			value = *schema.Maximum
+		}
+		return value
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	}
+
+	value := "tok"
+	if !*filled && schema.MaxLength == nil {
+		value = strings.TrimSpace(strings.Repeat("tok ", words))
+		*filled = true
+	}
+	if schema.MinLength != nil && len(value) < *schema.MinLength {
+		value += strings.Repeat("k", *schema.MinLength-len(value))
+	}
+	if schema.MaxLength != nil && len(value) > *schema.MaxLength {
+		value = value[:*schema.MaxLength]
+	}
+	return value
+}
+
+// sleep waits for base scaled by slowdown and jitter. It returns false if
+// the client went away first.
+func (m *MockServer) sleep(r *http.Request, base time.Duration, slowdown float64) bool {
+	if base <= 0 {
+		return r.Context().Err() == nil
+	}
+
+	factor := slowdown
+	if m.config.Jitter > 0 {
+		factor *= 1 + m.config.Jitter*(2*m.random()-1)
+	}
+	if factor < 0 {
+		factor = 0
+	}
+
+	timer := time.NewTimer(time.Duration(float64(base) * factor))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// cachedPrefix reports whether a prompt starting like request's was seen
+// before, and remembers this one. Prompts shorter than
+// mockPrefixCacheBytes are never cached.
+func (m *MockServer) cachedPrefix(request mockRequest) bool {
+	var builder strings.Builder
+	builder.WriteString(request.Prompt)
+	for _, message := range request.Messages {
+		builder.WriteString(message.Content)
+	}
+	text := builder.String()
+	if len(text) < mockPrefixCacheBytes {
+		return false
+	}
+
+	m.cacheMu.Lock()
+	defer m.cacheMu.Unlock()
+
+	key := text[:mockPrefixCacheBytes]
+	if m.prefixes[key] {
+		return true
+	}
+	m.prefixes[key] = true
+	return false
+}
+
+func (m *MockServer) random() float64 {
+	m.rngMu.Lock()
+	defer m.rngMu.Unlock()
+	return m.rng.Float64()
+}
+
+func writeMockError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{"message": message, "type": "mock_error"},
+	})
+}
+
+// runMockServer implements `llm-speed-test mock`, serving the mock API
+// until the process is stopped.
+func runMockServer(args []string) int {
+	config := MockServerConfig{ReportUsage: true}
+	var addr, models string
+
+	fs := flag.NewFlagSet("mock", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: llm-speed-test mock [flags]")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "Serves a mock OpenAI-compatible API under /v1 for offline benchmarking.")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "Flags:")
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&addr, "addr", "127.0.0.1:8000", "listen address")
+	fs.StringVar(&models, "models", "mock-model", "comma-separated model IDs")
+	fs.StringVar(&config.APIKey, "api-key", "", "required Bearer token, empty for no auth")
+	fs.DurationVar(&config.TTFT, "ttft", 200*time.Millisecond, "time to first token")
+	fs.Float64Var(&config.TokensPerSecond, "tps", 50, "decode tokens per second per request")
+	fs.Float64Var(&config.Jitter, "jitter", 0.1, "relative random spread of every delay (0-1)")
+	fs.Float64Var(&config.ConcurrencySlowdown, "slowdown", 0.05, "extra delay per additional in-flight request")
+	fs.Float64Var(&config.ErrorRate, "error-rate", 0, "fraction of requests failing with HTTP 500 (0-1)")
+	fs.IntVar(&config.OutputTokens, "output-tokens", 64, "tokens generated when max_tokens is not set")
+	fs.BoolVar(&config.ReportUsage, "usage", config.ReportUsage, "report token usage")
+	fs.BoolVar(&config.ContinuousUsage, "continuous-usage", config.ContinuousUsage, "attach running usage to every streamed chunk")
+	fs.StringVar(&config.Tokenizer, "tokenizer", config.Tokenizer, "HuggingFace tokenizer.json (or its directory) to count prompt tokens with")
+	fs.DurationVar(&config.CachedTTFT, "cached-ttft", config.CachedTTFT, "time to first token for prompts whose prefix was seen before, 0 to disable prefix caching")
+	fs.Float64Var(&config.MalformedToolCalls, "malformed-tool-calls", 0, "fraction of tool calls with truncated JSON arguments (0-1)")
+	fs.Float64Var(&config.StructuredSlowdown, "structured-slowdown", 0, "extra delay between tokens of requests constrained to a JSON schema (0.3 = +30%)")
+	fs.IntVar(&config.ReasoningTokens, "reasoning-tokens", 0, "thinking tokens streamed as reasoning_content before each chat answer, out of max_tokens")
+	fs.DurationVar(&config.ImageTokenTime, "image-token-time", 0, "extra time to first token per image token (one per 28x28 pixels), like a vision encoder")
+	fs.IntVar(&config.PromptOverhead, "prompt-overhead", config.PromptOverhead, "tokens added to the prompt usage of chat requests, like a chat template")
+	fs.IntVar(&config.MessageOverhead, "message-overhead", config.MessageOverhead, "tokens added to the prompt usage per chat message, like role headers")
+	fs.Int64Var(&config.Seed, "seed", 0, "seed for jitter and error injection, 0 for random")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return cliExitOK
+		}
+		return cliExitUsage
+	}
+	if config.ErrorRate < 0 || config.ErrorRate > 1 || config.Jitter < 0 || config.Jitter > 1 ||
+		config.MalformedToolCalls < 0 || config.MalformedToolCalls > 1 {
+		fmt.Fprintln(os.Stderr, "error: -error-rate, -jitter and -malformed-tool-calls must be between 0 and 1")
+		return cliExitUsage
+	}
+	if config.Tokenizer != "" {
+		if _, err := loadHFTokenizer(config.Tokenizer); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return cliExitUsage
+		}
+	}
+
+	for _, model := range strings.Split(models, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			config.Models = append(config.Models, model)
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "Mock API listening on http://%s/v1 (ttft %v, %.0f tok/s)\n", addr, config.TTFT, config.TokensPerSecond)
+	if err := http.ListenAndServe(addr, NewMockServer(config)); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return cliExitFailed
+	}
+	return cliExitOK
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func mockTestConfiguration(endpoint string) TestConfiguration {
+	config := defaultTestConfiguration()
+	config.APIEndpoint = endpoint
+	config.APIKey = "test-key"
+	config.Model = "mock-model"
+	config.PromptType = "custom"
+	config.Prompt = "Say something."
+	config.MaxTokens = 8
+	config.ConcurrentTests = 2
+	config.TestCount = 2
+	config.Timeout = 10
+	return config
+}
+
+func TestRunSpeedTestAgainstMockServer(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{
+		APIKey:          "test-key",
+		TTFT:            20 * time.Millisecond,
+		TokensPerSecond: 500,
+		ReportUsage:     true,
+		Seed:            1,
+	}))
+	defer server.Close()
+
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), mockTestConfiguration(server.URL+"/v1"), "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if batch.Summary.TotalTests != 4 || batch.Summary.SuccessfulTests != 4 {
+		t.Fatalf("expected 4 successful requests, got %+v", batch.Summary)
+	}
+	for _, result := range batch.Results {
+		if result.CompletionTokens != 8 || result.PromptTokens == 0 {
+			t.Fatalf("expected usage from the mock, got prompt=%d completion=%d", result.PromptTokens, result.CompletionTokens)
+		}
+		if result.RequestLatency < 20 {
+			t.Fatalf("expected TTFT of at least 20ms, got %.1fms", result.RequestLatency)
+		}
+		if result.InterTokenLatency <= 0 {
+			t.Fatalf("expected inter-token latency from streamed chunks")
+		}
+	}
+}
+
+// TestWorkloadsAgainstMockServer runs each workload feature end to end.
+// Every case starts from mockTestConfiguration and only sets what it
+// exercises; check also gets the requests the mock received. intercept,
+// when set, may answer a request in place of the mock.
+func TestWorkloadsAgainstMockServer(t *testing.T) {
+	cases := []struct {
+		name      string
+		mock      MockServerConfig
+		configure func(t *testing.T, config *TestConfiguration)
+		intercept func(w http.ResponseWriter, request OpenAIRequest) bool
+		check     func(t *testing.T, batch *TestBatch, requests []OpenAIRequest)
+	}{
+		{
+			name: "shared prefix",
+			mock: MockServerConfig{TTFT: 80 * time.Millisecond, CachedTTFT: 5 * time.Millisecond},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				config.PromptType = "fixed"
+				config.PromptLength = 200
+				config.MaxTokens = 2
+				config.PrefixConfig = PrefixConfiguration{Mode: prefixModeShared, Length: 120, HitRatio: 0.5}
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				pc := batch.Summary.PrefixCache
+				if pc == nil || pc.WarmRequests != 2 || pc.ColdRequests != 2 {
+					t.Fatalf("expected 2 warm and 2 cold requests, got %+v", pc)
+				}
+				if pc.AverageWarmTTFT >= pc.AverageColdTTFT || pc.TTFTSpeedup <= 1 {
+					t.Fatalf("expected warm requests to hit the mock's prefix cache, got %+v", pc)
+				}
+			},
+		},
+		{
+			name: "unique prefix",
+			mock: MockServerConfig{TTFT: 80 * time.Millisecond, CachedTTFT: 5 * time.Millisecond},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				config.PromptType = "fixed"
+				config.PromptLength = 200
+				config.MaxTokens = 2
+				config.PrefixConfig = PrefixConfiguration{Mode: prefixModeUnique}
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				if batch.Summary.PrefixCache != nil {
+					t.Fatalf("unique prompts should not be split into warm and cold")
+				}
+				for _, result := range batch.Results {
+					if result.RequestLatency < 40 {
+						t.Fatalf("result %d hit the prefix cache (TTFT %.1f ms)", result.TestNumber, result.RequestLatency)
+					}
+				}
+			},
+		},
+		{
+			name: "conversation",
+			mock: MockServerConfig{TTFT: time.Millisecond, TokensPerSecond: 2000},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				config.PromptType = "fixed"
+				config.PromptLength = 32
+				config.TestMode = "conversation"
+				config.ConversationConfig = ConversationConfiguration{Sessions: 2, Turns: 3, TurnLength: 16}
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				if len(batch.Results) != 6 {
+					t.Fatalf("expected 6 turns, got %d", len(batch.Results))
+				}
+
+				// Within a session every turn carries more context than the last.
+				sent := make(map[[2]int]int)
+				for _, result := range batch.Results {
+					if !result.Success {
+						t.Fatalf("turn %d of session %d failed: %s", result.Turn, result.Session, result.Error)
+					}
+					sent[[2]int{result.Session, result.Turn}] = result.PromptTokens
+				}
+				for session := 1; session <= 2; session++ {
+					for turn := 2; turn <= 3; turn++ {
+						if sent[[2]int{session, turn}] <= sent[[2]int{session, turn - 1}] {
+							t.Fatalf("session %d: turn %d sent %d prompt tokens after %d", session, turn,
+								sent[[2]int{session, turn}], sent[[2]int{session, turn - 1}])
+						}
+					}
+				}
+
+				if len(batch.Turns) != 3 || batch.Turns[2].AveragePromptTokens <= batch.Turns[0].AveragePromptTokens {
+					t.Fatalf("unexpected turn summaries: %+v", batch.Turns)
+				}
+
+				// The third turn resends both earlier exchanges, replies included.
+				longest := 0
+				for _, request := range requests {
+					messages := request.Messages
+					if len(messages) == 5 && messages[1].Role == "assistant" && messages[1].Content != "" {
+						longest++
+					}
+				}
+				if longest != 2 {
+					t.Fatalf("expected 2 requests with the full history, got %d", longest)
+				}
+			},
+		},
+		{
+			name: "tool calls",
+			mock: MockServerConfig{TTFT: 20 * time.Millisecond, TokensPerSecond: 500},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				config.PromptType = "fixed"
+				config.PromptLength = 32
+				config.MaxTokens = 16
+				config.ToolConfig = ToolConfiguration{Count: 3, ToolChoice: "search_web"}
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				for _, result := range batch.Results {
+					if !result.Success {
+						t.Fatalf("result %d failed: %s", result.TestNumber, result.Error)
+					}
+					if result.ToolCalls != 1 || result.ToolCallTokens == 0 {
+						t.Fatalf("result %d: expected one tool call with arguments, got %+v", result.TestNumber, result)
+					}
+					if result.TimeToFirstToolCall < 20 || result.ToolCallTokensPerSecond <= 0 {
+						t.Fatalf("result %d: unexpected tool call timing %.1f ms, %.1f tok/s",
+							result.TestNumber, result.TimeToFirstToolCall, result.ToolCallTokensPerSecond)
+					}
+					if result.RequestLatency > result.TimeToFirstToolCall+1 {
+						t.Fatalf("result %d: TTFT %.1f ms should include the tool call", result.TestNumber, result.RequestLatency)
+					}
+				}
+
+				tc := batch.Summary.ToolCalls
+				if tc == nil || tc.Requests != len(batch.Results) || tc.RequestsWithCalls != tc.Requests || tc.MalformedCalls != 0 {
+					t.Fatalf("unexpected tool call summary: %+v", tc)
+				}
+			},
+		},
+		{
+			name: "malformed tool calls",
+			mock: MockServerConfig{TTFT: time.Millisecond, MalformedToolCalls: 1},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				config.PromptType = "fixed"
+				config.PromptLength = 32
+				config.MaxTokens = 16
+				config.ToolConfig = ToolConfiguration{Count: 3}
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				for _, result := range batch.Results {
+					if result.Success || result.ErrorKind != errorKindMalformedToolCall {
+						t.Fatalf("result %d: expected a malformed tool call, got success=%v kind=%q error=%q",
+							result.TestNumber, result.Success, result.ErrorKind, result.Error)
+					}
+				}
+				if tc := batch.Summary.ToolCalls; tc == nil || tc.MalformedRate != 1 {
+					t.Fatalf("expected a malformed rate of 1, got %+v", tc)
+				}
+			},
+		},
+		{
+			name: "negative tool call index",
+			mock: MockServerConfig{TTFT: time.Millisecond},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				config.ToolConfig = ToolConfiguration{Count: 3}
+			},
+			intercept: func(w http.ResponseWriter, request OpenAIRequest) bool {
+				if !request.Stream {
+					return false
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				io.WriteString(w, `data: {"choices":[{"delta":{"tool_calls":[{"index":-1,"function":{"name":"get_weather","arguments":"{}"}}]}}]}`+"\n\n")
+				io.WriteString(w, "data: [DONE]\n\n")
+				return true
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				for _, result := range batch.Results {
+					if result.Success || result.ErrorKind != errorKindMalformedToolCall {
+						t.Fatalf("result %d: expected a malformed tool call, got success=%v kind=%q error=%q",
+							result.TestNumber, result.Success, result.ErrorKind, result.Error)
+					}
+				}
+				// The stream is not retried without streaming.
+				for _, request := range requests {
+					if !request.Stream {
+						t.Fatalf("a malformed tool call stream must not be retried without streaming")
+					}
+				}
+			},
+		},
+		{
+			name: "structured output",
+			mock: MockServerConfig{TTFT: time.Millisecond, TokensPerSecond: 400, StructuredSlowdown: 1},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				path := filepath.Join(t.TempDir(), "schema.json")
+				if err := os.WriteFile(path, []byte(testSchema), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				config.PromptType = "fixed"
+				config.PromptLength = 32
+				config.MaxTokens = 12
+				config.StructuredOutput = StructuredOutputConfiguration{SchemaPath: path, Method: structuredMethodGuidedJSON}
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				for _, result := range batch.Results {
+					if result.SchemaError != "" {
+						t.Fatalf("result %d does not follow the schema: %s (%q)", result.TestNumber, result.SchemaError, result.Response)
+					}
+				}
+
+				so := batch.Summary.StructuredOutput
+				if so == nil || so.ConstrainedRequests != 2 || so.BaselineRequests != 2 || so.ComplianceRate != 1 {
+					t.Fatalf("expected 2 compliant constrained and 2 baseline requests, got %+v", so)
+				}
+				if so.DecodeOverhead < 0.2 {
+					t.Fatalf("expected constrained decoding to be slower, got overhead %.2f", so.DecodeOverhead)
+				}
+			},
+		},
+		{
+			name: "image files",
+			mock: MockServerConfig{TTFT: time.Millisecond, PromptOverhead: 5},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				path := filepath.Join(t.TempDir(), "wide.png")
+				f, err := os.Create(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := png.Encode(f, image.NewRGBA(image.Rect(0, 0, 200, 100))); err != nil {
+					t.Fatal(err)
+				}
+				f.Close()
+				config.VisionConfig = VisionConfiguration{Images: []string{path}, Count: 3, Resolution: 56}
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				for _, result := range batch.Results {
+					// 56x28 pixels is two 28-pixel patches per image.
+					if !result.Success || result.Images != 3 || result.ImageResolution != 56 || result.ImageTokens != 6 {
+						t.Fatalf("result %d: expected 3 images of 56 px and 6 image tokens, got %+v", result.TestNumber, result)
+					}
+				}
+				if vs := batch.Summary.Vision; vs == nil || vs.Requests != len(batch.Results) || vs.AverageImageTokens != 6 {
+					t.Fatalf("unexpected vision summary: %+v", vs)
+				}
+			},
+		},
+		{
+			name: "image count step",
+			mock: MockServerConfig{TTFT: time.Millisecond, ImageTokenTime: 2 * time.Millisecond, PromptOverhead: 5},
+			configure: func(t *testing.T, config *TestConfiguration) {
+				config.TestMode = "image_count_step"
+				config.StepConfig = StepConfiguration{Start: 0, End: 2, Step: 1}
+				config.VisionConfig.Resolution = 112
+			},
+			check: func(t *testing.T, batch *TestBatch, requests []OpenAIRequest) {
+				if len(batch.SweepPoints) != 3 {
+					t.Fatalf("expected 3 step points, got %d", len(batch.SweepPoints))
+				}
+				for i, point := range batch.SweepPoints {
+					if point.Coordinates["visionConfig.count"] != float64(i) {
+						t.Fatalf("point %d: unexpected coordinates %v", i, point.Coordinates)
+					}
+					if i == 0 {
+						if point.Summary.Vision != nil {
+							t.Fatalf("the text-only point should have no vision summary, got %+v", point.Summary.Vision)
+						}
+						continue
+					}
+					// Each 112 px image is 16 tokens, encoded in 32 ms by the mock.
+					if vs := point.Summary.Vision; vs == nil || vs.AverageImageTokens != float64(16*i) {
+						t.Fatalf("point %d: expected %d image tokens, got %+v", i, 16*i, vs)
+					}
+					previous := batch.SweepPoints[i-1].Summary.PrefillLatencyDistribution.P50
+					if ttft := point.Summary.PrefillLatencyDistribution.P50; ttft < previous+20 {
+						t.Fatalf("point %d: TTFT %.1f ms should grow with the images (previous %.1f ms)", i, ttft, previous)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.mock.ReportUsage = true
+			tc.mock.Seed = 1
+			mock := NewMockServer(tc.mock)
+			var mu sync.Mutex
+			var requests []OpenAIRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				var request OpenAIRequest
+				if json.Unmarshal(body, &request) == nil {
+					mu.Lock()
+					requests = append(requests, request)
+					mu.Unlock()
+					if tc.intercept != nil && tc.intercept(w, request) {
+						return
+					}
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				mock.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			config := mockTestConfiguration(server.URL + "/v1")
+			tc.configure(t, &config)
+			batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), config, "")
+			if err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+			tc.check(t, batch, requests)
+		})
+	}
+}
+
+func TestMockServerInjectsErrors(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{ErrorRate: 1, ReportUsage: true}))
+	defer server.Close()
+
+	config := mockTestConfiguration(server.URL + "/v1")
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), config, "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if batch.Summary.FailedTests != batch.Summary.TotalTests || batch.Summary.ErrorRate != 1 {
+		t.Fatalf("expected every request to fail, got %+v", batch.Summary)
+	}
+}
@@ -4,8 +4,9 @@ package main
 type TestConfiguration struct {
 	APIEndpoint      string  `json:"apiEndpoint"`
 	APIKey           string  `json:"apiKey"`
+	Provider         string  `json:"provider,omitempty"` // "openai" (default), "openai_completions", "anthropic", "ollama" or "tgi"
 	Model            string  `json:"model"`
-	PromptType       string  `json:"promptType"`   // "fixed" or "custom"
+	PromptType       string  `json:"promptType"`   // "fixed", "simple", "complex", "custom" or "dataset"
 	PromptLength     int     `json:"promptLength"` // Length of prompt in tokens
 	Prompt           string  `json:"prompt"`       // Custom prompt (if PromptType is "custom")
 	MaxTokens        int     `json:"maxTokens"`
@@ -14,14 +15,39 @@ type TestConfiguration struct {
 	PresencePenalty  float32 `json:"presencePenalty"`
 	FrequencyPenalty float32 `json:"frequencyPenalty"`
 
+	DatasetConfig    DatasetConfiguration          `json:"datasetConfig"`    // Workload file (if PromptType is "dataset")
+	PrefixConfig     PrefixConfiguration           `json:"prefixConfig"`     // Prefix sharing of synthetic prompts
+	ToolConfig       ToolConfiguration             `json:"toolConfig"`       // Tool definitions sent with every request
+	StructuredOutput StructuredOutputConfiguration `json:"structuredOutput"` // JSON schema half of the requests are constrained to
+	VisionConfig     VisionConfiguration           `json:"visionConfig"`     // Images sent with every request
+
 	// Test Mode Configuration
-	TestMode   string            `json:"testMode"`   // "normal", "concurrency_step", "input_step"
-	StepConfig StepConfiguration `json:"stepConfig"` // Configuration for step tests
+	TestMode           string                    `json:"testMode"`           // "normal", "concurrency_step", "input_step", "image_count_step", "image_resolution_step", "sweep", "rate", "rate_step", "conversation"
+	StepConfig         StepConfiguration         `json:"stepConfig"`         // Configuration for step tests
+	Sweep              []SweepDimension          `json:"sweep,omitempty"`    // Dimensions of a sweep test; every combination of their values is run
+	RateConfig         RateConfiguration         `json:"rateConfig"`         // Configuration for open-loop (rate) tests
+	SLO                SLOConfiguration          `json:"slo"`                // Thresholds each rate_step step is checked against
+	ConversationConfig ConversationConfiguration `json:"conversationConfig"` // Sessions and turns of a conversation test
 
 	TestCount       int               `json:"testCount"`       // Number of submission rounds (per step)
 	ConcurrentTests int               `json:"concurrentTests"` // Requests per round (base or fixed)
 	Timeout         int               `json:"timeout"`         // in seconds
 	Headers         map[string]string `json:"headers,omitempty"`
+
+	// ExactPromptLength probes the server before the test and sizes
+	// synthetic prompts so the prompt tokens it reports (chat template
+	// included) equal promptLength.
+	ExactPromptLength bool `json:"exactPromptLength,omitempty"`
+
+	// Tokenizer is a HuggingFace tokenizer.json, or a directory holding
+	// one, used to build prompts and count tokens. When empty the app's
+	// tokenizer mappings are consulted, then the model's tiktoken encoding.
+	Tokenizer string `json:"tokenizer,omitempty"`
+
+	// Seed drives prompt generation, dataset sampling and open-loop
+	// arrivals. Runs with the same seed and configuration send the same
+	// prompts on the same schedule; 0 picks a seed from the clock.
+	Seed int64 `json:"seed,omitempty"`
 }
 
 // StepConfiguration defines parameters for step-based tests
@@ -31,38 +57,235 @@ type StepConfiguration struct {
 	Step  int `json:"step"`
 }
 
+// SweepDimension varies one numeric configuration field, named by its JSON
+// path (e.g. "maxTokens" or "temperature"), over either an explicit list of
+// values or an inclusive Start..End range.
+type SweepDimension struct {
+	Field  string    `json:"field"`
+	Values []float64 `json:"values,omitempty"`
+	Start  float64   `json:"start,omitempty"`
+	End    float64   `json:"end,omitempty"`
+	Step   float64   `json:"step,omitempty"`
+}
+
+// ConversationConfiguration defines a multi-turn chat workload. Sessions
+// run concurrently, up to concurrentTests at a time, and each sends Turns
+// requests one after another. Every turn resends the whole history: the
+// earlier user messages and the assistant's actual replies, followed by a
+// new user message.
+type ConversationConfiguration struct {
+	Sessions   int `json:"sessions"`
+	Turns      int `json:"turns"`      // Requests per session
+	TurnLength int `json:"turnLength"` // Tokens of each user message after the first (0 = promptLength)
+}
+
+// ToolConfiguration sends tool definitions with every request, to measure
+// how fast a model starts and finishes a function call. Tools come from
+// Path when set, otherwise the first Count built-in definitions are sent.
+type ToolConfiguration struct {
+	Count      int    `json:"count"`      // Built-in tool definitions sent (0 = none unless Path is set)
+	Path       string `json:"path"`       // JSON array of OpenAI tool definitions sent instead
+	ToolChoice string `json:"toolChoice"` // "required" (default), "auto" or the name of a tool to force
+}
+
+// StructuredOutputConfiguration constrains every other request to a JSON
+// schema, so the decode cost of guided decoding shows against the
+// unconstrained requests in between.
+type StructuredOutputConfiguration struct {
+	SchemaPath string `json:"schemaPath"` // JSON schema file (empty = unconstrained)
+	Method     string `json:"method"`     // "response_format" (default, json_schema) or "guided_json" (vLLM)
+}
+
+// VisionConfiguration attaches Count images to the user message of every
+// request, as base64 data URLs in an OpenAI content array, to benchmark
+// vision-language models. Files are sent in turn; without files each image
+// is generated noise unique to its request, so encoder caches never hit.
+type VisionConfiguration struct {
+	Images     []string `json:"images,omitempty"` // Local image files (empty = generated images)
+	Count      int      `json:"count"`            // Images per request (0 = text only)
+	Resolution int      `json:"resolution"`       // Longest side in pixels images are scaled to (0 = original size; required for generated images)
+	Detail     string   `json:"detail"`           // image_url detail: "auto", "low" or "high" (empty = server default)
+}
+
+// PrefixConfiguration controls how synthetic prompts start, to exercise
+// or rule out server-side prefix caching. In "shared" mode every prompt
+// opens with a Length-token prefix generated once per batch; a HitRatio
+// share of the requests reuses it (warm) and the rest get a fresh prefix
+// of the same length (cold). In "unique" mode every prompt opens with a
+// marker no other request shares. The prefix counts towards promptLength.
+type PrefixConfiguration struct {
+	Mode         string  `json:"mode"`         // "" (plain random prompts), "shared" or "unique"
+	Length       int     `json:"length"`       // Prefix tokens ("shared" mode)
+	HitRatio     float64 `json:"hitRatio"`     // Fraction of requests reusing the shared prefix (0-1)
+	SystemPrompt bool    `json:"systemPrompt"` // Send the shared prefix as a system message
+}
+
+// DatasetConfiguration points a test at a file of recorded conversations.
+// Each request replays one record: its messages are sent as-is and its
+// expected output length becomes max_tokens.
+type DatasetConfiguration struct {
+	Path            string `json:"path"`
+	Format          string `json:"format"`          // "sharegpt" or "jsonl"; empty detects from the file extension
+	Seed            int64  `json:"seed"`            // Sampling seed (0 = derived from the test seed)
+	MinPromptTokens int    `json:"minPromptTokens"` // Skip records with shorter prompts (0 = no limit)
+	MaxPromptTokens int    `json:"maxPromptTokens"` // Skip records with longer prompts (0 = no limit)
+}
+
+// RateConfiguration defines open-loop load generation, where requests are
+// sent on an arrival schedule instead of waiting for free concurrency slots.
+type RateConfiguration struct {
+	RequestRate    float64 `json:"requestRate"`    // Target arrival rate in requests per second
+	ArrivalProcess string  `json:"arrivalProcess"` // "constant" (default), "poisson" or "burst"
+	BurstSize      int     `json:"burstSize"`      // Requests released together in "burst" mode
+	NumRequests    int     `json:"numRequests"`    // Requests sent per rate
+	MaxInFlight    int     `json:"maxInFlight"`    // Optional cap on outstanding requests (0 = unlimited)
+
+	// Rate sweep ("rate_step" mode): rates from RateStart to RateEnd in
+	// increments of RateStep, NumRequests requests each.
+	RateStart float64 `json:"rateStart"`
+	RateEnd   float64 `json:"rateEnd"`
+	RateStep  float64 `json:"rateStep"`
+}
+
+// SLOConfiguration holds service level objectives used by rate sweeps.
+// A zero latency threshold is not checked. The error rate is checked
+// whenever it is set, so 0 allows no failed requests.
+type SLOConfiguration struct {
+	MaxP95TTFT    float64  `json:"maxP95Ttft"`             // ms
+	MaxP95Latency float64  `json:"maxP95Latency"`          // ms, total request latency
+	MaxP95TPOT    float64  `json:"maxP95Tpot"`             // ms
+	MaxErrorRate  *float64 `json:"maxErrorRate,omitempty"` // 0-1, nil to ignore
+}
+
 // TestResult represents the result of a single LLM speed test
 type TestResult struct {
-	ID                     string            `json:"id"`
-	Timestamp              string            `json:"timestamp"`
-	Configuration          TestConfiguration `json:"configuration"`
-	TestNumber             int               `json:"testNumber"`
-	RoundNumber            int               `json:"roundNumber"`
-	RoundPosition          int               `json:"roundPosition"`
-	ActualConcurrency      int               `json:"actualConcurrency"` // The concurrency level for this specific result
-	PromptTokens           int               `json:"promptTokens"`
-	CompletionTokens       int               `json:"completionTokens"`
-	TotalTokens            int               `json:"totalTokens"`
-	RequestLatency         float64           `json:"requestLatency"` // ms
-	TotalLatency           float64           `json:"totalLatency"`   // ms
-	OutputLatency          float64           `json:"outputLatency"`  // ms spent generating tokens
-	PrefillTokensPerSecond float64           `json:"prefillTokensPerSecond"`
-	OutputTokensPerSecond  float64           `json:"outputTokensPerSecond"`
-	Throughput             float64           `json:"throughput"` // tokens per second (decode)
-	Error                  string            `json:"error,omitempty"`
-	Success                bool              `json:"success"`
-	Response               string            `json:"response,omitempty"`
+	ID                        string             `json:"id"`
+	Timestamp                 string             `json:"timestamp"`
+	Configuration             TestConfiguration  `json:"configuration"`
+	TestNumber                int                `json:"testNumber"`
+	RoundNumber               int                `json:"roundNumber"`
+	RoundPosition             int                `json:"roundPosition"`
+	ActualConcurrency         int                `json:"actualConcurrency"` // The concurrency level for this specific result
+	PromptTokens              int                `json:"promptTokens"`
+	CompletionTokens          int                `json:"completionTokens"`
+	LocalCompletionTokens     int                `json:"localCompletionTokens"` // Completion tokens counted from the response text with the local tokenizer
+	TotalTokens               int                `json:"totalTokens"`
+	RequestLatency            float64            `json:"requestLatency"` // ms
+	TotalLatency              float64            `json:"totalLatency"`   // ms
+	OutputLatency             float64            `json:"outputLatency"`  // ms spent generating tokens
+	PrefillTokensPerSecond    float64            `json:"prefillTokensPerSecond"`
+	OutputTokensPerSecond     float64            `json:"outputTokensPerSecond"`
+	Throughput                float64            `json:"throughput"`                 // tokens per second (decode)
+	TimePerOutputToken        float64            `json:"timePerOutputToken"`         // ms, output latency / (completion tokens - 1)
+	InterTokenLatency         float64            `json:"interTokenLatency"`          // ms, mean gap between streamed chunks
+	InterTokenLatencyP50      float64            `json:"interTokenLatencyP50"`       // ms
+	InterTokenLatencyP99      float64            `json:"interTokenLatencyP99"`       // ms
+	MaxStall                  float64            `json:"maxStall"`                   // ms, longest gap between streamed chunks
+	QueueDelay                float64            `json:"queueDelay"`                 // ms between scheduled arrival and dispatch (rate mode)
+	SweepPoint                map[string]float64 `json:"sweepPoint,omitempty"`       // Swept field values this request ran with (step and sweep modes)
+	PrefixCache               string             `json:"prefixCache,omitempty"`      // "warm" when the prompt reused the shared prefix, "cold" when it had a fresh one (shared prefix mode)
+	Session                   int                `json:"session,omitempty"`          // Conversation session this request belongs to (conversation mode)
+	Turn                      int                `json:"turn,omitempty"`             // 1-based turn within the session (conversation mode)
+	ToolCalls                 int                `json:"toolCalls,omitempty"`        // Tool calls in the response (tool tests)
+	TimeToFirstToolCall       float64            `json:"timeToFirstToolCall"`        // ms until the first tool call chunk
+	ToolCallTokens            int                `json:"toolCallTokens"`             // Tokens of all tool call arguments
+	ToolCallTokensPerSecond   float64            `json:"toolCallTokensPerSecond"`    // Argument tokens over the time they streamed in
+	StructuredOutput          string             `json:"structuredOutput,omitempty"` // "constrained" when the output was held to the schema, "baseline" otherwise (structured output tests)
+	SchemaError               string             `json:"schemaError,omitempty"`      // Why a constrained output does not follow the schema
+	TimeToFirstReasoningToken float64            `json:"timeToFirstReasoningToken"`  // ms; 0 when the model did not reason
+	TimeToFirstAnswerToken    float64            `json:"timeToFirstAnswerToken"`     // ms until the first answer token after the reasoning
+	ReasoningTokens           int                `json:"reasoningTokens"`            // Server-reported when available, otherwise counted locally
+	AnswerTokens              int                `json:"answerTokens"`               // Completion tokens that are not reasoning
+	ReasoningTokensPerSecond  float64            `json:"reasoningTokensPerSecond"`   // Reasoning tokens over the time they streamed in
+	AnswerTokensPerSecond     float64            `json:"answerTokensPerSecond"`      // Answer tokens from the first answer token to the end
+	Images                    int                `json:"images,omitempty"`           // Images sent with the prompt (vision tests)
+	ImageResolution           int                `json:"imageResolution,omitempty"`  // Longest side in pixels of the largest image sent
+	ImageTokens               int                `json:"imageTokens,omitempty"`      // Prompt tokens reported by the server beyond the text and its chat template
+	Error                     string             `json:"error,omitempty"`
+	ErrorKind                 string             `json:"errorKind,omitempty"` // "malformed_tool_call" or "missing_tool_call"; empty for other failures
+	Success                   bool               `json:"success"`
+	Response                  string             `json:"response,omitempty"`
+
+	// interTokenGaps keeps the raw chunk gaps (ms) so the batch summary
+	// can pool them; it is not persisted.
+	interTokenGaps []float64
 }
 
 // TestBatch represents a batch of test results
 type TestBatch struct {
-	ID             string            `json:"id"`
-	StartTime      string            `json:"startTime"`
-	EndTime        string            `json:"endTime"`
-	Configuration  TestConfiguration `json:"configuration"`
-	Results        []TestResult      `json:"results"`
-	RoundSummaries []RoundSummary    `json:"roundSummaries,omitempty"`
-	Summary        TestSummary       `json:"summary"`
+	ID             string              `json:"id"`
+	Name           string              `json:"name,omitempty"` // User-assigned label shown in history
+	Tags           []string            `json:"tags,omitempty"`
+	Baseline       bool                `json:"baseline,omitempty"` // Reference run for regression checks; never pruned
+	MatrixID       string              `json:"matrixId,omitempty"` // Set for batches run side by side in one matrix run
+	ReplayOf       string              `json:"replayOf,omitempty"` // ID of the batch this one replays
+	Seed           int64               `json:"seed,omitempty"`     // Seed the batch's prompts and arrivals were generated from
+	StartTime      string              `json:"startTime"`
+	EndTime        string              `json:"endTime"`
+	Configuration  TestConfiguration   `json:"configuration"`
+	Results        []TestResult        `json:"results"`
+	RoundSummaries []RoundSummary      `json:"roundSummaries,omitempty"`
+	Summary        TestSummary         `json:"summary"`
+	RateSweep      *RateSweepResult    `json:"rateSweep,omitempty"`   // Set for rate_step tests
+	SweepPoints    []SweepPointSummary `json:"sweepPoints,omitempty"` // One entry per point of a step or sweep test, in run order
+	Turns          []TurnSummary       `json:"turns,omitempty"`       // One entry per turn of a conversation test, over all sessions
+
+	PromptCalibration *PromptCalibration `json:"promptCalibration,omitempty"` // Set when exactPromptLength is enabled
+}
+
+// PromptCalibration records how synthetic prompts were sized so the server
+// sees the requested number of prompt tokens.
+type PromptCalibration struct {
+	TemplateOverhead int                       `json:"templateOverhead"` // Server prompt tokens minus local tokens for the first probe
+	Lengths          []PromptLengthCalibration `json:"lengths"`
+}
+
+// PromptLengthCalibration is the calibration of one requested prompt length.
+type PromptLengthCalibration struct {
+	TargetTokens int `json:"targetTokens"`
+	LocalTokens  int `json:"localTokens"`  // Local tokenizer length prompts are generated at
+	ServerTokens int `json:"serverTokens"` // Prompt tokens the server reported for the closest probe
+	Probes       int `json:"probes"`
+}
+
+// RateSweepResult reports the outcome of a rate_step test.
+type RateSweepResult struct {
+	Goodput float64           `json:"goodput"` // Highest request rate (req/s) that met every SLO; 0 if none did
+	Steps   []RateStepSummary `json:"steps"`
+}
+
+// SweepPointSummary aggregates the requests run at one point of a step or
+// sweep test.
+type SweepPointSummary struct {
+	Coordinates                map[string]float64 `json:"coordinates"` // Swept field -> value
+	Summary                    TestSummary        `json:"summary"`
+	Duration                   float64            `json:"duration"`                   // ms of wall time spent on the point
+	TotalOutputTokensPerSecond float64            `json:"totalOutputTokensPerSecond"` // Completion tokens per second of wall time
+}
+
+// TurnSummary aggregates one turn of a conversation test over all
+// sessions, showing how latency grows with the accumulated context.
+type TurnSummary struct {
+	Turn                int         `json:"turn"`
+	AveragePromptTokens float64     `json:"averagePromptTokens"` // Context sent with the turn, history included
+	Summary             TestSummary `json:"summary"`
+}
+
+// RateStepSummary captures one arrival rate of a rate sweep and whether it
+// stayed within the configured SLO.
+type RateStepSummary struct {
+	RequestRate                float64  `json:"requestRate"`  // Target arrival rate (req/s)
+	AchievedRate               float64  `json:"achievedRate"` // Completed requests per second of step wall time
+	TotalRequests              int      `json:"totalRequests"`
+	SuccessfulRequests         int      `json:"successfulRequests"`
+	ErrorRate                  float64  `json:"errorRate"`
+	P95TTFT                    float64  `json:"p95Ttft"`    // ms
+	P95Latency                 float64  `json:"p95Latency"` // ms
+	P95TPOT                    float64  `json:"p95Tpot"`    // ms
+	AverageQueueDelay          float64  `json:"averageQueueDelay"`
+	TotalOutputTokensPerSecond float64  `json:"totalOutputTokensPerSecond"` // Completion tokens per second of step wall time
+	MeetsSLO                   bool     `json:"meetsSlo"`
+	Violations                 []string `json:"violations,omitempty"`
 }
 
 // TestSummary provides aggregated statistics for a test batch
@@ -92,6 +315,110 @@ type TestSummary struct {
 	MinRoundThroughput            float64 `json:"minRoundThroughput"`
 	MaxRoundThroughput            float64 `json:"maxRoundThroughput"`
 	ErrorRate                     float64 `json:"errorRate"`
+
+	// Tail statistics over successful requests
+	LatencyDistribution               DistributionStats `json:"latencyDistribution"`               // Total latency (ms)
+	PrefillLatencyDistribution        DistributionStats `json:"prefillLatencyDistribution"`        // TTFT (ms)
+	OutputLatencyDistribution         DistributionStats `json:"outputLatencyDistribution"`         // Output latency (ms)
+	OutputTokensPerSecondDistribution DistributionStats `json:"outputTokensPerSecondDistribution"` // Decode tokens/sec
+
+	// Decode smoothness
+	AverageTimePerOutputToken      float64           `json:"averageTimePerOutputToken"` // ms
+	TimePerOutputTokenDistribution DistributionStats `json:"timePerOutputTokenDistribution"`
+	AverageInterTokenLatency       float64           `json:"averageInterTokenLatency"`      // ms, mean of per-request means
+	InterTokenLatencyDistribution  DistributionStats `json:"interTokenLatencyDistribution"` // pooled over all chunk gaps
+	MaxStall                       float64           `json:"maxStall"`                      // ms, longest gap seen in the batch
+
+	// Client-side queueing in open-loop tests
+	AverageQueueDelay      float64           `json:"averageQueueDelay"` // ms
+	QueueDelayDistribution DistributionStats `json:"queueDelayDistribution"`
+
+	// Server-reported vs locally counted completion tokens
+	UsageDeviation float64 `json:"usageDeviation"` // Mean of (reported - local) / local; large values suggest misreported usage
+
+	// TTFT of warm vs cold requests in shared prefix mode
+	PrefixCache *PrefixCacheSummary `json:"prefixCache,omitempty"`
+
+	// Tool call timing and failures in tool tests
+	ToolCalls *ToolCallSummary `json:"toolCalls,omitempty"`
+
+	// Constrained vs unconstrained decoding in structured output tests
+	StructuredOutput *StructuredOutputSummary `json:"structuredOutput,omitempty"`
+
+	// Reasoning vs answer tokens of reasoning models
+	Reasoning *ReasoningSummary `json:"reasoning,omitempty"`
+
+	// Image share of the prompt in vision tests
+	Vision *VisionSummary `json:"vision,omitempty"`
+}
+
+// VisionSummary describes the requests that carried images. Their TTFT
+// includes the server's image decoding, preprocessing and vision encoding.
+type VisionSummary struct {
+	Requests           int               `json:"requests"` // Successful requests with images
+	AverageImages      float64           `json:"averageImages"`
+	AverageImageTokens float64           `json:"averageImageTokens"`
+	ImageTokenShare    float64           `json:"imageTokenShare"` // Image tokens / prompt tokens (0-1)
+	AverageTTFT        float64           `json:"averageTTFT"`     // ms
+	TTFTDistribution   DistributionStats `json:"ttftDistribution"`
+}
+
+// ReasoningSummary splits the output of reasoning models into thinking and
+// answer, over the successful requests that reasoned.
+type ReasoningSummary struct {
+	Requests                              int               `json:"requests"` // Successful requests with reasoning tokens
+	AverageReasoningTokens                float64           `json:"averageReasoningTokens"`
+	AverageAnswerTokens                   float64           `json:"averageAnswerTokens"`
+	AverageTimeToFirstReasoningToken      float64           `json:"averageTimeToFirstReasoningToken"` // ms
+	TimeToFirstReasoningTokenDistribution DistributionStats `json:"timeToFirstReasoningTokenDistribution"`
+	AverageTimeToFirstAnswerToken         float64           `json:"averageTimeToFirstAnswerToken"` // ms
+	TimeToFirstAnswerTokenDistribution    DistributionStats `json:"timeToFirstAnswerTokenDistribution"`
+	AverageReasoningTokensPerSecond       float64           `json:"averageReasoningTokensPerSecond"`
+	ReasoningTokensPerSecondDistribution  DistributionStats `json:"reasoningTokensPerSecondDistribution"`
+	AverageAnswerTokensPerSecond          float64           `json:"averageAnswerTokensPerSecond"`
+	AnswerTokensPerSecondDistribution     DistributionStats `json:"answerTokensPerSecondDistribution"`
+}
+
+// StructuredOutputSummary compares requests constrained to a JSON schema
+// with the unconstrained baseline requests of the same batch.
+type StructuredOutputSummary struct {
+	ConstrainedRequests         int               `json:"constrainedRequests"` // Successful constrained requests
+	BaselineRequests            int               `json:"baselineRequests"`
+	CompliantRequests           int               `json:"compliantRequests"` // Constrained outputs that follow the schema
+	ComplianceRate              float64           `json:"complianceRate"`    // CompliantRequests / ConstrainedRequests
+	AverageConstrainedTPS       float64           `json:"averageConstrainedTps"`
+	AverageBaselineTPS          float64           `json:"averageBaselineTps"`
+	ConstrainedTPSDistribution  DistributionStats `json:"constrainedTpsDistribution"`
+	BaselineTPSDistribution     DistributionStats `json:"baselineTpsDistribution"`
+	ConstrainedTTFTDistribution DistributionStats `json:"constrainedTtftDistribution"` // ms; includes grammar setup
+	BaselineTTFTDistribution    DistributionStats `json:"baselineTtftDistribution"`    // ms
+	DecodeOverhead              float64           `json:"decodeOverhead"`              // 1 - median constrained / median baseline output tok/s; 0 without both kinds
+}
+
+// ToolCallSummary reports how fast tool calls were produced and how many
+// of them could not be used.
+type ToolCallSummary struct {
+	Requests                            int               `json:"requests"`                   // Requests sent with tools
+	RequestsWithCalls                   int               `json:"requestsWithCalls"`          // Successful requests that returned a tool call
+	MalformedCalls                      int               `json:"malformedCalls"`             // Requests whose tool call had unparseable arguments
+	MissingCalls                        int               `json:"missingCalls"`               // Requests that answered in text although a call was required
+	MalformedRate                       float64           `json:"malformedRate"`              // MalformedCalls / Requests
+	AverageTimeToFirstToolCall          float64           `json:"averageTimeToFirstToolCall"` // ms
+	TimeToFirstToolCallDistribution     DistributionStats `json:"timeToFirstToolCallDistribution"`
+	AverageToolCallTokensPerSecond      float64           `json:"averageToolCallTokensPerSecond"`
+	ToolCallTokensPerSecondDistribution DistributionStats `json:"toolCallTokensPerSecondDistribution"`
+}
+
+// PrefixCacheSummary compares the TTFT of requests that reused the shared
+// prefix with those that did not.
+type PrefixCacheSummary struct {
+	WarmRequests         int               `json:"warmRequests"` // Successful requests reusing the shared prefix
+	ColdRequests         int               `json:"coldRequests"`
+	AverageWarmTTFT      float64           `json:"averageWarmTtft"` // ms
+	AverageColdTTFT      float64           `json:"averageColdTtft"` // ms
+	WarmTTFTDistribution DistributionStats `json:"warmTtftDistribution"`
+	ColdTTFTDistribution DistributionStats `json:"coldTtftDistribution"`
+	TTFTSpeedup          float64           `json:"ttftSpeedup"` // Median cold TTFT / median warm TTFT; 0 without both kinds
 }
 
 // RoundSummary captures aggregated metrics for a single test round
@@ -111,6 +438,12 @@ type RoundSummary struct {
 	TotalPrefillTokensPerSecond   float64 `json:"totalPrefillTokensPerSecond"`
 	AverageOutputTokensPerSecond  float64 `json:"averageOutputTokensPerSecond"`
 	TotalOutputTokensPerSecond    float64 `json:"totalOutputTokensPerSecond"`
+
+	// Tail statistics over the round's successful requests
+	TotalLatencyDistribution          DistributionStats `json:"totalLatencyDistribution"`
+	PrefillLatencyDistribution        DistributionStats `json:"prefillLatencyDistribution"`
+	OutputLatencyDistribution         DistributionStats `json:"outputLatencyDistribution"`
+	OutputTokensPerSecondDistribution DistributionStats `json:"outputTokensPerSecondDistribution"`
 }
 
 // ProgressUpdate represents a progress update during test execution
@@ -144,8 +477,32 @@ type ComparisonRequest struct {
 
 // ComparisonResult represents the result of comparing multiple test batches
 type ComparisonResult struct {
-	Batches    []TestBatch       `json:"batches"`
-	Comparison ComparisonSummary `json:"comparison"`
+	Batches    []TestBatch           `json:"batches"`
+	Comparison ComparisonSummary     `json:"comparison"`
+	MatrixID   string                `json:"matrixId,omitempty"` // Set when the batches come from one matrix run
+	Targets    []MatrixTargetSummary `json:"targets,omitempty"`  // Per-target outcome of a matrix run, in target order
+}
+
+// MatrixTarget is one endpoint/model pair of a matrix run. It embeds a
+// saved API config so stored connections can be reused as targets.
+type MatrixTarget struct {
+	SavedAPIConfig
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model"`
+	BatchID  string `json:"batchId,omitempty"` // Batch to run this target as; generated when empty
+
+	Tokenizer string `json:"tokenizer,omitempty"` // HuggingFace tokenizer for this target's model; see TestConfiguration.Tokenizer
+}
+
+// MatrixTargetSummary reports how one target of a matrix run did.
+type MatrixTargetSummary struct {
+	Name        string      `json:"name"`
+	APIEndpoint string      `json:"apiEndpoint"`
+	Provider    string      `json:"provider,omitempty"`
+	Model       string      `json:"model"`
+	BatchID     string      `json:"batchId"`
+	Summary     TestSummary `json:"summary"`
+	Error       string      `json:"error,omitempty"` // Set when the target's run failed
 }
 
 // ComparisonSummary provides comparison statistics
@@ -154,6 +511,43 @@ type ComparisonSummary struct {
 	BestThroughputBatchID      string `json:"bestThroughputBatchId"`
 	BestRoundThroughputBatchID string `json:"bestRoundThroughputBatchId"`
 	LowestErrorRateBatchID     string `json:"lowestErrorRateBatchId"`
+	BestP95TTFTBatchID         string `json:"bestP95TtftBatchId"`
+	BestP95LatencyBatchID      string `json:"bestP95LatencyBatchId"`
+}
+
+// RegressionTolerances bounds how much worse a batch may be than its
+// baseline before it counts as a regression. Latency and throughput limits
+// are relative changes of the median; the error rate limit is absolute.
+type RegressionTolerances struct {
+	MaxTTFTIncrease      float64 `json:"maxTtftIncrease"`      // 0.1 = median TTFT may rise 10%
+	MaxLatencyIncrease   float64 `json:"maxLatencyIncrease"`   // Median total latency
+	MaxTPOTIncrease      float64 `json:"maxTpotIncrease"`      // Median time per output token
+	MaxOutputTPSDecrease float64 `json:"maxOutputTpsDecrease"` // 0.1 = median output tok/s may drop 10%
+	MaxErrorRateIncrease float64 `json:"maxErrorRateIncrease"` // 0.01 = error rate may rise one point
+	Significance         float64 `json:"significance"`         // p-value below which a change is real (0 = 0.05)
+}
+
+// RegressionCheck is the verdict for one metric of a baseline comparison.
+type RegressionCheck struct {
+	Metric    string  `json:"metric"`
+	Unit      string  `json:"unit"`
+	Baseline  float64 `json:"baseline"`  // Median, or rate for the error rate
+	Candidate float64 `json:"candidate"` // Median, or rate for the error rate
+	Change    float64 `json:"change"`    // Relative change (absolute for the error rate); positive = worse
+	Tolerance float64 `json:"tolerance"`
+	PValue    float64 `json:"pValue"`  // One-sided p-value that the candidate is worse
+	Verdict   string  `json:"verdict"` // "pass", "regressed", "improved" or "skipped"
+}
+
+// RegressionReport compares a batch against a baseline batch.
+type RegressionReport struct {
+	BaselineID  string               `json:"baselineId"`
+	CandidateID string               `json:"candidateId"`
+	Tolerances  RegressionTolerances `json:"tolerances"`
+	Passed      bool                 `json:"passed"`
+	Checks      []RegressionCheck    `json:"checks"`
+	Warnings    []string             `json:"warnings,omitempty"` // Configuration differences that weaken the comparison
+	Report      string               `json:"report"`             // Human-readable diff
 }
 
 // ExportFormat represents the format for data export
@@ -199,12 +593,32 @@ type TestUIState struct {
 	InputStepCount        int               `json:"inputStepCount"`
 }
 
+// HistoryRetentionPolicy controls which completed batches are kept
+// in the on-disk history. Zero values disable the corresponding limit;
+// a config without a policy keeps the last defaultMaxStoredBatches.
+type HistoryRetentionPolicy struct {
+	MaxBatches int  `json:"maxBatches"` // Keep at most this many batches (0 = unlimited)
+	MaxAgeDays int  `json:"maxAgeDays"` // Drop batches older than this (0 = never)
+	KeepTagged bool `json:"keepTagged"` // Tagged batches are never pruned
+}
+
 // AppConfig is the persisted configuration for the desktop app.
 // It mirrors the key configuration options used on the frontend
 // so that state can be restored across restarts.
 type AppConfig struct {
-	TestState            TestUIState      `json:"testState"`
-	SavedAPIConfigs      []SavedAPIConfig `json:"savedApiConfigs"`
-	LastValidAPIEndpoint string           `json:"lastValidApiEndpoint"`
-	LastValidAPIKey      string           `json:"lastValidApiKey"`
+	TestState            TestUIState             `json:"testState"`
+	SavedAPIConfigs      []SavedAPIConfig        `json:"savedApiConfigs"`
+	LastValidAPIEndpoint string                  `json:"lastValidApiEndpoint"`
+	LastValidAPIKey      string                  `json:"lastValidApiKey"`
+	HistoryRetention     *HistoryRetentionPolicy `json:"historyRetention,omitempty"` // nil = keep the last defaultMaxStoredBatches
+	MetricsAddress       string                  `json:"metricsAddress,omitempty"`   // Prometheus /metrics listen address; empty = disabled
+	Tokenizers           []TokenizerMapping      `json:"tokenizers,omitempty"`
+}
+
+// TokenizerMapping points the models matching Model at a HuggingFace
+// tokenizer. Model is a case-insensitive path.Match pattern such as
+// "Qwen/*"; the first matching mapping wins.
+type TokenizerMapping struct {
+	Model string `json:"model"`
+	Path  string `json:"path"` // tokenizer.json or a directory containing it
 }
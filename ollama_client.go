@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const ollamaDefaultEndpoint = "http://localhost:11434"
+
+// OllamaClient talks to Ollama's native /api/chat endpoint, which streams
+// newline-delimited JSON rather than SSE.
+type OllamaClient struct {
+	apiEndpoint string
+	apiKey      string
+	httpClient  *http.Client
+}
+
+// NewOllamaClient creates a client for the Ollama server at apiEndpoint
+// (e.g. http://localhost:11434). The API key is optional and sent as a
+// Bearer token for servers behind an authenticating proxy.
+func NewOllamaClient(apiEndpoint, apiKey string, timeout int) *OllamaClient {
+	if apiEndpoint == "" {
+		apiEndpoint = ollamaDefaultEndpoint
+	}
+
+	return &OllamaClient{
+		apiEndpoint: strings.TrimRight(apiEndpoint, "/"),
+		apiKey:      apiKey,
+		httpClient: &http.Client{
+			Timeout: time.Duration(timeout) * time.Second,
+		},
+	}
+}
+
+type ollamaOptions struct {
+	NumPredict       int     `json:"num_predict,omitempty"`
+	Temperature      float32 `json:"temperature,omitempty"`
+	TopP             float32 `json:"top_p,omitempty"`
+	PresencePenalty  float32 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float32 `json:"frequency_penalty,omitempty"`
+}
+
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"` // Ollama streams unless told otherwise
+	Options  ollamaOptions `json:"options"`
+}
+
+// ollamaChatChunk is both a streamed line and the non-streaming response.
+// Token counts are only present on the final ("done") object.
+type ollamaChatChunk struct {
+	Model           string  `json:"model"`
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	DoneReason      string  `json:"done_reason"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	Error           string  `json:"error"`
+}
+
+// GenerateCompletion sends request to /api/chat.
+func (c *OllamaClient) GenerateCompletion(ctx context.Context, request OpenAIRequest, headers map[string]string, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
+	payload := ollamaChatRequest{
+		Model:    request.Model,
+		Messages: request.Messages,
+		Stream:   request.Stream,
+		Options: ollamaOptions{
+			NumPredict:       request.MaxTokens,
+			Temperature:      request.Temperature,
+			TopP:             request.TopP,
+			PresencePenalty:  request.PresencePenalty,
+			FrequencyPenalty: request.FrequencyPenalty,
+		},
+	}
+
+	resp, startTime, err := postJSON(ctx, c.httpClient, c.apiEndpoint+"/api/chat", payload, headers, func(req *http.Request) {
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if request.Stream {
+		return c.handleStreamingResponse(resp.Body, startTime, onToken, onFirstToken)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading response: %v", err)
+	}
+
+	var chunk ollamaChatChunk
+	if err := json.Unmarshal(body, &chunk); err != nil {
+		return nil, 0, fmt.Errorf("error unmarshaling response: %v", err)
+	}
+	if chunk.Error != "" {
+		return nil, 0, fmt.Errorf("API error: %s", chunk.Error)
+	}
+
+	return newTextResponse("", chunk.Model, chunk.Message.Content, chunk.DoneReason, ollamaToUsage(chunk)), time.Since(startTime), nil
+}
+
+func (c *OllamaClient) handleStreamingResponse(body io.Reader, startTime time.Time, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	collector := newStreamCollector(startTime, onToken, onFirstToken)
+
+	var model string
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var chunk ollamaChatChunk
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return nil, 0, fmt.Errorf("error unmarshaling stream chunk: %v", err)
+		}
+		if chunk.Error != "" {
+			return nil, 0, fmt.Errorf("stream error: %s", chunk.Error)
+		}
+
+		if model == "" {
+			model = chunk.Model
+		}
+		collector.add(chunk.Message.Content)
+
+		if chunk.Done {
+			return collector.response("", model, chunk.DoneReason, ollamaToUsage(chunk)), collector.firstTokenLatency(), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error reading stream response: %v", err)
+	}
+
+	return collector.response("", model, "", Usage{}), collector.firstTokenLatency(), nil
+}
+
+func ollamaToUsage(chunk ollamaChatChunk) Usage {
+	return Usage{
+		PromptTokens:     chunk.PromptEvalCount,
+		CompletionTokens: chunk.EvalCount,
+		TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+	}
+}
@@ -14,15 +14,46 @@ import (
 
 // OpenAIClient represents a client for OpenAI-compatible APIs
 type OpenAIClient struct {
-	apiEndpoint string
-	apiKey      string
-	httpClient  *http.Client
+	apiEndpoint    string
+	apiKey         string
+	httpClient     *http.Client
+	textCompletion bool // use the legacy /completions endpoint with a raw prompt
 }
 
 // OpenAIRequest represents a request to the OpenAI API
 type OpenAIRequest struct {
+	Model            string          `json:"model"`
+	Messages         []Message       `json:"messages"`
+	MaxTokens        int             `json:"max_tokens,omitempty"`
+	Temperature      float32         `json:"temperature,omitempty"`
+	TopP             float32         `json:"top_p,omitempty"`
+	PresencePenalty  float32         `json:"presence_penalty,omitempty"`
+	FrequencyPenalty float32         `json:"frequency_penalty,omitempty"`
+	Stream           bool            `json:"stream,omitempty"`
+	StreamOptions    *StreamOptions  `json:"stream_options,omitempty"`
+	Tools            []Tool          `json:"tools,omitempty"`
+	ToolChoice       interface{}     `json:"tool_choice,omitempty"` // "auto", "required", "none" or a named function
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	GuidedJSON       json.RawMessage `json:"guided_json,omitempty"` // vLLM's own way to constrain output to a JSON schema
+}
+
+// ResponseFormat constrains the output, e.g. to a JSON schema.
+type ResponseFormat struct {
+	Type       string            `json:"type"` // "json_schema" or "json_object"
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"`
+}
+
+// JSONSchemaFormat names the schema a json_schema response must follow.
+type JSONSchemaFormat struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// OpenAICompletionRequest is the legacy /completions request body, which
+// takes a raw prompt instead of chat messages.
+type OpenAICompletionRequest struct {
 	Model            string         `json:"model"`
-	Messages         []Message      `json:"messages"`
+	Prompt           string         `json:"prompt"`
 	MaxTokens        int            `json:"max_tokens,omitempty"`
 	Temperature      float32        `json:"temperature,omitempty"`
 	TopP             float32        `json:"top_p,omitempty"`
@@ -38,8 +69,101 @@ type StreamOptions struct {
 
 // Message represents a message in the conversation
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role             string     `json:"role"`
+	Content          string     `json:"content"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"` // thinking of reasoning models, never sent back
+
+	// Parts replaces Content on the wire when set, for multimodal
+	// requests; Content then holds only the text.
+	Parts []ContentPart `json:"-"`
+}
+
+// ContentPart is one element of a multimodal message content array.
+type ContentPart struct {
+	Type     string    `json:"type"` // "text" or "image_url"
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL points at an image, here always a base64 data URL.
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// MarshalJSON sends Parts as the content array when a message has any;
+// Content then only serves local token counting.
+func (m Message) MarshalJSON() ([]byte, error) {
+	type plain Message
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []ContentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+// UnmarshalJSON accepts content as a string or as a content array, whose
+// text parts are joined into Content.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+	var raw struct {
+		plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.plain)
+
+	content := bytes.TrimSpace(raw.Content)
+	if len(content) == 0 || string(content) == "null" {
+		return nil
+	}
+	if content[0] != '[' {
+		return json.Unmarshal(content, &m.Content)
+	}
+	if err := json.Unmarshal(content, &m.Parts); err != nil {
+		return err
+	}
+	var texts []string
+	for _, part := range m.Parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
+
+// Tool is a function the model may call.
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function; Parameters is its JSON schema.
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call generated by the model. Streamed deltas
+// carry Index to say which call a fragment of Arguments belongs to.
+type ToolCall struct {
+	Index    int              `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the called function and its JSON-encoded arguments.
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
 }
 
 // OpenAIResponse represents a response from the OpenAI API
@@ -50,20 +174,39 @@ type OpenAIResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage"`
+
+	// Offsets from the start of the request of the first and last streamed
+	// tool call fragments; zero when no tool call was streamed.
+	ToolCallStart time.Duration `json:"-"`
+	ToolCallEnd   time.Duration `json:"-"`
+
+	// Offsets of the first and last streamed reasoning fragments and of
+	// the first answer fragment; zero when none was streamed.
+	ReasoningStart time.Duration `json:"-"`
+	ReasoningEnd   time.Duration `json:"-"`
+	AnswerStart    time.Duration `json:"-"`
 }
 
 // Choice represents a choice in the response
 type Choice struct {
 	Index        int     `json:"index"`
 	Message      Message `json:"message"`
+	Text         string  `json:"text,omitempty"` // legacy /completions responses
 	FinishReason string  `json:"finish_reason"`
 }
 
 // Usage represents token usage information
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+// CompletionTokensDetails breaks down the completion tokens; reasoning
+// tokens are part of CompletionTokens.
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
 }
 
 // NewOpenAIClient creates a new OpenAI client
@@ -81,13 +224,36 @@ func NewOpenAIClient(apiEndpoint, apiKey string, timeout int) *OpenAIClient {
 	}
 }
 
+// NewOpenAICompletionsClient creates a client that sends requests to the
+// legacy /completions endpoint, skipping the server's chat template.
+func NewOpenAICompletionsClient(apiEndpoint, apiKey string, timeout int) *OpenAIClient {
+	client := NewOpenAIClient(apiEndpoint, apiKey, timeout)
+	client.textCompletion = true
+	return client
+}
+
 // GenerateCompletion generates a completion using the OpenAI API
 // onToken is called when a new token is received (stream mode only)
 // onFirstToken is called when the first token is received with the TTFT duration (stream mode only)
-func (c *OpenAIClient) GenerateCompletion(ctx context.Context, request OpenAIRequest, headers map[string]string, onToken func(string), onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
+func (c *OpenAIClient) GenerateCompletion(ctx context.Context, request OpenAIRequest, headers map[string]string, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
 	requestURL := fmt.Sprintf("%s/chat/completions", c.apiEndpoint)
+	var payload interface{} = request
+	if c.textCompletion {
+		requestURL = fmt.Sprintf("%s/completions", c.apiEndpoint)
+		payload = OpenAICompletionRequest{
+			Model:            request.Model,
+			Prompt:           flattenMessages(request.Messages),
+			MaxTokens:        request.MaxTokens,
+			Temperature:      request.Temperature,
+			TopP:             request.TopP,
+			PresencePenalty:  request.PresencePenalty,
+			FrequencyPenalty: request.FrequencyPenalty,
+			Stream:           request.Stream,
+			StreamOptions:    request.StreamOptions,
+		}
+	}
 
-	jsonData, err := json.Marshal(request)
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, 0, fmt.Errorf("error marshaling request: %v", err)
 	}
@@ -132,18 +298,27 @@ func (c *OpenAIClient) GenerateCompletion(ctx context.Context, request OpenAIReq
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
 		return nil, 0, fmt.Errorf("error unmarshaling response: %v", err)
 	}
+	for i := range openAIResp.Choices {
+		if openAIResp.Choices[i].Message.Content == "" && openAIResp.Choices[i].Text != "" {
+			openAIResp.Choices[i].Message = Message{Role: "assistant", Content: openAIResp.Choices[i].Text}
+		}
+	}
 
 	return &openAIResp, time.Since(startTime), nil
 }
 
 type streamChoiceDelta struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role             string     `json:"role"`
+	Content          string     `json:"content"`
+	ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+	ReasoningContent string     `json:"reasoning_content,omitempty"` // DeepSeek, vLLM and SGLang reasoning parsers
+	Reasoning        string     `json:"reasoning,omitempty"`         // the same under the name newer servers use
 }
 
 type streamChoice struct {
 	Index        int               `json:"index"`
 	Delta        streamChoiceDelta `json:"delta"`
+	Text         string            `json:"text"` // legacy /completions chunks
 	FinishReason string            `json:"finish_reason"`
 }
 
@@ -156,7 +331,7 @@ type streamResponse struct {
 	Usage   *Usage         `json:"usage,omitempty"`
 }
 
-func (c *OpenAIClient) handleStreamingResponse(resp *http.Response, startTime time.Time, onToken func(string), onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
+func (c *OpenAIClient) handleStreamingResponse(resp *http.Response, startTime time.Time, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
 	reader := bufio.NewReader(resp.Body)
 	defer resp.Body.Close()
 
@@ -168,6 +343,10 @@ func (c *OpenAIClient) handleStreamingResponse(resp *http.Response, startTime ti
 	var responseID string
 	var model string
 	var created int64
+	var toolCalls []ToolCall
+	var toolCallStart, toolCallEnd time.Duration
+	var reasoningBuilder strings.Builder
+	var reasoningStart, reasoningEnd, answerStart time.Duration
 
 	for {
 		line, err := reader.ReadString('\n')
@@ -207,18 +386,77 @@ func (c *OpenAIClient) handleStreamingResponse(resp *http.Response, startTime ti
 		}
 
 		if len(chunk.Choices) > 0 {
-			delta := chunk.Choices[0].Delta
-			if delta.Content != "" {
-				builder.WriteString(delta.Content)
-				
-				// Call onToken callback
-				if onToken != nil {
-					onToken(delta.Content)
+			// Everything generated in this event goes to onToken at once, so
+			// every event is one chunk arrival.
+			var eventText strings.Builder
+
+			// Chat chunks carry delta.content; text completion chunks carry text.
+			content := chunk.Choices[0].Delta.Content
+			if content == "" {
+				content = chunk.Choices[0].Text
+			}
+			if content != "" {
+				builder.WriteString(content)
+				eventText.WriteString(content)
+			}
+
+			if content != "" && answerStart == 0 {
+				answerStart = time.Since(startTime)
+			}
+
+			// Reasoning models stream their thinking apart from the answer.
+			// It is generated output, so it counts towards TTFT too.
+			reasoning := chunk.Choices[0].Delta.ReasoningContent
+			if reasoning == "" {
+				reasoning = chunk.Choices[0].Delta.Reasoning
+			}
+			if reasoning != "" {
+				reasoningBuilder.WriteString(reasoning)
+				reasoningEnd = time.Since(startTime)
+				if reasoningStart == 0 {
+					reasoningStart = reasoningEnd
+				}
+				eventText.WriteString(reasoning)
+			}
+
+			// Tool calls stream as fragments of their arguments, keyed by
+			// index. They are generated tokens too, so they count towards
+			// TTFT and the live token rate.
+			generated := content != "" || reasoning != ""
+			for _, delta := range chunk.Choices[0].Delta.ToolCalls {
+				// Calls are numbered in order, so a fragment either continues
+				// a known call or starts the next one.
+				if delta.Index < 0 || delta.Index > len(toolCalls) {
+					return nil, 0, fmt.Errorf("%w: index %d after %d calls", errToolCallIndex, delta.Index, len(toolCalls))
+				}
+				if delta.Index == len(toolCalls) {
+					toolCalls = append(toolCalls, ToolCall{Index: len(toolCalls), Type: "function"})
+				}
+				call := &toolCalls[delta.Index]
+				if delta.ID != "" {
+					call.ID = delta.ID
 				}
+				if delta.Function.Name != "" {
+					call.Function.Name = delta.Function.Name
+				}
+				call.Function.Arguments += delta.Function.Arguments
+
+				if delta.Function.Name == "" && delta.Function.Arguments == "" {
+					continue
+				}
+				generated = true
+				toolCallEnd = time.Since(startTime)
+				if toolCallStart == 0 {
+					toolCallStart = toolCallEnd
+				}
+				eventText.WriteString(delta.Function.Arguments)
+			}
+			if onToken != nil && eventText.Len() > 0 {
+				onToken(eventText.String(), chunk.Usage)
 			}
 			
 			// Calculate TTFT on first content received
-			if ttft == 0 && delta.Content != "" {
+			if ttft == 0 && generated {
 				ttft = time.Since(startTime)
 				if onFirstToken != nil {
 					onFirstToken(ttft)
@@ -243,8 +481,10 @@ func (c *OpenAIClient) handleStreamingResponse(resp *http.Response, startTime ti
 	choice := Choice{
 		Index: 0,
 		Message: Message{
-			Role:    "assistant",
-			Content: builder.String(),
+			Role:             "assistant",
+			Content:          builder.String(),
+			ToolCalls:        toolCalls,
+			ReasoningContent: reasoningBuilder.String(),
 		},
 		FinishReason: finishReason,
 	}
@@ -255,6 +495,13 @@ func (c *OpenAIClient) handleStreamingResponse(resp *http.Response, startTime ti
 		Created: created,
 		Model:   model,
 		Choices: []Choice{choice},
+
+		ToolCallStart: toolCallStart,
+		ToolCallEnd:   toolCallEnd,
+
+		ReasoningStart: reasoningStart,
+		ReasoningEnd:   reasoningEnd,
+		AnswerStart:    answerStart,
 	}
 
 	if usageAvailable {
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Modes of PrefixConfiguration.
+const (
+	prefixModeShared = "shared"
+	prefixModeUnique = "unique"
+)
+
+// Values of TestResult.PrefixCache.
+const (
+	prefixCacheWarm = "warm"
+	prefixCacheCold = "cold"
+)
+
+// validatePrefixConfig checks a prefix configuration against the prompts it
+// is applied to. The shared prefix must leave room for a per-request part.
+func validatePrefixConfig(pc PrefixConfiguration, promptType string, promptLength int) error {
+	switch pc.Mode {
+	case "":
+		return nil
+	case prefixModeShared:
+		if pc.Length <= 0 {
+			return fmt.Errorf("invalid prefix configuration: length must be positive, got %d", pc.Length)
+		}
+		if pc.Length >= promptLength {
+			return fmt.Errorf("invalid prefix configuration: prefix length (%d) must be below the prompt length (%d)", pc.Length, promptLength)
+		}
+		if pc.HitRatio < 0 || pc.HitRatio > 1 {
+			return fmt.Errorf("invalid prefix configuration: hitRatio must be between 0 and 1, got %g", pc.HitRatio)
+		}
+	case prefixModeUnique:
+	default:
+		return fmt.Errorf("unsupported prefix mode: %s", pc.Mode)
+	}
+
+	if !isSyntheticPromptType(promptType) {
+		return fmt.Errorf("prefix mode %s needs a synthetic prompt type, got %s", pc.Mode, promptType)
+	}
+	return nil
+}
+
+// prefixWorkload builds the opening of every synthetic prompt of a batch.
+// Prompts of the same test number get the same prefix in every batch of a
+// matrix run.
+type prefixWorkload struct {
+	config  PrefixConfiguration
+	seed    int64
+	nonce   string // Keeps unique markers from repeating across runs with different seeds
+	shared  string
+	counter *tokenCounter
+}
+
+// newPrefixWorkload returns the workload for config, or nil when prompts
+// are plain random text. Prefixes and markers derive from config.Seed. The
+// shared prefix is taken from prompts when given, so every target of a
+// matrix run uses the same one.
+func newPrefixWorkload(config TestConfiguration, prompts *promptCache, counter *tokenCounter) *prefixWorkload {
+	pc := config.PrefixConfig
+	if pc.Mode == "" {
+		return nil
+	}
+
+	w := &prefixWorkload{
+		config:  pc,
+		seed:    config.Seed,
+		nonce:   fmt.Sprintf("%08x", uint32(deriveSeed(config.Seed, "marker"))),
+		counter: counter,
+	}
+	if pc.Mode == prefixModeShared {
+		generate := func() string {
+			return newPromptGenerator(counter, deriveSeed(w.seed, "prefix")).GenerateExactPrompt(pc.Length, "complex")
+		}
+		if prompts != nil {
+			w.shared = prompts.prefix(pc.Length, generate)
+		} else {
+			w.shared = generate()
+		}
+	}
+	return w
+}
+
+// warm reports whether request testNumber reuses the shared prefix.
+// Requests are spread evenly so any run of them hits close to HitRatio.
+// The warm-up request (test number 0) always uses it, which primes the
+// server's cache before measured requests start.
+func (w *prefixWorkload) warm(testNumber int) bool {
+	if testNumber <= 0 {
+		return true
+	}
+	ratio := w.config.HitRatio
+	return math.Floor(float64(testNumber)*ratio) > math.Floor(float64(testNumber-1)*ratio)
+}
+
+// marker is the opening no other request shares.
+func (w *prefixWorkload) marker(testNumber int) string {
+	return fmt.Sprintf("Request %s-%d.", w.nonce, testNumber)
+}
+
+// messages returns the messages of request testNumber, promptLength tokens
+// in total, and its TestResult.PrefixCache value. body generates the
+// per-request remainder of the given length.
+func (w *prefixWorkload) messages(testNumber, promptLength int, body func(length int) string) ([]Message, string) {
+	if w.config.Mode == prefixModeUnique {
+		marker := w.marker(testNumber)
+		rest := body(max(promptLength-w.counter.count(marker), 1))
+		return []Message{{Role: "user", Content: marker + " " + rest}}, ""
+	}
+
+	prefix, cache := w.shared, prefixCacheWarm
+	if !w.warm(testNumber) {
+		// A cold prefix opens with a unique marker, so not even its first
+		// tokens match anything cached.
+		marker := w.marker(testNumber)
+		length := max(w.config.Length-w.counter.count(marker), 1)
+		generator := newPromptGenerator(w.counter, deriveSeed(w.seed, "prefix", testNumber))
+		prefix = marker + " " + generator.GenerateExactPrompt(length, "complex")
+		cache = prefixCacheCold
+	}
+
+	rest := body(max(promptLength-w.config.Length, 1))
+	if w.config.SystemPrompt {
+		return []Message{{Role: "system", Content: prefix}, {Role: "user", Content: rest}}, cache
+	}
+	return []Message{{Role: "user", Content: prefix + "\n\n" + rest}}, cache
+}
+
+// summarizePrefixCache compares warm and cold TTFT over the successful
+// results, or returns nil when no result used the shared prefix mode.
+func summarizePrefixCache(results []TestResult) *PrefixCacheSummary {
+	var warm, cold []float64
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		switch result.PrefixCache {
+		case prefixCacheWarm:
+			warm = append(warm, result.RequestLatency)
+		case prefixCacheCold:
+			cold = append(cold, result.RequestLatency)
+		}
+	}
+	if len(warm) == 0 && len(cold) == 0 {
+		return nil
+	}
+
+	summary := &PrefixCacheSummary{
+		WarmRequests:         len(warm),
+		ColdRequests:         len(cold),
+		AverageWarmTTFT:      mean(warm),
+		AverageColdTTFT:      mean(cold),
+		WarmTTFTDistribution: computeDistribution(warm),
+		ColdTTFTDistribution: computeDistribution(cold),
+	}
+	if summary.WarmTTFTDistribution.P50 > 0 && len(cold) > 0 {
+		summary.TTFTSpeedup = summary.ColdTTFTDistribution.P50 / summary.WarmTTFTDistribution.P50
+	}
+	return summary
+}
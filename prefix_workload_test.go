@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrefixWorkloadSpreadsHits(t *testing.T) {
+	cases := []struct {
+		ratio float64
+		want  int
+	}{{0, 0}, {0.25, 2}, {0.5, 4}, {1, 8}}
+	for _, tc := range cases {
+		w := &prefixWorkload{config: PrefixConfiguration{Mode: prefixModeShared, HitRatio: tc.ratio}}
+		if !w.warm(0) {
+			t.Errorf("ratio %g: the warm-up request must prime the shared prefix", tc.ratio)
+		}
+		warm := 0
+		for n := 1; n <= 8; n++ {
+			if w.warm(n) {
+				warm++
+			}
+		}
+		if warm != tc.want {
+			t.Errorf("ratio %g: expected %d warm requests out of 8, got %d", tc.ratio, tc.want, warm)
+		}
+	}
+}
+
+func TestValidatePrefixConfig(t *testing.T) {
+	cases := []struct {
+		name    string
+		pc      PrefixConfiguration
+		prompt  string
+		wantErr string
+	}{
+		{"off", PrefixConfiguration{}, "custom", ""},
+		{"unique", PrefixConfiguration{Mode: "unique"}, "fixed", ""},
+		{"shared", PrefixConfiguration{Mode: "shared", Length: 100, HitRatio: 0.5}, "fixed", ""},
+		{"unknown mode", PrefixConfiguration{Mode: "nope"}, "fixed", "unsupported prefix mode"},
+		{"no length", PrefixConfiguration{Mode: "shared", HitRatio: 1}, "fixed", "must be positive"},
+		{"too long", PrefixConfiguration{Mode: "shared", Length: 512, HitRatio: 1}, "fixed", "below the prompt length"},
+		{"bad ratio", PrefixConfiguration{Mode: "shared", Length: 100, HitRatio: 1.5}, "fixed", "hitRatio"},
+		{"custom prompt", PrefixConfiguration{Mode: "unique"}, "custom", "synthetic prompt type"},
+	}
+	for _, tc := range cases {
+		err := validatePrefixConfig(tc.pc, tc.prompt, 512)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
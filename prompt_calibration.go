@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// maxCalibrationProbes bounds the probe requests spent on one prompt
+// length. With the model's own tokenizer the first probe already hits the
+// target; a mismatched tokenizer needs a few corrections.
+const maxCalibrationProbes = 4
+
+// isSyntheticPromptType reports whether prompts of promptType are generated
+// to a token length rather than supplied by the user.
+func isSyntheticPromptType(promptType string) bool {
+	return promptType != "custom" && promptType != "dataset"
+}
+
+// probeMessages builds a probe prompt of local tokens laid out like the
+// requests of the run: through prefix when there is one, so a shared prefix
+// sent as a system message carries its template overhead too. A custom
+// prompt is sent as it is.
+func probeMessages(config TestConfiguration, prefix *prefixWorkload, local int, counter *tokenCounter) []Message {
+	if config.PromptType == "custom" && config.Prompt != "" {
+		return []Message{{Role: "user", Content: config.Prompt}}
+	}
+	body := func(length int) string {
+		generator := newPromptGenerator(counter, deriveSeed(config.Seed, "calibration", length))
+		return generator.GenerateExactPrompt(length, config.PromptType)
+	}
+	if prefix != nil {
+		messages, _ := prefix.messages(0, local, body)
+		return messages
+	}
+	return []Message{{Role: "user", Content: body(local)}}
+}
+
+// probePrompt sends messages as a one-token request and returns their local
+// token count and the prompt tokens the server reports for them.
+func probePrompt(ctx context.Context, client LLMBackend, config TestConfiguration, messages []Message, counter *tokenCounter) (int, int, error) {
+	request := OpenAIRequest{
+		Model:       config.Model,
+		Messages:    messages,
+		MaxTokens:   1,
+		Temperature: config.Temperature,
+		TopP:        config.TopP,
+	}
+	response, _, err := client.GenerateCompletion(ctx, request, config.Headers, nil, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("prompt probe failed: %w", err)
+	}
+	if response.Usage.PromptTokens <= 0 {
+		return 0, 0, fmt.Errorf("prompt probes need the server to report prompt tokens")
+	}
+	local := 0
+	for _, message := range messages {
+		local += counter.count(message.Content)
+	}
+	return local, response.Usage.PromptTokens, nil
+}
+
+// calibratePrompts sends one-token probe requests to learn, for every
+// prompt length in lengths, the local token count whose prompts the server
+// reports as exactly that length. Probes use the message layout of the
+// run (see probeMessages) and are not part of the results. It returns the
+// local length to generate each requested length at.
+func (s *SpeedTestService) calibratePrompts(ctx context.Context, client LLMBackend, config TestConfiguration, prefix *prefixWorkload, lengths []int, counter *tokenCounter) (map[int]int, *PromptCalibration, error) {
+	if !isSyntheticPromptType(config.PromptType) {
+		return nil, nil, fmt.Errorf("exactPromptLength needs a synthetic prompt type, got %s", config.PromptType)
+	}
+
+	unique := make(map[int]bool)
+	for _, length := range lengths {
+		unique[length] = true
+	}
+	targets := make([]int, 0, len(unique))
+	for length := range unique {
+		targets = append(targets, length)
+	}
+	sort.Ints(targets)
+
+	probe := func(local int) (int, int, error) {
+		return probePrompt(ctx, client, config, probeMessages(config, prefix, local, counter), counter)
+	}
+
+	localLengths := make(map[int]int, len(targets))
+	calibration := &PromptCalibration{}
+	overhead := 0
+	for i, target := range targets {
+		point := PromptLengthCalibration{TargetTokens: target}
+		local := target - overhead
+		bestDiff := -1
+		for point.Probes < maxCalibrationProbes {
+			if local < 1 {
+				return nil, nil, fmt.Errorf("chat template overhead of %d tokens leaves no room for a %d-token prompt", overhead, target)
+			}
+
+			point.Probes++
+			actual, server, err := probe(local)
+			if err != nil {
+				return nil, nil, err
+			}
+			if i == 0 && point.Probes == 1 {
+				overhead = server - actual
+				calibration.TemplateOverhead = overhead
+			}
+
+			diff := server - target
+			if diff < 0 {
+				diff = -diff
+			}
+			if bestDiff < 0 || diff < bestDiff {
+				bestDiff = diff
+				point.LocalTokens, point.ServerTokens = local, server
+			}
+			if server == target {
+				break
+			}
+			local += target - server
+		}
+		if point.ServerTokens != target {
+			log.Printf("prompt calibration: closest prompt to %d tokens has %d on the server", target, point.ServerTokens)
+		}
+
+		localLengths[target] = point.LocalTokens
+		calibration.Lengths = append(calibration.Lengths, point)
+	}
+	return localLengths, calibration, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wordTokenizerJSON counts every whitespace-separated word as one token,
+// so exact lengths are always reachable.
+const wordTokenizerJSON = `{
+  "pre_tokenizer": {"type": "WhitespaceSplit"},
+  "model": {"type": "BPE", "vocab": {"<unk>": 0}, "merges": [], "unk_token": "<unk>", "fuse_unk": true}
+}`
+
+func TestGenerateExactPromptHitsTarget(t *testing.T) {
+	tok, err := parseHFTokenizer([]byte(wordTokenizerJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pg := newPromptGenerator(&tokenCounter{hf: tok}, 1)
+
+	for _, promptType := range []string{"fixed", "simple", "complex"} {
+		for _, target := range []int{20, 300} {
+			prompt := pg.GenerateExactPrompt(target, promptType)
+			if got := pg.countTokens(prompt); got != target {
+				t.Errorf("%s/%d: got %d tokens", promptType, target, got)
+			}
+		}
+	}
+
+	// Fixed prompts keep their closing instruction.
+	if prompt := pg.GenerateExactPrompt(40, "fixed"); !strings.HasSuffix(prompt, "explanation.") {
+		t.Errorf("fixed prompt lost its instruction: %q", prompt)
+	}
+}
+
+func TestRunSpeedTestCalibratesPromptLength(t *testing.T) {
+	dir := writeTokenizer(t, wordTokenizerJSON)
+	server := httptest.NewServer(NewMockServer(MockServerConfig{
+		TTFT: time.Millisecond, TokensPerSecond: 2000, ReportUsage: true, Seed: 1,
+		Tokenizer: dir, PromptOverhead: 7,
+	}))
+	defer server.Close()
+
+	config := mockTestConfiguration(server.URL + "/v1")
+	config.PromptType = "fixed"
+	config.Tokenizer = dir
+	config.ExactPromptLength = true
+	config.TestMode = "input_step"
+	config.StepConfig = StepConfiguration{Start: 32, End: 64, Step: 32}
+
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), config, "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	calibration := batch.PromptCalibration
+	if calibration == nil || calibration.TemplateOverhead != 7 || len(calibration.Lengths) != 2 {
+		t.Fatalf("unexpected calibration: %+v", calibration)
+	}
+	if first := calibration.Lengths[0]; first.LocalTokens != 25 || first.Probes != 2 {
+		t.Fatalf("expected the first length to need one correction, got %+v", first)
+	}
+	if second := calibration.Lengths[1]; second.LocalTokens != 57 || second.Probes != 1 {
+		t.Fatalf("expected the learnt overhead to hit the second length at once, got %+v", second)
+	}
+
+	for _, result := range batch.Results {
+		if !result.Success || result.PromptTokens != result.Configuration.PromptLength {
+			t.Fatalf("result %d: server saw %d prompt tokens, want %d (%s)",
+				result.TestNumber, result.PromptTokens, result.Configuration.PromptLength, result.Error)
+		}
+	}
+}
+
+func TestCalibrationUsesTheRunMessageLayout(t *testing.T) {
+	dir := writeTokenizer(t, wordTokenizerJSON)
+	server := httptest.NewServer(NewMockServer(MockServerConfig{
+		TTFT: time.Millisecond, TokensPerSecond: 2000, ReportUsage: true, Seed: 1,
+		Tokenizer: dir, PromptOverhead: 2, MessageOverhead: 3,
+	}))
+	defer server.Close()
+
+	// The shared prefix goes out as a system message, so every request
+	// carries two role headers.
+	config := mockTestConfiguration(server.URL + "/v1")
+	config.PromptType = "fixed"
+	config.PromptLength = 48
+	config.Tokenizer = dir
+	config.ExactPromptLength = true
+	config.PrefixConfig = PrefixConfiguration{Mode: prefixModeShared, Length: 16, HitRatio: 1, SystemPrompt: true}
+
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), config, "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if calibration := batch.PromptCalibration; calibration == nil || calibration.TemplateOverhead != 8 {
+		t.Fatalf("expected the overhead of two messages (8 tokens), got %+v", calibration)
+	}
+	for _, result := range batch.Results {
+		if !result.Success || result.PromptTokens != 48 {
+			t.Fatalf("result %d: server saw %d prompt tokens, want 48 (%s)", result.TestNumber, result.PromptTokens, result.Error)
+		}
+	}
+}
+
+func TestExactPromptLengthNeedsSyntheticPrompts(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{ReportUsage: true}))
+	defer server.Close()
+
+	config := mockTestConfiguration(server.URL + "/v1")
+	config.ExactPromptLength = true
+	_, err := NewSpeedTestService().RunSpeedTest(context.Background(), config, "")
+	if err == nil || !strings.Contains(err.Error(), "synthetic prompt type") {
+		t.Fatalf("expected a prompt type error, got %v", err)
+	}
+}
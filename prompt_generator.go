@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"log"
 	"math/rand"
 	"strings"
@@ -12,20 +14,44 @@ import (
 
 // PromptGenerator generates test prompts of specified token lengths
 type PromptGenerator struct {
-	wordPool  []string
-	rng       *rand.Rand
-	tokenizer *tiktoken.Tiktoken
+	wordPool []string
+	rng      *rand.Rand
+	counter  *tokenCounter
 }
 
 const defaultTokenizerEncoding = "cl100k_base"
 
 // NewPromptGenerator creates a new prompt generator
 func NewPromptGenerator(model string) *PromptGenerator {
+	return newPromptGenerator(newTokenCounter(model), 0)
+}
+
+// newPromptGenerator creates a prompt generator that measures prompts with
+// counter. Generators with the same non-zero seed produce the same prompts;
+// a zero seed is taken from the clock.
+func newPromptGenerator(counter *tokenCounter, seed int64) *PromptGenerator {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
 	return &PromptGenerator{
-		wordPool:  generateWordPool(),
-		rng:       rand.New(rand.NewSource(time.Now().UnixNano())),
-		tokenizer: initTokenizer(model),
+		wordPool: generateWordPool(),
+		rng:      rand.New(rand.NewSource(seed)),
+		counter:  counter,
+	}
+}
+
+// deriveSeed returns the seed of one random stream of a batch, named by
+// stream and indices (e.g. the prompt of one test number). Each stream
+// depends only on the batch seed, not on the order in which concurrent
+// requests happen to draw from it.
+func deriveSeed(seed int64, stream string, indices ...int) int64 {
+	h := fnv.New64a()
+	binary.Write(h, binary.LittleEndian, seed)
+	h.Write([]byte(stream))
+	for _, index := range indices {
+		binary.Write(h, binary.LittleEndian, int64(index))
 	}
+	return int64(h.Sum64())
 }
 
 // GeneratePrompt generates a prompt with approximately the specified number of tokens
@@ -42,6 +68,66 @@ func (pg *PromptGenerator) GeneratePrompt(targetTokens int, promptType string) s
 	}
 }
 
+// GenerateExactPrompt generates a prompt of exactly targetTokens tokens
+// when the tokenizer allows it. The prompt is generated as usual, trimmed
+// word by word, then padded with short words. Fixed prompts end with their
+// instruction, so they are trimmed and padded at the front instead.
+func (pg *PromptGenerator) GenerateExactPrompt(targetTokens int, promptType string) string {
+	if targetTokens <= 0 {
+		targetTokens = 1
+	}
+	prompt := pg.GeneratePrompt(targetTokens, promptType)
+	front := promptType != "simple" && promptType != "complex"
+	return pg.fitToTokens(prompt, targetTokens, front)
+}
+
+// fitToTokens trims prompt to the most words that stay within
+// targetTokens, then pads it with single words until it reaches the target.
+// With front set, words are removed from and added to the start. The
+// result can stay below the target when no padding word fits.
+func (pg *PromptGenerator) fitToTokens(prompt string, targetTokens int, front bool) string {
+	words := strings.Fields(prompt)
+	keep := func(n int) []string {
+		if front {
+			return words[len(words)-n:]
+		}
+		return words[:n]
+	}
+
+	// Token counts grow with the number of words kept, so the longest
+	// prefix (or suffix) within the target can be found by bisection.
+	lo, hi := 0, len(words)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if pg.countTokens(strings.Join(keep(mid), " ")) <= targetTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	kept := append([]string(nil), keep(lo)...)
+
+	pads := []string{"the", "a", "of", "and", "to", "in"}
+	current := pg.countTokens(strings.Join(kept, " "))
+	for current < targetTokens {
+		padded := false
+		for _, pad := range pads {
+			candidate := append([]string{pad}, kept...)
+			if !front {
+				candidate = append(append([]string(nil), kept...), pad)
+			}
+			if count := pg.countTokens(strings.Join(candidate, " ")); count > current && count <= targetTokens {
+				kept, current, padded = candidate, count, true
+				break
+			}
+		}
+		if !padded {
+			break
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
 // generateFixedLengthPrompt creates a prompt with approximately targetTokens tokens
 func (pg *PromptGenerator) generateFixedLengthPrompt(targetTokens int) string {
 	if targetTokens <= 0 {
@@ -201,14 +287,50 @@ func initTokenizer(model string) *tiktoken.Tiktoken {
 	return enc
 }
 
-func (pg *PromptGenerator) countTokens(text string) int {
-	if pg != nil && pg.tokenizer != nil {
-		return len(pg.tokenizer.Encode(text, nil, nil))
+// tokenCounter counts tokens with a HuggingFace tokenizer or a model's
+// tiktoken encoding, falling back to EstimateActualTokens when neither
+// could be loaded. Encoding does not modify the tokenizer, so one counter
+// can serve concurrent requests.
+type tokenCounter struct {
+	tokenizer *tiktoken.Tiktoken
+	hf        *hfTokenizer
+}
+
+func newTokenCounter(model string) *tokenCounter {
+	return &tokenCounter{tokenizer: initTokenizer(model)}
+}
+
+// loadTokenCounter returns a counter using the HuggingFace tokenizer at
+// tokenizerPath, or the model's tiktoken encoding when the path is empty.
+func loadTokenCounter(model, tokenizerPath string) (*tokenCounter, error) {
+	if tokenizerPath == "" {
+		return newTokenCounter(model), nil
+	}
+	tok, err := loadHFTokenizer(tokenizerPath)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenCounter{hf: tok}, nil
+}
+
+func (c *tokenCounter) count(text string) int {
+	if c != nil && c.hf != nil {
+		return c.hf.count(text)
+	}
+	if c != nil && c.tokenizer != nil {
+		return len(c.tokenizer.Encode(text, nil, nil))
 	}
 
 	return EstimateActualTokens(text)
 }
 
+func (pg *PromptGenerator) countTokens(text string) int {
+	if pg == nil {
+		return EstimateActualTokens(text)
+	}
+	return pg.counter.count(text)
+}
+
 func (pg *PromptGenerator) ensureMinimumTokens(prompt string, targetTokens int) string {
 	trimmed := strings.TrimSpace(prompt)
 	if targetTokens <= 0 {
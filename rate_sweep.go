@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// validateRateSweepConfig checks the settings of a rate_step test.
+func validateRateSweepConfig(rc RateConfiguration, slo SLOConfiguration) error {
+	if err := validateRateConfig(rc); err != nil {
+		return err
+	}
+	if rc.RateStart <= 0 || rc.RateStep <= 0 || rc.RateEnd < rc.RateStart {
+		return fmt.Errorf("invalid rate sweep configuration: rateStart=%g, rateEnd=%g, rateStep=%g",
+			rc.RateStart, rc.RateEnd, rc.RateStep)
+	}
+	if slo.MaxP95TTFT < 0 || slo.MaxP95Latency < 0 || slo.MaxP95TPOT < 0 {
+		return fmt.Errorf("invalid SLO: latency thresholds cannot be negative")
+	}
+	if slo.MaxErrorRate != nil && (*slo.MaxErrorRate < 0 || *slo.MaxErrorRate > 1) {
+		return fmt.Errorf("invalid SLO: maxErrorRate must be between 0 and 1, got %g", *slo.MaxErrorRate)
+	}
+	if slo.MaxP95TTFT == 0 && slo.MaxP95Latency == 0 && slo.MaxP95TPOT == 0 && slo.MaxErrorRate == nil {
+		return fmt.Errorf("invalid SLO: a rate sweep needs at least one threshold (maxP95Ttft, maxP95Latency, maxP95Tpot or maxErrorRate)")
+	}
+	return nil
+}
+
+// buildRateSweep returns the arrival rates of a rate sweep in ascending order.
+// Rates are computed from the step index so float error does not accumulate.
+func buildRateSweep(rc RateConfiguration) []float64 {
+	if rc.RateStart <= 0 || rc.RateStep <= 0 || rc.RateEnd < rc.RateStart {
+		return nil
+	}
+
+	const epsilon = 1e-9
+	var rates []float64
+	for i := 0; ; i++ {
+		rate := rc.RateStart + float64(i)*rc.RateStep
+		if rate > rc.RateEnd+epsilon {
+			break
+		}
+		rates = append(rates, rate)
+	}
+	return rates
+}
+
+// summarizeRateStep reduces the results of one sweep step and checks them
+// against slo. elapsed is the wall time from the first arrival until the
+// last request finished.
+func summarizeRateStep(rate float64, summary TestSummary, results []TestResult, elapsed time.Duration, slo SLOConfiguration) RateStepSummary {
+	step := RateStepSummary{
+		RequestRate:        rate,
+		TotalRequests:      summary.TotalTests,
+		SuccessfulRequests: summary.SuccessfulTests,
+		ErrorRate:          summary.ErrorRate,
+		P95TTFT:            summary.PrefillLatencyDistribution.P95,
+		P95Latency:         summary.LatencyDistribution.P95,
+		P95TPOT:            summary.TimePerOutputTokenDistribution.P95,
+		AverageQueueDelay:  summary.AverageQueueDelay,
+	}
+
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		var completionTokens int
+		for _, result := range results {
+			if result.Success {
+				completionTokens += result.CompletionTokens
+			}
+		}
+		step.AchievedRate = float64(summary.SuccessfulTests) / seconds
+		step.TotalOutputTokensPerSecond = float64(completionTokens) / seconds
+	}
+
+	step.Violations = checkSLO(step, slo)
+	step.MeetsSLO = len(step.Violations) == 0
+	return step
+}
+
+// checkSLO lists every objective the step missed. A step without any
+// successful request always fails, since its latency figures are empty.
+func checkSLO(step RateStepSummary, slo SLOConfiguration) []string {
+	if step.SuccessfulRequests == 0 {
+		return []string{"no successful requests"}
+	}
+
+	var violations []string
+	if slo.MaxP95TTFT > 0 && step.P95TTFT > slo.MaxP95TTFT {
+		violations = append(violations, fmt.Sprintf("p95 TTFT %.1fms > %.1fms", step.P95TTFT, slo.MaxP95TTFT))
+	}
+	if slo.MaxP95Latency > 0 && step.P95Latency > slo.MaxP95Latency {
+		violations = append(violations, fmt.Sprintf("p95 latency %.1fms > %.1fms", step.P95Latency, slo.MaxP95Latency))
+	}
+	if slo.MaxP95TPOT > 0 && step.P95TPOT > slo.MaxP95TPOT {
+		violations = append(violations, fmt.Sprintf("p95 TPOT %.2fms > %.2fms", step.P95TPOT, slo.MaxP95TPOT))
+	}
+	if slo.MaxErrorRate != nil && step.ErrorRate > *slo.MaxErrorRate {
+		violations = append(violations, fmt.Sprintf("error rate %.2f%% > %.2f%%", step.ErrorRate*100, *slo.MaxErrorRate*100))
+	}
+	return violations
+}
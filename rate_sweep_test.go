@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildRateSweep(t *testing.T) {
+	rates := buildRateSweep(RateConfiguration{RateStart: 0.5, RateEnd: 1.5, RateStep: 0.1})
+	if len(rates) != 11 {
+		t.Fatalf("expected 11 rates from 0.5 to 1.5, got %d: %v", len(rates), rates)
+	}
+	if rates[0] != 0.5 || rates[len(rates)-1] < 1.499 {
+		t.Fatalf("unexpected sweep bounds: %v", rates)
+	}
+	if got := buildRateSweep(RateConfiguration{RateStart: 2, RateEnd: 1, RateStep: 1}); got != nil {
+		t.Fatalf("expected no rates when end < start, got %v", got)
+	}
+}
+
+func TestSummarizeRateStepChecksSLO(t *testing.T) {
+	maxErrorRate := 0.01
+	slo := SLOConfiguration{MaxP95TTFT: 500, MaxErrorRate: &maxErrorRate}
+	results := []TestResult{
+		{Success: true, CompletionTokens: 100},
+		{Success: true, CompletionTokens: 100},
+	}
+
+	summary := TestSummary{TotalTests: 2, SuccessfulTests: 2}
+	summary.PrefillLatencyDistribution.P95 = 320
+	step := summarizeRateStep(4, summary, results, 2*time.Second, slo)
+	if !step.MeetsSLO {
+		t.Fatalf("expected step to meet the SLO, got violations %v", step.Violations)
+	}
+	if step.AchievedRate != 1 || step.TotalOutputTokensPerSecond != 100 {
+		t.Fatalf("unexpected rates: achieved=%f output=%f", step.AchievedRate, step.TotalOutputTokensPerSecond)
+	}
+
+	summary.PrefillLatencyDistribution.P95 = 650
+	summary.ErrorRate = 0.05
+	step = summarizeRateStep(8, summary, results, 2*time.Second, slo)
+	if step.MeetsSLO || len(step.Violations) != 2 {
+		t.Fatalf("expected TTFT and error rate violations, got %v", step.Violations)
+	}
+
+	step = summarizeRateStep(16, TestSummary{TotalTests: 2, FailedTests: 2, ErrorRate: 1}, nil, time.Second, SLOConfiguration{})
+	if step.MeetsSLO {
+		t.Fatalf("a step without successful requests must not meet the SLO")
+	}
+
+	// A zero error rate is a real objective, not a disabled check.
+	noErrors := 0.0
+	summary = TestSummary{TotalTests: 100, SuccessfulTests: 99, FailedTests: 1, ErrorRate: 0.01}
+	step = summarizeRateStep(4, summary, results, 2*time.Second, SLOConfiguration{MaxErrorRate: &noErrors})
+	if step.MeetsSLO {
+		t.Fatalf("expected a failed request to break a zero error rate SLO")
+	}
+}
+
+func TestValidateRateSweepConfigNeedsAThreshold(t *testing.T) {
+	rc := RateConfiguration{RateStart: 1, RateEnd: 4, RateStep: 1, NumRequests: 10}
+	noErrors, tooHigh := 0.0, 1.5
+	tests := []struct {
+		name    string
+		slo     SLOConfiguration
+		wantErr bool
+	}{
+		{"none", SLOConfiguration{}, true},
+		{"ttft", SLOConfiguration{MaxP95TTFT: 500}, false},
+		{"zero error rate", SLOConfiguration{MaxErrorRate: &noErrors}, false},
+		{"error rate above 1", SLOConfiguration{MaxErrorRate: &tooHigh}, true},
+		{"negative latency", SLOConfiguration{MaxP95Latency: -1, MaxErrorRate: &noErrors}, true},
+	}
+	for _, tt := range tests {
+		if err := validateRateSweepConfig(rc, tt.slo); (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
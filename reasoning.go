@@ -0,0 +1,81 @@
+package main
+
+import "time"
+
+// applyReasoning splits the output of a reasoning model into thinking and
+// answer. Reasoning tokens come from the server's usage details when it
+// reports them and are counted locally otherwise. Requests without any
+// reasoning are left untouched.
+func applyReasoning(result *TestResult, response *OpenAIResponse, reasoning, answer string, totalLatency time.Duration, counter *tokenCounter) {
+	reported := 0
+	if details := response.Usage.CompletionTokensDetails; details != nil {
+		reported = details.ReasoningTokens
+	}
+	if reasoning == "" && reported == 0 {
+		return
+	}
+
+	local := counter.count(reasoning)
+	result.LocalCompletionTokens += local
+	result.ReasoningTokens = local
+	result.AnswerTokens = counter.count(answer)
+	if reported > 0 {
+		result.ReasoningTokens = reported
+		if result.CompletionTokens >= reported {
+			result.AnswerTokens = result.CompletionTokens - reported
+		}
+	}
+
+	if response.ReasoningStart > 0 {
+		result.TimeToFirstReasoningToken = float64(response.ReasoningStart) / float64(time.Millisecond)
+		reasoningMs := float64(response.ReasoningEnd-response.ReasoningStart) / float64(time.Millisecond)
+		result.ReasoningTokensPerSecond = computeTokensPerSecond(result.ReasoningTokens, reasoningMs)
+	}
+	if response.AnswerStart > 0 {
+		result.TimeToFirstAnswerToken = float64(response.AnswerStart) / float64(time.Millisecond)
+		answerMs := float64(totalLatency-response.AnswerStart) / float64(time.Millisecond)
+		result.AnswerTokensPerSecond = computeTokensPerSecond(result.AnswerTokens, answerMs)
+	}
+}
+
+// summarizeReasoning reports reasoning and answer timing over the
+// successful results that reasoned, or nil when none did.
+func summarizeReasoning(results []TestResult) *ReasoningSummary {
+	var reasoningTokens, answerTokens, firstReasoning, firstAnswer, reasoningTPS, answerTPS []float64
+	for _, result := range results {
+		if !result.Success || result.ReasoningTokens == 0 {
+			continue
+		}
+		reasoningTokens = append(reasoningTokens, float64(result.ReasoningTokens))
+		answerTokens = append(answerTokens, float64(result.AnswerTokens))
+		if result.TimeToFirstReasoningToken > 0 {
+			firstReasoning = append(firstReasoning, result.TimeToFirstReasoningToken)
+		}
+		if result.TimeToFirstAnswerToken > 0 {
+			firstAnswer = append(firstAnswer, result.TimeToFirstAnswerToken)
+		}
+		if result.ReasoningTokensPerSecond > 0 {
+			reasoningTPS = append(reasoningTPS, result.ReasoningTokensPerSecond)
+		}
+		if result.AnswerTokensPerSecond > 0 {
+			answerTPS = append(answerTPS, result.AnswerTokensPerSecond)
+		}
+	}
+	if len(reasoningTokens) == 0 {
+		return nil
+	}
+
+	return &ReasoningSummary{
+		Requests:                              len(reasoningTokens),
+		AverageReasoningTokens:                mean(reasoningTokens),
+		AverageAnswerTokens:                   mean(answerTokens),
+		AverageTimeToFirstReasoningToken:      mean(firstReasoning),
+		TimeToFirstReasoningTokenDistribution: computeDistribution(firstReasoning),
+		AverageTimeToFirstAnswerToken:         mean(firstAnswer),
+		TimeToFirstAnswerTokenDistribution:    computeDistribution(firstAnswer),
+		AverageReasoningTokensPerSecond:       mean(reasoningTPS),
+		ReasoningTokensPerSecondDistribution:  computeDistribution(reasoningTPS),
+		AverageAnswerTokensPerSecond:          mean(answerTPS),
+		AnswerTokensPerSecondDistribution:     computeDistribution(answerTPS),
+	}
+}
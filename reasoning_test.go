@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReasoningIsSeparatedFromAnswer(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{
+		TTFT: 20 * time.Millisecond, TokensPerSecond: 500, ReasoningTokens: 12, ReportUsage: true, Seed: 1,
+	}))
+	defer server.Close()
+
+	config := mockTestConfiguration(server.URL + "/v1")
+	config.PromptType = "fixed"
+	config.PromptLength = 32
+	config.MaxTokens = 20
+
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), config, "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	for _, result := range batch.Results {
+		if !result.Success {
+			t.Fatalf("result %d failed: %s", result.TestNumber, result.Error)
+		}
+		if result.ReasoningTokens != 12 || result.AnswerTokens != 8 {
+			t.Fatalf("result %d: expected 12 reasoning and 8 answer tokens, got %d and %d",
+				result.TestNumber, result.ReasoningTokens, result.AnswerTokens)
+		}
+		// TTFT is the first reasoning token; the answer starts after all of them.
+		if result.TimeToFirstReasoningToken > result.RequestLatency+1 {
+			t.Fatalf("result %d: TTFT %.1f ms should end at the first reasoning token (%.1f ms)",
+				result.TestNumber, result.RequestLatency, result.TimeToFirstReasoningToken)
+		}
+		if result.TimeToFirstAnswerToken < result.TimeToFirstReasoningToken+15 {
+			t.Fatalf("result %d: first answer token at %.1f ms, too close to the first reasoning token at %.1f ms",
+				result.TestNumber, result.TimeToFirstAnswerToken, result.TimeToFirstReasoningToken)
+		}
+		if result.ReasoningTokensPerSecond <= 0 || result.AnswerTokensPerSecond <= 0 {
+			t.Fatalf("result %d: missing decode speeds %+v", result.TestNumber, result)
+		}
+	}
+
+	rs := batch.Summary.Reasoning
+	if rs == nil || rs.Requests != len(batch.Results) || rs.AverageReasoningTokens != 12 {
+		t.Fatalf("unexpected reasoning summary: %+v", rs)
+	}
+}
+
+func TestNoReasoningSummaryWithoutReasoning(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{TTFT: time.Millisecond, ReportUsage: true, Seed: 1}))
+	defer server.Close()
+
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), mockTestConfiguration(server.URL+"/v1"), "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if batch.Summary.Reasoning != nil || batch.Results[0].TimeToFirstAnswerToken != 0 {
+		t.Fatalf("expected no reasoning metrics, got %+v", batch.Summary.Reasoning)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultRegressionSignificance = 0.05
+
+// defaultRegressionTolerances allows 10% drift on latency and throughput
+// and one point of error rate, which absorbs run-to-run noise on a shared
+// GPU without hiding a real slowdown.
+func defaultRegressionTolerances() RegressionTolerances {
+	return RegressionTolerances{
+		MaxTTFTIncrease:      0.10,
+		MaxLatencyIncrease:   0.10,
+		MaxTPOTIncrease:      0.10,
+		MaxOutputTPSDecrease: 0.10,
+		MaxErrorRateIncrease: 0.01,
+		Significance:         defaultRegressionSignificance,
+	}
+}
+
+// validateRegressionTolerances rejects negative limits and a significance
+// level outside (0, 1). A zero significance selects the default.
+func validateRegressionTolerances(tol RegressionTolerances) error {
+	if tol.MaxTTFTIncrease < 0 || tol.MaxLatencyIncrease < 0 || tol.MaxTPOTIncrease < 0 ||
+		tol.MaxOutputTPSDecrease < 0 || tol.MaxErrorRateIncrease < 0 {
+		return fmt.Errorf("regression tolerances cannot be negative")
+	}
+	if tol.Significance < 0 || tol.Significance >= 1 {
+		return fmt.Errorf("significance must be between 0 and 1, got %g", tol.Significance)
+	}
+	return nil
+}
+
+// regressionMetric describes a per-request metric compared by CompareToBaseline.
+type regressionMetric struct {
+	name         string
+	unit         string
+	higherBetter bool
+	tolerance    func(RegressionTolerances) float64
+	value        func(TestResult) float64
+}
+
+var regressionMetrics = []regressionMetric{
+	{"TTFT", "ms", false, func(t RegressionTolerances) float64 { return t.MaxTTFTIncrease },
+		func(r TestResult) float64 { return r.RequestLatency }},
+	{"Total latency", "ms", false, func(t RegressionTolerances) float64 { return t.MaxLatencyIncrease },
+		func(r TestResult) float64 { return r.TotalLatency }},
+	{"TPOT", "ms", false, func(t RegressionTolerances) float64 { return t.MaxTPOTIncrease },
+		func(r TestResult) float64 { return r.TimePerOutputToken }},
+	{"Output tok/s", "tok/s", true, func(t RegressionTolerances) float64 { return t.MaxOutputTPSDecrease },
+		func(r TestResult) float64 { return r.OutputTokensPerSecond }},
+}
+
+// CompareToBaseline checks candidate against baseline. A metric regresses
+// only when its median moved past the tolerance in the bad direction and a
+// one-sided Mann-Whitney U test on the per-request values says the shift is
+// significant, so a single slow request cannot fail a run on its own.
+func CompareToBaseline(baseline, candidate TestBatch, tol RegressionTolerances) RegressionReport {
+	if tol.Significance <= 0 {
+		tol.Significance = defaultRegressionSignificance
+	}
+
+	report := RegressionReport{
+		BaselineID:  baseline.ID,
+		CandidateID: candidate.ID,
+		Tolerances:  tol,
+		Passed:      true,
+		Warnings:    compareBaselineConfigs(baseline.Configuration, candidate.Configuration),
+	}
+
+	for _, metric := range regressionMetrics {
+		check := compareMetric(metric, baseline.Results, candidate.Results, tol)
+		if check.Verdict == "regressed" {
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	errorCheck := compareErrorRates(baseline.Results, candidate.Results, tol)
+	if errorCheck.Verdict == "regressed" {
+		report.Passed = false
+	}
+	report.Checks = append(report.Checks, errorCheck)
+
+	report.Report = FormatRegressionReport(report)
+	return report
+}
+
+func compareMetric(metric regressionMetric, baselineResults, candidateResults []TestResult, tol RegressionTolerances) RegressionCheck {
+	base := regressionSamples(baselineResults, metric.value)
+	cand := regressionSamples(candidateResults, metric.value)
+	check := RegressionCheck{
+		Metric:    metric.name + " p50",
+		Unit:      metric.unit,
+		Tolerance: metric.tolerance(tol),
+		PValue:    1,
+		Verdict:   "skipped",
+	}
+	if len(base) == 0 || len(cand) == 0 {
+		return check
+	}
+
+	check.Baseline = computeDistribution(base).P50
+	check.Candidate = computeDistribution(cand).P50
+	if check.Baseline != 0 {
+		check.Change = (check.Candidate - check.Baseline) / check.Baseline
+		if metric.higherBetter {
+			check.Change = -check.Change
+		}
+	}
+
+	worse, better := cand, base
+	if metric.higherBetter {
+		worse, better = base, cand
+	}
+	check.PValue = mannWhitneyGreater(worse, better)
+	improvedP := mannWhitneyGreater(better, worse)
+
+	switch {
+	case check.Change > check.Tolerance && check.PValue < tol.Significance:
+		check.Verdict = "regressed"
+	case check.Change < -check.Tolerance && improvedP < tol.Significance:
+		check.Verdict = "improved"
+	default:
+		check.Verdict = "pass"
+	}
+	return check
+}
+
+func compareErrorRates(baselineResults, candidateResults []TestResult, tol RegressionTolerances) RegressionCheck {
+	baseFailed, candFailed := countFailed(baselineResults), countFailed(candidateResults)
+	check := RegressionCheck{
+		Metric:    "Error rate",
+		Unit:      "%",
+		Tolerance: tol.MaxErrorRateIncrease,
+		PValue:    1,
+		Verdict:   "skipped",
+	}
+	if len(baselineResults) == 0 || len(candidateResults) == 0 {
+		return check
+	}
+
+	check.Baseline = float64(baseFailed) / float64(len(baselineResults))
+	check.Candidate = float64(candFailed) / float64(len(candidateResults))
+	check.Change = check.Candidate - check.Baseline
+	check.PValue = twoProportionGreater(candFailed, len(candidateResults), baseFailed, len(baselineResults))
+	improvedP := twoProportionGreater(baseFailed, len(baselineResults), candFailed, len(candidateResults))
+
+	switch {
+	case check.Change > check.Tolerance && check.PValue < tol.Significance:
+		check.Verdict = "regressed"
+	case check.Change < -check.Tolerance && improvedP < tol.Significance:
+		check.Verdict = "improved"
+	default:
+		check.Verdict = "pass"
+	}
+	return check
+}
+
+// regressionSamples collects a metric from successful results, skipping
+// zeros (e.g. TPOT of single-token responses) that carry no measurement.
+func regressionSamples(results []TestResult, value func(TestResult) float64) []float64 {
+	samples := make([]float64, 0, len(results))
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		if v := value(result); v > 0 && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			samples = append(samples, v)
+		}
+	}
+	return samples
+}
+
+func countFailed(results []TestResult) int {
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+		}
+	}
+	return failed
+}
+
+// compareBaselineConfigs lists configuration differences that make the
+// comparison less meaningful. They are reported, not treated as failures.
+func compareBaselineConfigs(baseline, candidate TestConfiguration) []string {
+	var warnings []string
+	diff := func(field string, a, b interface{}) {
+		if a != b {
+			warnings = append(warnings, fmt.Sprintf("%s differs: baseline %v, candidate %v", field, a, b))
+		}
+	}
+
+	diff("model", baseline.Model, candidate.Model)
+	diff("test mode", baseline.TestMode, candidate.TestMode)
+	diff("prompt type", baseline.PromptType, candidate.PromptType)
+	if baseline.PromptType != "dataset" && baseline.PromptType != "custom" {
+		diff("prompt length", baseline.PromptLength, candidate.PromptLength)
+	}
+	diff("max tokens", baseline.MaxTokens, candidate.MaxTokens)
+	diff("concurrency", baseline.ConcurrentTests, candidate.ConcurrentTests)
+	diff("provider", baseline.Provider, candidate.Provider)
+
+	return warnings
+}
+
+// FormatRegressionReport renders report as a plain-text table.
+func FormatRegressionReport(report RegressionReport) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Regression check: %s vs baseline %s\n", shortBatchID(report.CandidateID), shortBatchID(report.BaselineID))
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(&b, "  warning: %s\n", warning)
+	}
+	fmt.Fprintf(&b, "  %-18s %12s %12s %9s %9s %8s  %s\n",
+		"metric", "baseline", "candidate", "change", "limit", "p-value", "verdict")
+
+	regressions := 0
+	for _, check := range report.Checks {
+		if check.Verdict == "skipped" {
+			fmt.Fprintf(&b, "  %-18s %12s %12s %9s %9s %8s  %s\n", check.Metric, "-", "-", "-", "-", "-", "skipped (no samples)")
+			continue
+		}
+
+		baseline, candidate := formatRegressionValue(check.Baseline, check.Unit), formatRegressionValue(check.Candidate, check.Unit)
+		change := fmt.Sprintf("%+.1f%%", check.Change*100)
+		limit := fmt.Sprintf("%.1f%%", check.Tolerance*100)
+		if check.Unit == "%" {
+			change = fmt.Sprintf("%+.2fpt", check.Change*100)
+			limit = fmt.Sprintf("%.2fpt", check.Tolerance*100)
+		}
+
+		verdict := check.Verdict
+		if verdict == "regressed" {
+			verdict = "REGRESSED"
+			regressions++
+		}
+		fmt.Fprintf(&b, "  %-18s %12s %12s %9s %9s %8.4f  %s\n",
+			check.Metric, baseline, candidate, change, limit, check.PValue, verdict)
+	}
+
+	if report.Passed {
+		b.WriteString("Result: PASS\n")
+	} else {
+		fmt.Fprintf(&b, "Result: FAIL (%d regression(s), significance %.3g)\n", regressions, report.Tolerances.Significance)
+	}
+	return b.String()
+}
+
+func formatRegressionValue(value float64, unit string) string {
+	if unit == "%" {
+		return fmt.Sprintf("%.2f%%", value*100)
+	}
+	return fmt.Sprintf("%.1f %s", value, unit)
+}
+
+func shortBatchID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// loadBaselineBatch reads a baseline batch with loadStoredBatch and checks
+// that it has per-request results to compare against.
+func loadBaselineBatch(ref string) (*TestBatch, error) {
+	batch, err := loadStoredBatch(ref, "baseline")
+	if err != nil {
+		return nil, err
+	}
+	if len(batch.Results) == 0 {
+		return nil, fmt.Errorf("baseline %s has no per-request results", ref)
+	}
+	return batch, nil
+}
+
+// loadStoredBatch reads a batch from a history batch file, a JSON export
+// (which wraps the batch under "batch"), or the ID of a batch in the
+// history directory. role names the batch in errors.
+func loadStoredBatch(ref, role string) (*TestBatch, error) {
+	path := ref
+	if _, err := os.Stat(path); err != nil {
+		dir, dirErr := getHistoryDir()
+		if dirErr != nil || strings.ContainsAny(ref, `/\`) {
+			return nil, fmt.Errorf("%s not found: %s", role, ref)
+		}
+		path = filepath.Join(dir, ref+".json")
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("%s not found: %s", role, ref)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", role, err)
+	}
+
+	var wrapped struct {
+		Batch *TestBatch `json:"batch"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", role, err)
+	}
+	batch := wrapped.Batch
+	if batch == nil {
+		batch = &TestBatch{}
+		if err := json.Unmarshal(data, batch); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", role, err)
+		}
+	}
+
+	if batch.ID == "" {
+		return nil, fmt.Errorf("%s %s does not contain a test batch", role, ref)
+	}
+	return batch, nil
+}
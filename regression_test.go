@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMannWhitneyGreater(t *testing.T) {
+	low := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	high := []float64{11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+
+	if p := mannWhitneyGreater(high, low); p > 0.001 {
+		t.Fatalf("expected a clear shift to be significant, got p=%f", p)
+	}
+	if p := mannWhitneyGreater(low, high); p < 0.99 {
+		t.Fatalf("expected the wrong direction to be insignificant, got p=%f", p)
+	}
+	if p := mannWhitneyGreater(low, low); p < 0.4 {
+		t.Fatalf("expected identical samples to be insignificant, got p=%f", p)
+	}
+	if p := mannWhitneyGreater([]float64{5, 5, 5}, []float64{5, 5, 5}); p != 1 {
+		t.Fatalf("expected all-tied samples to yield p=1, got %f", p)
+	}
+}
+
+// regressionBatch builds successful results whose TTFT and output tok/s
+// spread evenly around the given centres.
+func regressionBatch(id string, ttft, tps float64, failed int) TestBatch {
+	batch := TestBatch{ID: id, Configuration: TestConfiguration{Model: "m"}}
+	for i := 0; i < 30; i++ {
+		offset := float64(i%10) - 4.5
+		batch.Results = append(batch.Results, TestResult{
+			Success:               true,
+			RequestLatency:        ttft + offset,
+			TotalLatency:          ttft*4 + offset,
+			OutputTokensPerSecond: tps + offset/10,
+		})
+	}
+	for i := 0; i < failed; i++ {
+		batch.Results = append(batch.Results, TestResult{Error: "boom"})
+	}
+	return batch
+}
+
+func TestCompareToBaseline(t *testing.T) {
+	baseline := regressionBatch("base", 100, 50, 0)
+	tol := defaultRegressionTolerances()
+
+	report := CompareToBaseline(baseline, regressionBatch("same", 100, 50, 0), tol)
+	if !report.Passed {
+		t.Fatalf("expected identical batch to pass:\n%s", report.Report)
+	}
+
+	// +5% TTFT is significant but inside the 10% tolerance.
+	report = CompareToBaseline(baseline, regressionBatch("small", 105, 50, 0), tol)
+	if !report.Passed {
+		t.Fatalf("expected change within tolerance to pass:\n%s", report.Report)
+	}
+
+	report = CompareToBaseline(baseline, regressionBatch("slow", 130, 40, 0), tol)
+	if report.Passed {
+		t.Fatalf("expected slower batch to regress:\n%s", report.Report)
+	}
+	verdicts := map[string]string{}
+	for _, check := range report.Checks {
+		verdicts[check.Metric] = check.Verdict
+	}
+	if verdicts["TTFT p50"] != "regressed" || verdicts["Output tok/s p50"] != "regressed" {
+		t.Fatalf("unexpected verdicts: %v", verdicts)
+	}
+	if verdicts["TPOT p50"] != "skipped" || verdicts["Error rate"] != "pass" {
+		t.Fatalf("unexpected verdicts: %v", verdicts)
+	}
+	if !strings.Contains(report.Report, "REGRESSED") || !strings.Contains(report.Report, "Result: FAIL") {
+		t.Fatalf("expected the diff report to flag the regression:\n%s", report.Report)
+	}
+
+	report = CompareToBaseline(baseline, regressionBatch("faster", 70, 50, 0), tol)
+	if !report.Passed || report.Checks[0].Verdict != "improved" {
+		t.Fatalf("expected faster batch to pass as improved:\n%s", report.Report)
+	}
+
+	report = CompareToBaseline(baseline, regressionBatch("flaky", 100, 50, 10), tol)
+	if report.Passed {
+		t.Fatalf("expected higher error rate to regress:\n%s", report.Report)
+	}
+}
+
+func TestLoadBaselineBatch(t *testing.T) {
+	dir := t.TempDir()
+	batch := regressionBatch("base", 100, 50, 0)
+
+	exported, _ := json.Marshal(map[string]interface{}{"exportMetadata": map[string]string{}, "batch": batch})
+	exportPath := filepath.Join(dir, "export.json")
+	os.WriteFile(exportPath, exported, 0o600)
+
+	stored, _ := json.Marshal(batch)
+	historyPath := filepath.Join(dir, "history.json")
+	os.WriteFile(historyPath, stored, 0o600)
+
+	for _, path := range []string{exportPath, historyPath} {
+		loaded, err := loadBaselineBatch(path)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if loaded.ID != "base" || len(loaded.Results) != 30 {
+			t.Fatalf("%s: unexpected batch %s with %d results", path, loaded.ID, len(loaded.Results))
+		}
+	}
+
+	if _, err := loadBaselineBatch(filepath.Join(dir, "missing.json")); err == nil {
+		t.Fatalf("expected a missing baseline to fail")
+	}
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
+	"math/rand"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -15,65 +17,208 @@ import (
 
 // SpeedTestService handles LLM speed testing operations
 type SpeedTestService struct {
-	progressChan  chan ProgressUpdate
-	resultsChan   chan TestResult
-	telemetryChan chan TelemetryUpdate
+	events *EventBus
+
+	mu         sync.RWMutex
+	tokenizers []TokenizerMapping
 }
 
 // NewSpeedTestService creates a new speed test service
 func NewSpeedTestService() *SpeedTestService {
 	return &SpeedTestService{
-		progressChan:  make(chan ProgressUpdate, 100),
-		resultsChan:   make(chan TestResult, 100),
-		telemetryChan: make(chan TelemetryUpdate, 100),
+		events: NewEventBus(),
 	}
 }
 
-// GetProgressChannel returns the progress update channel
-func (s *SpeedTestService) GetProgressChannel() <-chan ProgressUpdate {
-	return s.progressChan
+// Events returns the bus progress, results, telemetry and batch completion
+// are published on. Subscribe before starting a batch to see all of it.
+func (s *SpeedTestService) Events() *EventBus {
+	return s.events
 }
 
-// GetResultsChannel returns the test results channel
-func (s *SpeedTestService) GetResultsChannel() <-chan TestResult {
-	return s.resultsChan
+// SetTokenizers replaces the model to tokenizer mappings consulted for
+// batches whose configuration names no tokenizer.
+func (s *SpeedTestService) SetTokenizers(mappings []TokenizerMapping) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokenizers = append([]TokenizerMapping(nil), mappings...)
 }
 
-// GetTelemetryChannel returns the telemetry update channel
-func (s *SpeedTestService) GetTelemetryChannel() <-chan TelemetryUpdate {
-	return s.telemetryChan
+// tokenizerFor returns the tokenizer config runs with: its own, or the
+// mapped one for its model.
+func (s *SpeedTestService) tokenizerFor(config TestConfiguration) string {
+	if config.Tokenizer != "" {
+		return config.Tokenizer
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return resolveTokenizer(s.tokenizers, config.Model)
 }
 
 type testStep struct {
 	concurrency  int
-	promptLength int
+	promptLength int                // Local token length synthetic prompts are generated at
+	requests     int                // Requests sent in this step
+	requestRate  float64            // Open-loop arrival rate (req/s); 0 means closed-loop
+	turns        int                // Requests per conversation session; 0 means independent requests
+	config       *TestConfiguration // Configuration of a sweep point; nil means the batch configuration
+	coordinates  map[string]float64 // Swept field values of a sweep point
+}
+
+// configFor returns the configuration requests of step run with.
+func (step testStep) configFor(batch TestConfiguration) TestConfiguration {
+	if step.config != nil {
+		return *step.config
+	}
+	return batch
 }
 
 // RunSpeedTest executes a speed test batch
 func (s *SpeedTestService) RunSpeedTest(ctx context.Context, config TestConfiguration, batchID string) (*TestBatch, error) {
+	return s.runSpeedTest(ctx, config, batchID, runOptions{})
+}
+
+// requestWorkload is the state requests of a batch are built from. It is
+// set up once per batch; nil fields are features the batch does not use.
+type requestWorkload struct {
+	// dataset replays the record assigned to each test number instead of a
+	// generated prompt; otherwise prompts, when set, supplies the prompt.
+	dataset *Dataset
+	prompts *promptCache
+	prefix  *prefixWorkload
+	// conv is set per session: the request is the session's next turn, its
+	// history is sent first and a successful turn adds its exchange to it.
+	conv *conversation
+	// tools are offered to the model and must be called as
+	// config.ToolConfig asks.
+	tools []Tool
+	// structured constrains the output of every other request to its
+	// schema and checks it.
+	structured *structuredOutput
+	// vision attaches config.VisionConfig's images to the last message.
+	vision *visionWorkload
+	// counter counts tokens locally, e.g. to cross-check reported usage.
+	counter *tokenCounter
+}
+
+// runOptions carries settings shared between the batches of a matrix run
+// rather than configured by the user.
+type runOptions struct {
+	prompts   *promptCache // nil = every request generates its own prompt
+	gate      *gateTicket  // nil = start right after the warm-up
+	matrixID  string
+	batchName string
+	replayOf  string
+}
+
+func (s *SpeedTestService) runSpeedTest(ctx context.Context, config TestConfiguration, batchID string, opts runOptions) (batch *TestBatch, err error) {
 	if batchID == "" {
 		batchID = uuid.New().String()
 	}
+
+	// A batch that fails early must not hold up the rest of its matrix.
+	if opts.gate != nil {
+		defer opts.gate.leave()
+	}
+
+	// Every batch ends with a completed event, even when it fails to
+	// start, so batch-scoped subscribers always terminate.
+	defer func() {
+		completed := Event{Type: EventCompleted, BatchID: batchID, Model: config.Model, Batch: batch}
+		if err != nil {
+			completed.Error = err.Error()
+		}
+		s.events.Publish(completed)
+	}()
 	startTime := time.Now().Format(time.RFC3339)
 
+	// Prompts and streamed tokens are counted with the model's tokenizer,
+	// loaded once for the whole batch. The resolved path is kept in the
+	// configuration so the batch records which tokenizer it used.
+	config.Tokenizer = s.tokenizerFor(config)
+	counter, err := loadTokenCounter(config.Model, config.Tokenizer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prompts, the dataset sample and open-loop arrivals all derive from
+	// the batch seed, so replaying a batch with its recorded seed sends
+	// the same prompts on the same schedule.
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	if config.PromptType == "dataset" && config.DatasetConfig.Seed == 0 {
+		config.DatasetConfig.Seed = deriveSeed(config.Seed, "dataset")
+	}
+
 	// 1. Generate Steps
+	// Step and sweep modes run one step per point of their sweep.
+	dims, err := sweepDimensionsFor(config)
+	if err != nil {
+		return nil, err
+	}
+
 	steps := []testStep{}
-	if config.TestMode == "concurrency_step" {
-		if config.StepConfig.Start <= 0 || config.StepConfig.Step <= 0 || config.StepConfig.End < config.StepConfig.Start {
-			return nil, fmt.Errorf("invalid step configuration for concurrency: start=%d, end=%d, step=%d",
-				config.StepConfig.Start, config.StepConfig.End, config.StepConfig.Step)
+	if dims != nil {
+		points, err := buildSweepPoints(config, dims)
+		if err != nil {
+			return nil, err
+		}
+		for _, point := range points {
+			pointConfig := point.config
+			steps = append(steps, testStep{
+				concurrency:  pointConfig.ConcurrentTests,
+				promptLength: pointConfig.PromptLength,
+				config:       &pointConfig,
+				coordinates:  point.coordinates,
+			})
+		}
+	} else if config.TestMode == "rate" {
+		// Open-loop mode: requests arrive at a fixed average rate regardless
+		// of how quickly the server answers. Concurrency only groups results
+		// into rounds for the round summaries.
+		if err := validateRateConfig(config.RateConfig); err != nil {
+			return nil, err
 		}
-		for c := config.StepConfig.Start; c <= config.StepConfig.End; c += config.StepConfig.Step {
-			steps = append(steps, testStep{concurrency: c, promptLength: config.PromptLength})
+		if config.RateConfig.RequestRate <= 0 {
+			return nil, fmt.Errorf("invalid rate configuration: requestRate must be positive, got %g", config.RateConfig.RequestRate)
 		}
-	} else if config.TestMode == "input_step" {
-		if config.StepConfig.Start <= 0 || config.StepConfig.Step <= 0 || config.StepConfig.End < config.StepConfig.Start {
-			return nil, fmt.Errorf("invalid step configuration for input: start=%d, end=%d, step=%d",
-				config.StepConfig.Start, config.StepConfig.End, config.StepConfig.Step)
+		steps = append(steps, testStep{
+			concurrency:  max(config.ConcurrentTests, 1),
+			promptLength: config.PromptLength,
+			requests:     config.RateConfig.NumRequests,
+			requestRate:  config.RateConfig.RequestRate,
+		})
+	} else if config.TestMode == "rate_step" {
+		// Rate sweep: one open-loop step per arrival rate. Steps run in
+		// ascending order and the sweep stops at the first SLO violation.
+		if err := validateRateSweepConfig(config.RateConfig, config.SLO); err != nil {
+			return nil, err
 		}
-		for l := config.StepConfig.Start; l <= config.StepConfig.End; l += config.StepConfig.Step {
-			steps = append(steps, testStep{concurrency: config.ConcurrentTests, promptLength: l})
+		for _, rate := range buildRateSweep(config.RateConfig) {
+			steps = append(steps, testStep{
+				concurrency:  max(config.ConcurrentTests, 1),
+				promptLength: config.PromptLength,
+				requests:     config.RateConfig.NumRequests,
+				requestRate:  rate,
+			})
 		}
+	} else if config.TestMode == "conversation" {
+		// Conversation mode: concurrency bounds the sessions in flight,
+		// and each session sends its turns one after another.
+		if err := validateConversationConfig(config); err != nil {
+			return nil, err
+		}
+		cc := config.ConversationConfig
+		steps = append(steps, testStep{
+			concurrency:  max(config.ConcurrentTests, 1),
+			promptLength: config.PromptLength,
+			requests:     cc.Sessions * cc.Turns,
+			turns:        cc.Turns,
+		})
 	} else {
 		// Normal mode (default)
 		// Ensure concurrency is valid
@@ -85,33 +230,118 @@ func (s *SpeedTestService) RunSpeedTest(ctx context.Context, config TestConfigur
 	}
 
 	// 2. Calculate Total Tests
-	// For normal mode, this is simply testCount * concurrency.
-	// For step modes, we sum over each generated step so that
-	// progress, telemetry and UI charts see the true total.
+	// Closed-loop steps send testCount * concurrency requests; open-loop
+	// steps send a fixed number of requests. We sum over every step so
+	// that progress, telemetry and UI charts see the true total.
 	totalTests := 0
-	for _, step := range steps {
-		totalTests += config.TestCount * step.concurrency
+	for i := range steps {
+		if steps[i].requests == 0 {
+			steps[i].requests = steps[i].configFor(config).TestCount * steps[i].concurrency
+		}
+		totalTests += steps[i].requests
 	}
 
 	if totalTests <= 0 {
 		return nil, fmt.Errorf("invalid test configuration: total tests must be positive")
 	}
+	for _, step := range steps {
+		if err := validatePrefixConfig(config.PrefixConfig, config.PromptType, step.promptLength); err != nil {
+			return nil, err
+		}
+	}
+
+	// Dataset workloads are loaded once per batch; every request then
+	// replays the record assigned to its test number.
+	var dataset *Dataset
+	if config.PromptType == "dataset" {
+		var err error
+		dataset, err = loadDataset(config.DatasetConfig, counter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tools, err := loadTools(config)
+	if err != nil {
+		return nil, err
+	}
+	structured, err := loadStructuredOutput(config)
+	if err != nil {
+		return nil, err
+	}
+	stepConfigs := make([]TestConfiguration, len(steps))
+	for i, step := range steps {
+		stepConfigs[i] = step.configFor(config)
+	}
+	vision, err := newVisionWorkload(config, stepConfigs)
+	if err != nil {
+		return nil, err
+	}
 
 	results := make([]TestResult, 0, totalTests)
 	var mu sync.Mutex
 
-	// Reuse a single OpenAI client for the whole batch
-	client := NewOpenAIClient(config.APIEndpoint, config.APIKey, config.Timeout)
+	// Reuse a single backend client for the whole batch
+	client, err := NewBackend(config.Provider, config.APIEndpoint, config.APIKey, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Shared prefixes are generated once per batch (or matrix run).
+	prefix := newPrefixWorkload(config, opts.prompts, counter)
+
+	// Exact-length prompts are sized against the server's own prompt token
+	// counts before the warm-up, so every request uses calibrated lengths.
+	var calibration *PromptCalibration
+	if config.ExactPromptLength {
+		lengths := make([]int, len(steps))
+		for i, step := range steps {
+			lengths[i] = step.promptLength
+		}
+		localLengths, cal, err := s.calibratePrompts(ctx, client, config, prefix, lengths, counter)
+		if err != nil {
+			return nil, err
+		}
+		for i := range steps {
+			steps[i].promptLength = localLengths[steps[i].promptLength]
+		}
+		calibration = cal
+	}
+
+	// Image tokens are the prompt tokens beyond the text and its chat
+	// template, so the template overhead is taken from the calibration or
+	// from a text-only probe laid out like the run's requests.
+	if vision != nil && len(steps) > 0 {
+		if calibration != nil {
+			vision.templateOverhead, vision.templateOverheadKnown = calibration.TemplateOverhead, true
+		} else if local, server, err := probePrompt(ctx, client, config, probeMessages(config, prefix, steps[0].promptLength, counter), counter); err == nil {
+			vision.templateOverhead, vision.templateOverheadKnown = server-local, true
+		} else {
+			log.Printf("Image tokens will not be reported: %v", err)
+		}
+	}
+
+	work := requestWorkload{
+		dataset:    dataset,
+		prompts:    opts.prompts,
+		prefix:     prefix,
+		tools:      tools,
+		structured: structured,
+		vision:     vision,
+		counter:    counter,
+	}
 
 	// Optional warm-up request (not included in results)
 	// This helps hide cold-start latency for some local deployments.
 	if len(steps) > 0 {
 		warmupStep := steps[0]
-		warmupResult := s.runIndividualTest(ctx, client, config, 0, warmupStep.concurrency, warmupStep.promptLength, nil, nil)
+		warmupResult := s.runIndividualTest(ctx, client, warmupStep.configFor(config), 0, warmupStep.concurrency, warmupStep.promptLength, work, nil, nil)
 		if !warmupResult.Success && warmupResult.Error != "" {
 			log.Printf("Warm-up request failed: %s", warmupResult.Error)
 		}
 	}
+	if opts.gate != nil {
+		opts.gate.wait()
+	}
 
 	// Telemetry state
 	var activeTests int32
@@ -155,22 +385,7 @@ func (s *SpeedTestService) RunSpeedTest(ctx context.Context, config TestConfigur
 					avgTTFT = float64(ttftSum) / float64(ttftCount) / 1000.0 // convert us to ms
 				}
 				if len(ttftValues) > 0 {
-					// Sort copy to avoid race conditions if we were writing (but we only read/append under lock)
-					// Actually sort.Float64s modifies the slice. We should make a copy if we wanted to keep original order,
-					// but here order doesn't matter for appending.
-					// However, if appending happens concurrently... appending is under lock.
-					// So it is safe to sort in place.
-					sortedTTFT := make([]float64, len(ttftValues))
-					copy(sortedTTFT, ttftValues)
-					sort.Float64s(sortedTTFT)
-					idx := int(math.Ceil(float64(len(sortedTTFT))*0.95)) - 1
-					if idx < 0 {
-						idx = 0
-					}
-					if idx >= len(sortedTTFT) {
-						idx = len(sortedTTFT) - 1
-					}
-					p95TTFT = sortedTTFT[idx]
+					p95TTFT = computeDistribution(ttftValues).P95
 				}
 				telemetryMu.Unlock()
 
@@ -187,17 +402,125 @@ func (s *SpeedTestService) RunSpeedTest(ctx context.Context, config TestConfigur
 					StepTotal:       stepTotal,
 				}
 
-				select {
-				case s.telemetryChan <- update:
-				default:
-					// Skip if channel is full
-				}
+				s.events.Publish(Event{Type: EventTelemetry, BatchID: batchID, Model: config.Model, Step: update.StepCurrent, Timestamp: update.Timestamp, Telemetry: &update})
 			}
 		}
 	}()
 
+	// runTest executes one request of a step and reports whether it
+	// succeeded. slots bounds how many requests may be in flight (nil means
+	// unbounded). scheduled is the open-loop arrival time; it is zero for
+	// closed-loop steps. conv is the session a conversation turn belongs to.
+	runTest := func(step testStep, currentGlobalIndex int, slots chan struct{}, scheduled time.Time, conv *conversation) bool {
+		// Acquire semaphore
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return false
+			}
+			defer func() { <-slots }()
+		}
+		dispatched := time.Now()
+
+		// Send progress update
+		progress := ProgressUpdate{
+			TestID:     uuid.New().String(),
+			BatchID:    batchID,
+			TestNumber: currentGlobalIndex + 1,
+			TotalTests: totalTests,
+			Status:     "running",
+		}
+
+		if ctx.Err() != nil {
+			return false
+		}
+		stepNumber := int(atomic.LoadInt32(&currentStepIndex))
+		running := progress
+		s.events.Publish(Event{Type: EventProgress, BatchID: batchID, Model: config.Model, Step: stepNumber, Progress: &running})
+
+		inFlight := atomic.AddInt32(&activeTests, 1)
+
+		// Live token count for InstantTPS. Servers that attach running
+		// usage to content chunks are exact; otherwise each chunk is counted
+		// with the model's tokenizer.
+		reportedTokens := 0
+		onToken := func(content string, usage *Usage) {
+			var tokens int
+			if usage != nil && usage.CompletionTokens > 0 {
+				tokens = usage.CompletionTokens - reportedTokens
+				reportedTokens = usage.CompletionTokens
+			} else {
+				tokens = counter.count(content)
+				if tokens < 1 && content != "" {
+					tokens = 1 // a non-empty chunk carries at least one token
+				}
+			}
+			atomic.AddInt64(&generatedTokens, int64(tokens))
+		}
+
+		onFirstToken := func(d time.Duration) {
+			ms := float64(d.Microseconds()) / 1000.0
+			atomic.AddInt64(&ttftSum, d.Microseconds())
+			atomic.AddInt64(&ttftCount, 1)
+			telemetryMu.Lock()
+			ttftValues = append(ttftValues, ms)
+			telemetryMu.Unlock()
+		}
+
+		// Run individual test with specific step parameters
+		turnWork := work
+		turnWork.conv = conv
+		result := s.runIndividualTest(ctx, client, step.configFor(config), currentGlobalIndex+1, step.concurrency, step.promptLength, turnWork, onToken, onFirstToken)
+
+		atomic.AddInt32(&activeTests, -1)
+		atomic.AddInt32(&completedTests, 1)
+
+		// A request aborted by cancellation says nothing about the server.
+		if !result.Success && ctx.Err() != nil {
+			return false
+		}
+
+		result.ID = progress.TestID
+		result.SweepPoint = step.coordinates
+
+		// In open-loop steps the time spent waiting for a free slot (or for
+		// the dispatcher) is queueing delay, not server latency. The
+		// concurrency actually observed replaces the nominal round size.
+		if !scheduled.IsZero() {
+			result.QueueDelay = math.Max(0, float64(dispatched.Sub(scheduled))/float64(time.Millisecond))
+			result.ActualConcurrency = int(inFlight)
+		}
+
+		// Update progress
+		if result.Success {
+			progress.Status = "completed"
+		} else {
+			progress.Status = "failed"
+			progress.Message = result.Error
+		}
+
+		s.events.Publish(Event{Type: EventProgress, BatchID: batchID, Model: config.Model, Step: stepNumber, Progress: &progress})
+
+		// Store result
+		mu.Lock()
+		results = append(results, result)
+		mu.Unlock()
+
+		s.events.Publish(Event{Type: EventResult, BatchID: batchID, Model: config.Model, Step: stepNumber, Result: &result})
+		return result.Success
+	}
+
+	arrivalRNG := rand.New(rand.NewSource(deriveSeed(config.Seed, "arrivals")))
 	globalTestIndex := 0
 
+	var rateSweep *RateSweepResult
+	if config.TestMode == "rate_step" {
+		rateSweep = &RateSweepResult{}
+	}
+	var sweepPoints []SweepPointSummary
+
+steps:
 	for stepIndex, step := range steps {
 		// Update current step index for telemetry consumers (1-based)
 		atomic.StoreInt32(&currentStepIndex, int32(stepIndex+1))
@@ -209,120 +532,145 @@ func (s *SpeedTestService) RunSpeedTest(ctx context.Context, config TestConfigur
 		default:
 		}
 
-		stepTotalTests := config.TestCount * step.concurrency
+		stepTotalTests := step.requests
+		stepStart := time.Now()
+		mu.Lock()
+		stepFirstResult := len(results)
+		mu.Unlock()
 		var wg sync.WaitGroup
 
-		// Create a semaphore for this step's concurrency
-		semaphore := make(chan struct{}, step.concurrency)
-
-		for i := 0; i < stepTotalTests; i++ {
-			// Check if context is cancelled
-			select {
-			case <-ctx.Done():
-				break
-			default:
+		if step.requestRate > 0 {
+			// Open loop: dispatch on the arrival schedule and never wait for
+			// earlier requests, unless maxInFlight caps outstanding requests.
+			var slots chan struct{}
+			if config.RateConfig.MaxInFlight > 0 {
+				slots = make(chan struct{}, config.RateConfig.MaxInFlight)
 			}
 
-			wg.Add(1)
-			go func(stepTestIndex int, currentGlobalIndex int) {
-				defer wg.Done()
-
-				// Acquire semaphore
-				select {
-				case semaphore <- struct{}{}:
-				case <-ctx.Done():
-					return
-				}
-				defer func() { <-semaphore }()
-
-				// Send progress update
-				progress := ProgressUpdate{
-					TestID:     uuid.New().String(),
-					BatchID:    batchID,
-					TestNumber: currentGlobalIndex + 1,
-					TotalTests: totalTests,
-					Status:     "running",
-				}
-
-				select {
-				case s.progressChan <- progress:
-				case <-ctx.Done():
-					return
-				}
-
-				atomic.AddInt32(&activeTests, 1)
-
-				// Define callbacks
-				onToken := func(content string) {
-					// We need token count. Since we don't have tokenizer here, we approximate or assume 1 chunk ~= 1 token?
-					// No, that's bad. But OpenAI usage comes at end.
-					// However, we can count characters or words?
-					// For accurate TPS, we need token count.
-					// If we don't have a tokenizer, character count / 4 is a rough approximation for English.
-					// Or we can just count "chunks" if chunk usually contains 1 token (not always true).
-					// But wait, `speed_test_service.go` computes TPS based on Usage.CompletionTokens.
-					// For real-time visualization, we can use a simple heuristic: len(content) / 4.
-					// Or just count generated characters and frontend divides by 4?
-					// Let's use len(content) / 4.0 as rough token estimate for instant TPS.
-					tokens := int64(len(content) / 3) // rough estimate
-					if tokens < 1 && len(content) > 0 {
-						tokens = 1
+			schedule := buildArrivalSchedule(config.RateConfig.ArrivalProcess, step.requestRate, config.RateConfig.BurstSize, stepTotalTests, arrivalRNG)
+
+		dispatch:
+			for i, offset := range schedule {
+				scheduled := stepStart.Add(offset)
+				if wait := time.Until(scheduled); wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-ctx.Done():
+						timer.Stop()
+						break dispatch
+					case <-timer.C:
 					}
-					atomic.AddInt64(&generatedTokens, tokens)
-				}
-
-				onFirstToken := func(d time.Duration) {
-					ms := float64(d.Microseconds()) / 1000.0
-					atomic.AddInt64(&ttftSum, d.Microseconds())
-					atomic.AddInt64(&ttftCount, 1)
-					telemetryMu.Lock()
-					ttftValues = append(ttftValues, ms)
-					telemetryMu.Unlock()
-				}
-
-				// Run individual test with specific step parameters
-				result := s.runIndividualTest(ctx, client, config, currentGlobalIndex+1, step.concurrency, step.promptLength, onToken, onFirstToken)
-
-				atomic.AddInt32(&activeTests, -1)
-				atomic.AddInt32(&completedTests, 1)
-
-				result.ID = progress.TestID
-
-				// Update progress
-				if result.Success {
-					progress.Status = "completed"
-				} else {
-					progress.Status = "failed"
-					progress.Message = result.Error
 				}
 
-				select {
-				case s.progressChan <- progress:
-				case <-ctx.Done():
-					return
-				}
-
-				// Store result
-				mu.Lock()
-				results = append(results, result)
-				mu.Unlock()
+				wg.Add(1)
+				go func(currentGlobalIndex int, scheduled time.Time) {
+					defer wg.Done()
+					runTest(step, currentGlobalIndex, slots, scheduled, nil)
+				}(globalTestIndex+i, scheduled)
+			}
+		} else if step.turns > 0 {
+			// A session holds its slot for all of its turns. A failed turn
+			// ends the session, since the next turn would lack its reply.
+			semaphore := make(chan struct{}, step.concurrency)
+			sessions := stepTotalTests / step.turns
+			cc := config.ConversationConfig
+
+			for session := 1; session <= sessions; session++ {
+				wg.Add(1)
+				go func(session int) {
+					defer wg.Done()
+					select {
+					case semaphore <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+					defer func() { <-semaphore }()
+
+					conv := &conversation{session: session}
+					for turn := 1; turn <= step.turns; turn++ {
+						turnStep := step
+						turnStep.promptLength = cc.turnLength(turn, step.promptLength)
+						index := globalTestIndex + (session-1)*step.turns + turn - 1
+						if !runTest(turnStep, index, nil, time.Time{}, conv) {
+							return
+						}
+					}
+				}(session)
+			}
+		} else {
+			// Create a semaphore for this step's concurrency
+			semaphore := make(chan struct{}, step.concurrency)
 
+			for i := 0; i < stepTotalTests; i++ {
+				// Check if context is cancelled
 				select {
-				case s.resultsChan <- result:
 				case <-ctx.Done():
-					return
+					break
+				default:
 				}
 
-			}(i, globalTestIndex+i)
+				wg.Add(1)
+				go func(currentGlobalIndex int) {
+					defer wg.Done()
+					runTest(step, currentGlobalIndex, semaphore, time.Time{}, nil)
+				}(globalTestIndex + i)
+			}
 		}
 		wg.Wait()
 		globalTestIndex += stepTotalTests
+
+		if step.coordinates != nil && ctx.Err() == nil {
+			stepResults := results[stepFirstResult:]
+			sweepPoints = append(sweepPoints, summarizeSweepPoint(step.coordinates, s.calculateSummary(stepResults), stepResults, time.Since(stepStart)))
+		}
+
+		if rateSweep != nil {
+			if ctx.Err() != nil {
+				// A cancelled step is incomplete and says nothing about the SLO.
+				break steps
+			}
+			stepResults := results[stepFirstResult:]
+			stepSummary := summarizeRateStep(step.requestRate, s.calculateSummary(stepResults), stepResults, time.Since(stepStart), config.SLO)
+			rateSweep.Steps = append(rateSweep.Steps, stepSummary)
+			if !stepSummary.MeetsSLO {
+				log.Printf("Rate sweep stopped at %.2f req/s: %v", step.requestRate, stepSummary.Violations)
+				break steps
+			}
+			rateSweep.Goodput = step.requestRate
+		}
 	}
 
 	endTime := time.Now().Format(time.RFC3339)
 
+	// The ticker may not fire again before it is stopped, so publish the
+	// final state explicitly; otherwise subscribers keep showing the last
+	// in-flight snapshot.
+	{
+		telemetryMu.Lock()
+		final := TelemetryUpdate{
+			Timestamp:       time.Now().UnixMilli(),
+			CompletedTests:  int(atomic.LoadInt32(&completedTests)),
+			TotalTests:      totalTests,
+			GeneratedTokens: atomic.LoadInt64(&generatedTokens),
+			StepCurrent:     int(atomic.LoadInt32(&currentStepIndex)),
+			StepTotal:       stepTotal,
+		}
+		if count := atomic.LoadInt64(&ttftCount); count > 0 {
+			final.AverageTTFT = float64(atomic.LoadInt64(&ttftSum)) / float64(count) / 1000.0
+		}
+		if len(ttftValues) > 0 {
+			final.P95TTFT = computeDistribution(ttftValues).P95
+		}
+		telemetryMu.Unlock()
+		s.events.Publish(Event{Type: EventTelemetry, BatchID: batchID, Model: config.Model, Step: final.StepCurrent, Timestamp: final.Timestamp, Telemetry: &final})
+	}
+
 	// Calculate summary
 	summary := s.calculateSummary(results)
+	var turns []TurnSummary
+	if config.TestMode == "conversation" {
+		turns = s.summarizeTurns(results)
+	}
 	roundSummaries := s.calculateRoundSummaries(results, config)
 
 	// Calculate aggregated Round stats (Total Throughput per Round)
@@ -354,21 +702,31 @@ func (s *SpeedTestService) RunSpeedTest(ctx context.Context, config TestConfigur
 		}
 	}
 
-	batch := &TestBatch{
+	batch = &TestBatch{
 		ID:             batchID,
+		Name:           opts.batchName,
+		MatrixID:       opts.matrixID,
+		ReplayOf:       opts.replayOf,
+		Seed:           config.Seed,
 		StartTime:      startTime,
 		EndTime:        endTime,
 		Configuration:  config,
 		Results:        results,
 		RoundSummaries: roundSummaries,
 		Summary:        summary,
+		RateSweep:      rateSweep,
+		SweepPoints:    sweepPoints,
+		Turns:          turns,
+
+		PromptCalibration: calibration,
 	}
 
 	return batch, nil
 }
 
-// runIndividualTest runs a single speed test
-func (s *SpeedTestService) runIndividualTest(ctx context.Context, client *OpenAIClient, config TestConfiguration, testNumber int, concurrency int, promptLength int, onToken func(string), onFirstToken func(time.Duration)) TestResult {
+// runIndividualTest runs a single speed test, building its request from
+// work as described on requestWorkload.
+func (s *SpeedTestService) runIndividualTest(ctx context.Context, client LLMBackend, config TestConfiguration, testNumber int, concurrency int, promptLength int, work requestWorkload, onToken TokenCallback, onFirstToken func(time.Duration)) TestResult {
 	if concurrency <= 0 {
 		concurrency = 1
 	}
@@ -387,19 +745,69 @@ func (s *SpeedTestService) runIndividualTest(ctx context.Context, client *OpenAI
 	}
 
 	// Generate prompt based on configuration
-	var promptContent string
-	if config.PromptType == "custom" && config.Prompt != "" {
-		promptContent = config.Prompt
+	var messages []Message
+	maxTokens := config.MaxTokens
+	var datasetPromptTokens int
+	if work.dataset != nil {
+		record := work.dataset.RecordFor(testNumber)
+		messages = record.Messages
+		datasetPromptTokens = record.PromptTokens
+		if record.OutputTokens > 0 {
+			maxTokens = record.OutputTokens
+		}
+	} else if config.PromptType == "custom" && config.Prompt != "" {
+		messages = []Message{{Role: "user", Content: config.Prompt}}
 	} else {
-		promptGen := NewPromptGenerator(config.Model)
-		promptContent = promptGen.GeneratePrompt(promptLength, config.PromptType)
+		// Each prompt is seeded by its test number and length, so it does
+		// not depend on the order concurrent requests are generated in.
+		generate := func(length int) string {
+			generator := newPromptGenerator(work.counter, deriveSeed(config.Seed, "prompt", testNumber, length))
+			if config.ExactPromptLength {
+				return generator.GenerateExactPrompt(length, config.PromptType)
+			}
+			return generator.GeneratePrompt(length, config.PromptType)
+		}
+		body := func(length int) string {
+			if work.prompts != nil {
+				return work.prompts.get(testNumber, length, func() string { return generate(length) })
+			}
+			return generate(length)
+		}
+		if work.prefix != nil {
+			messages, result.PrefixCache = work.prefix.messages(testNumber, promptLength, body)
+		} else {
+			messages = []Message{{Role: "user", Content: body(promptLength)}}
+		}
+	}
+
+	// Images are encoded before the clock starts; TTFT then covers the
+	// upload and the server's image preprocessing and vision encoding.
+	if work.vision != nil && config.VisionConfig.Count > 0 {
+		parts, resolution, err := work.vision.parts(config.VisionConfig, testNumber)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		messages = append([]Message(nil), messages...)
+		last := &messages[len(messages)-1]
+		last.Parts = append([]ContentPart{{Type: "text", Text: last.Content}}, parts...)
+		result.Images, result.ImageResolution = config.VisionConfig.Count, resolution
+	}
+
+	// A conversation turn counts as a round: round summaries then
+	// aggregate each turn over all sessions.
+	if work.conv != nil {
+		work.conv.turn++
+		result.Session, result.Turn = work.conv.session, work.conv.turn
+		result.RoundNumber, result.RoundPosition = work.conv.turn, work.conv.session
+		messages = append(append([]Message(nil), work.conv.messages...), messages...)
 	}
 
 	// Prepare request
 	request := OpenAIRequest{
 		Model:            config.Model,
-		Messages:         []Message{{Role: "user", Content: promptContent}},
-		MaxTokens:        config.MaxTokens,
+		Messages:         messages,
+		MaxTokens:        maxTokens,
 		Temperature:      config.Temperature,
 		TopP:             config.TopP,
 		PresencePenalty:  config.PresencePenalty,
@@ -409,21 +817,50 @@ func (s *SpeedTestService) runIndividualTest(ctx context.Context, client *OpenAI
 			IncludeUsage: true,
 		},
 	}
+	if len(work.tools) > 0 {
+		request.Tools = work.tools
+		request.ToolChoice = toolChoiceParam(config.ToolConfig.choice())
+	}
+	if work.structured != nil {
+		result.StructuredOutput = structuredBaseline
+		if work.structured.constrained(testNumber) {
+			result.StructuredOutput = structuredConstrained
+			work.structured.apply(&request)
+		}
+	}
+
+	// Record the arrival time of every streamed chunk so inter-token
+	// latency can be derived once the request completes.
+	var chunkTimes []time.Time
+	trackChunk := func(content string, usage *Usage) {
+		chunkTimes = append(chunkTimes, time.Now())
+		if onToken != nil {
+			onToken(content, usage)
+		}
+	}
 
 	// Execute request
 	startTime := time.Now()
-	response, latency, err := client.GenerateCompletion(ctx, request, config.Headers, onToken, onFirstToken)
+	response, latency, err := client.GenerateCompletion(ctx, request, config.Headers, trackChunk, onFirstToken)
 	if err != nil && request.Stream {
 		// Check if error is due to cancellation
 		if ctx.Err() != nil {
 			result.Error = "cancelled"
 			return result
 		}
+		if errors.Is(err, errToolCallIndex) {
+			result.ErrorKind = errorKindMalformedToolCall
+			result.Error = err.Error()
+			return result
+		}
 
 		log.Printf("Streaming completion failed, retrying without stream: %v", err)
 		request.Stream = false
 		request.StreamOptions = nil
-		// Retry without stream
+		// Retry without stream. Chunks of the failed stream say nothing
+		// about this request, and its latency starts with the retry.
+		chunkTimes = nil
+		startTime = time.Now()
 		response, latency, err = client.GenerateCompletion(ctx, request, config.Headers, nil, nil)
 	}
 	totalLatency := time.Since(startTime)
@@ -454,6 +891,38 @@ func (s *SpeedTestService) runIndividualTest(ctx context.Context, client *OpenAI
 	result.PromptTokens = response.Usage.PromptTokens
 	result.CompletionTokens = response.Usage.CompletionTokens
 	result.TotalTokens = response.Usage.TotalTokens
+	reply, reasoning := "", ""
+	var toolCalls []ToolCall
+	if len(response.Choices) > 0 {
+		reply = response.Choices[0].Message.Content
+		reasoning = response.Choices[0].Message.ReasoningContent
+		toolCalls = response.Choices[0].Message.ToolCalls
+		result.LocalCompletionTokens = work.counter.count(reply)
+	}
+	applyReasoning(&result, response, reasoning, reply, totalLatency, work.counter)
+	if result.Images > 0 && result.PromptTokens > 0 && work.vision.templateOverheadKnown {
+		textTokens := work.vision.templateOverhead
+		for _, message := range messages {
+			textTokens += work.counter.count(message.Content)
+		}
+		result.ImageTokens = max(result.PromptTokens-textTokens, 0)
+	}
+	if len(work.tools) > 0 {
+		applyToolCalls(&result, response, toolCalls, work.counter)
+	}
+	if result.StructuredOutput == structuredConstrained {
+		if err := work.structured.check(reply); err != nil {
+			result.SchemaError = err.Error()
+		}
+	}
+	if work.conv != nil {
+		work.conv.messages = append(messages, Message{Role: "assistant", Content: reply})
+	}
+	if result.PromptTokens == 0 && datasetPromptTokens > 0 {
+		// Servers that omit usage still get the record's own prompt length.
+		result.PromptTokens = datasetPromptTokens
+		result.TotalTokens = datasetPromptTokens + result.CompletionTokens
+	}
 
 	if outputLatencyMs <= 0 && (result.PromptTokens > 0 || result.CompletionTokens > 0) {
 		prefillMs, outputMs := splitLatencyByTokens(totalLatencyMs, result.PromptTokens, result.CompletionTokens)
@@ -476,9 +945,68 @@ func (s *SpeedTestService) runIndividualTest(ctx context.Context, client *OpenAI
 	// Throughput focuses on decode speed to align with third-party tooling
 	result.Throughput = result.OutputTokensPerSecond
 
+	// TPOT follows the vLLM definition: decode time spread over every
+	// token after the first. Both it and ITL need a streamed response;
+	// the decode time of a non-streamed one is only an estimate.
+	if request.Stream {
+		if result.CompletionTokens > 1 {
+			result.TimePerOutputToken = outputLatencyMs / float64(result.CompletionTokens-1)
+		}
+		applyInterTokenLatency(&result, chunkTimes)
+	}
+
 	return result
 }
 
+// applyToolCalls records the tool calls of a response. Argument tokens are
+// counted locally, so their rate is measured over the time between the
+// first and last streamed fragments. Calls that cannot be used fail the
+// request with an ErrorKind separating them from transport failures.
+func applyToolCalls(result *TestResult, response *OpenAIResponse, calls []ToolCall, counter *tokenCounter) {
+	result.ToolCalls = len(calls)
+	for _, call := range calls {
+		result.ToolCallTokens += counter.count(call.Function.Arguments)
+	}
+	result.LocalCompletionTokens += result.ToolCallTokens
+	if response.ToolCallStart > 0 {
+		result.TimeToFirstToolCall = float64(response.ToolCallStart) / float64(time.Millisecond)
+		argumentMs := float64(response.ToolCallEnd-response.ToolCallStart) / float64(time.Millisecond)
+		result.ToolCallTokensPerSecond = computeTokensPerSecond(result.ToolCallTokens, argumentMs)
+	}
+
+	if kind, message := checkToolCalls(calls, result.Configuration.ToolConfig.choice()); kind != "" {
+		result.Success = false
+		result.ErrorKind = kind
+		result.Error = message
+	}
+}
+
+// applyInterTokenLatency derives inter-token latency statistics from the
+// arrival times of streamed chunks. Gaps are measured per chunk, so servers
+// that batch several tokens into one event report fewer, longer gaps.
+func applyInterTokenLatency(result *TestResult, chunkTimes []time.Time) {
+	if len(chunkTimes) < 2 {
+		return
+	}
+
+	gaps := make([]float64, 0, len(chunkTimes)-1)
+	var sum float64
+	for i := 1; i < len(chunkTimes); i++ {
+		gap := float64(chunkTimes[i].Sub(chunkTimes[i-1])) / float64(time.Millisecond)
+		gaps = append(gaps, gap)
+		sum += gap
+		if gap > result.MaxStall {
+			result.MaxStall = gap
+		}
+	}
+
+	stats := computeDistribution(gaps)
+	result.InterTokenLatency = sum / float64(len(gaps))
+	result.InterTokenLatencyP50 = stats.P50
+	result.InterTokenLatencyP99 = stats.P99
+	result.interTokenGaps = gaps
+}
+
 func computeTokensPerSecond(tokens int, durationMs float64) float64 {
 	if tokens <= 0 || durationMs <= 0 {
 		return 0
@@ -561,9 +1089,36 @@ func (s *SpeedTestService) calculateSummary(results []TestResult) TestSummary {
 	validPrefillTPS := 0
 	validOutputTPS := 0
 
+	var latencySamples, prefillSamples, outputLatencySamples, outputTPSSamples []float64
+	var tpotSamples, interTokenGaps, queueDelaySamples []float64
+	var totalInterTokenLatency float64
+	interTokenSamples := 0
+	var totalUsageDeviation float64
+	usageSamples := 0
+
 	for _, result := range results {
 		if result.Success {
 			summary.SuccessfulTests++
+			latencySamples = append(latencySamples, result.TotalLatency)
+			prefillSamples = append(prefillSamples, result.RequestLatency)
+			outputLatencySamples = append(outputLatencySamples, result.OutputLatency)
+			if result.TimePerOutputToken > 0 {
+				tpotSamples = append(tpotSamples, result.TimePerOutputToken)
+			}
+			if result.InterTokenLatency > 0 {
+				totalInterTokenLatency += result.InterTokenLatency
+				interTokenSamples++
+			}
+			interTokenGaps = append(interTokenGaps, result.interTokenGaps...)
+			queueDelaySamples = append(queueDelaySamples, result.QueueDelay)
+			if result.CompletionTokens > 0 && result.LocalCompletionTokens > 0 {
+				local := float64(result.LocalCompletionTokens)
+				totalUsageDeviation += (float64(result.CompletionTokens) - local) / local
+				usageSamples++
+			}
+			if result.MaxStall > summary.MaxStall {
+				summary.MaxStall = result.MaxStall
+			}
 			totalLatency += result.TotalLatency
 			totalPrefillLatency += result.RequestLatency
 			totalOutputLatency += result.OutputLatency
@@ -606,6 +1161,7 @@ func (s *SpeedTestService) calculateSummary(results []TestResult) TestSummary {
 			}
 
 			if result.OutputTokensPerSecond > 0 {
+				outputTPSSamples = append(outputTPSSamples, result.OutputTokensPerSecond)
 				totalOutputTokensPerSecond += result.OutputTokensPerSecond
 				validOutputTPS++
 				if result.OutputTokensPerSecond < minOutputTokensPerSecond {
@@ -620,6 +1176,12 @@ func (s *SpeedTestService) calculateSummary(results []TestResult) TestSummary {
 		}
 	}
 
+	summary.PrefixCache = summarizePrefixCache(results)
+	summary.ToolCalls = summarizeToolCalls(results)
+	summary.StructuredOutput = summarizeStructuredOutput(results)
+	summary.Reasoning = summarizeReasoning(results)
+	summary.Vision = summarizeVision(results)
+
 	if summary.SuccessfulTests > 0 {
 		count := float64(summary.SuccessfulTests)
 		summary.AverageLatency = totalLatency / count
@@ -634,6 +1196,36 @@ func (s *SpeedTestService) calculateSummary(results []TestResult) TestSummary {
 		summary.AverageThroughput = totalThroughput / count
 		summary.MinThroughput = minThroughput
 		summary.MaxThroughput = maxThroughput
+		summary.LatencyDistribution = computeDistribution(latencySamples)
+		summary.PrefillLatencyDistribution = computeDistribution(prefillSamples)
+		summary.OutputLatencyDistribution = computeDistribution(outputLatencySamples)
+		summary.OutputTokensPerSecondDistribution = computeDistribution(outputTPSSamples)
+
+		if len(tpotSamples) > 0 {
+			var totalTPOT float64
+			for _, v := range tpotSamples {
+				totalTPOT += v
+			}
+			summary.AverageTimePerOutputToken = totalTPOT / float64(len(tpotSamples))
+			summary.TimePerOutputTokenDistribution = computeDistribution(tpotSamples)
+		}
+		if interTokenSamples > 0 {
+			summary.AverageInterTokenLatency = totalInterTokenLatency / float64(interTokenSamples)
+		}
+		// ITL percentiles are pooled over every chunk gap in the batch,
+		// matching how vLLM and GenAI-Perf report them.
+		summary.InterTokenLatencyDistribution = computeDistribution(interTokenGaps)
+
+		var totalQueueDelay float64
+		for _, v := range queueDelaySamples {
+			totalQueueDelay += v
+		}
+		summary.AverageQueueDelay = totalQueueDelay / count
+		summary.QueueDelayDistribution = computeDistribution(queueDelaySamples)
+
+		if usageSamples > 0 {
+			summary.UsageDeviation = totalUsageDeviation / float64(usageSamples)
+		}
 
 		if validPrefillTPS > 0 {
 			summary.AveragePrefillTokensPerSecond = totalPrefillTokensPerSecond / float64(validPrefillTPS)
@@ -646,10 +1238,10 @@ func (s *SpeedTestService) calculateSummary(results []TestResult) TestSummary {
 			summary.MinOutputTokensPerSecond = minOutputTokensPerSecond
 			summary.MaxOutputTokensPerSecond = maxOutputTokensPerSecond
 		}
-
-		summary.ErrorRate = float64(summary.FailedTests) / float64(summary.TotalTests)
 	}
 
+	summary.ErrorRate = float64(summary.FailedTests) / float64(summary.TotalTests)
+
 	return summary
 }
 
@@ -671,6 +1263,10 @@ func (s *SpeedTestService) calculateRoundSummaries(results []TestResult, config
 		totalOutputTPS        float64
 		prefillSamples        int
 		outputSamples         int
+		latencyValues         []float64
+		prefillValues         []float64
+		outputLatencyValues   []float64
+		outputTPSValues       []float64
 	}
 
 	accumulators := make(map[int]*roundAccumulator)
@@ -729,6 +1325,9 @@ func (s *SpeedTestService) calculateRoundSummaries(results []TestResult, config
 			acc.totalPrefillLatency += result.RequestLatency
 			acc.totalOutputLatency += result.OutputLatency
 			acc.totalLatency += result.TotalLatency
+			acc.latencyValues = append(acc.latencyValues, result.TotalLatency)
+			acc.prefillValues = append(acc.prefillValues, result.RequestLatency)
+			acc.outputLatencyValues = append(acc.outputLatencyValues, result.OutputLatency)
 			if result.PrefillTokensPerSecond > 0 {
 				acc.totalPrefillTPS += result.PrefillTokensPerSecond
 				acc.prefillSamples++
@@ -738,6 +1337,7 @@ func (s *SpeedTestService) calculateRoundSummaries(results []TestResult, config
 			if result.OutputTokensPerSecond > 0 {
 				acc.totalOutputTPS += result.OutputTokensPerSecond
 				acc.outputSamples++
+				acc.outputTPSValues = append(acc.outputTPSValues, result.OutputTokensPerSecond)
 			}
 			acc.summary.TotalOutputTokensPerSecond += result.OutputTokensPerSecond
 		} else {
@@ -775,6 +1375,10 @@ func (s *SpeedTestService) calculateRoundSummaries(results []TestResult, config
 			if acc.outputSamples > 0 {
 				acc.summary.AverageOutputTokensPerSecond = acc.totalOutputTPS / float64(acc.outputSamples)
 			}
+			acc.summary.TotalLatencyDistribution = computeDistribution(acc.latencyValues)
+			acc.summary.PrefillLatencyDistribution = computeDistribution(acc.prefillValues)
+			acc.summary.OutputLatencyDistribution = computeDistribution(acc.outputLatencyValues)
+			acc.summary.OutputTokensPerSecondDistribution = computeDistribution(acc.outputTPSValues)
 		}
 		rounds = append(rounds, acc.summary)
 	}
@@ -795,6 +1399,8 @@ func (s *SpeedTestService) CompareBatches(batches []TestBatch) (*ComparisonResul
 	bestThroughputBatch := batches[0]
 	bestRoundThroughputBatch := batches[0]
 	lowestErrorRateBatch := batches[0]
+	bestP95TTFTBatch := batches[0]
+	bestP95LatencyBatch := batches[0]
 
 	for _, batch := range batches {
 		if batch.Summary.AverageLatency < bestLatencyBatch.Summary.AverageLatency {
@@ -809,12 +1415,20 @@ func (s *SpeedTestService) CompareBatches(batches []TestBatch) (*ComparisonResul
 		if batch.Summary.ErrorRate < lowestErrorRateBatch.Summary.ErrorRate {
 			lowestErrorRateBatch = batch
 		}
+		if batch.Summary.PrefillLatencyDistribution.P95 < bestP95TTFTBatch.Summary.PrefillLatencyDistribution.P95 {
+			bestP95TTFTBatch = batch
+		}
+		if batch.Summary.LatencyDistribution.P95 < bestP95LatencyBatch.Summary.LatencyDistribution.P95 {
+			bestP95LatencyBatch = batch
+		}
 	}
 
 	comparison.BestLatencyBatchID = bestLatencyBatch.ID
 	comparison.BestThroughputBatchID = bestThroughputBatch.ID
 	comparison.BestRoundThroughputBatchID = bestRoundThroughputBatch.ID
 	comparison.LowestErrorRateBatchID = lowestErrorRateBatch.ID
+	comparison.BestP95TTFTBatchID = bestP95TTFTBatch.ID
+	comparison.BestP95LatencyBatchID = bestP95LatencyBatch.ID
 
 	return &ComparisonResult{
 		Batches:    batches,
@@ -822,9 +1436,7 @@ func (s *SpeedTestService) CompareBatches(batches []TestBatch) (*ComparisonResul
 	}, nil
 }
 
-// Close closes the service channels
+// Close ends every event subscription.
 func (s *SpeedTestService) Close() {
-	close(s.progressChan)
-	close(s.resultsChan)
-	close(s.telemetryChan)
+	s.events.Close()
 }
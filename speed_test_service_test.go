@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApplyInterTokenLatency(t *testing.T) {
+	start := time.Now()
+	chunkTimes := []time.Time{
+		start,
+		start.Add(10 * time.Millisecond),
+		start.Add(20 * time.Millisecond),
+		start.Add(70 * time.Millisecond),
+	}
+
+	var result TestResult
+	applyInterTokenLatency(&result, chunkTimes)
+
+	if result.MaxStall != 50 {
+		t.Fatalf("expected a 50ms max stall, got %f", result.MaxStall)
+	}
+	if result.InterTokenLatency < 23.3 || result.InterTokenLatency > 23.4 {
+		t.Fatalf("expected mean ITL of ~23.33ms, got %f", result.InterTokenLatency)
+	}
+	if result.InterTokenLatencyP50 != 10 {
+		t.Fatalf("expected p50 ITL of 10ms, got %f", result.InterTokenLatencyP50)
+	}
+	if len(result.interTokenGaps) != 3 {
+		t.Fatalf("expected 3 gaps, got %d", len(result.interTokenGaps))
+	}
+
+	summary := NewSpeedTestService().calculateSummary([]TestResult{
+		{Success: true, InterTokenLatency: result.InterTokenLatency, MaxStall: result.MaxStall, interTokenGaps: result.interTokenGaps},
+		{Success: true, InterTokenLatency: 5, MaxStall: 5, interTokenGaps: []float64{5}},
+	})
+	if summary.MaxStall != 50 {
+		t.Fatalf("expected batch max stall of 50ms, got %f", summary.MaxStall)
+	}
+	if summary.InterTokenLatencyDistribution.P50 != 10 {
+		t.Fatalf("expected pooled p50 ITL of 10ms, got %f", summary.InterTokenLatencyDistribution.P50)
+	}
+}
+
+func TestStreamedTokensUseContinuousUsage(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{ReportUsage: true, ContinuousUsage: true, Seed: 1}))
+	defer server.Close()
+
+	service := NewSpeedTestService()
+	sub := service.Events().Subscribe("usage-batch", 0)
+
+	config := mockTestConfiguration(server.URL + "/v1")
+	config.APIKey = ""
+	batch, err := service.RunSpeedTest(context.Background(), config, "usage-batch")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	var generated int64
+	for event := range sub.Events() {
+		if event.Telemetry != nil {
+			generated = event.Telemetry.GeneratedTokens
+		}
+	}
+	// 4 requests of 8 tokens each, counted from the per-chunk usage
+	// rather than estimated from the text.
+	if generated != 32 {
+		t.Fatalf("expected 32 streamed tokens, got %d", generated)
+	}
+	for _, result := range batch.Results {
+		if result.LocalCompletionTokens == 0 {
+			t.Fatalf("result %d has no locally counted completion tokens", result.TestNumber)
+		}
+	}
+}
+
+func TestSummaryUsageDeviation(t *testing.T) {
+	summary := NewSpeedTestService().calculateSummary([]TestResult{
+		{Success: true, CompletionTokens: 120, LocalCompletionTokens: 100},
+		{Success: true, CompletionTokens: 100, LocalCompletionTokens: 100},
+		{Success: true, CompletionTokens: 0, LocalCompletionTokens: 50}, // no usage reported
+		{Success: false, CompletionTokens: 10, LocalCompletionTokens: 1},
+	})
+	if math.Abs(summary.UsageDeviation-0.10) > 1e-9 {
+		t.Fatalf("expected a +10%% usage deviation, got %g", summary.UsageDeviation)
+	}
+}
+
+func TestTokenCounterFallsBackToEstimate(t *testing.T) {
+	var counter *tokenCounter
+	text := "one two three, four."
+	if got, want := counter.count(text), EstimateActualTokens(text); got != want {
+		t.Fatalf("expected the estimate %d without a tokenizer, got %d", want, got)
+	}
+}
+
+func TestSeedMakesPromptsReproducible(t *testing.T) {
+	mock := NewMockServer(MockServerConfig{TTFT: time.Millisecond, TokensPerSecond: 2000, ReportUsage: true, Seed: 1})
+	var mu sync.Mutex
+	var prompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var request OpenAIRequest
+		if json.Unmarshal(body, &request) == nil && len(request.Messages) > 0 {
+			mu.Lock()
+			prompts = append(prompts, request.Messages[0].Content)
+			mu.Unlock()
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		mock.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	run := func(seed int64) (*TestBatch, []string) {
+		prompts = nil
+		config := mockTestConfiguration(server.URL + "/v1")
+		config.PromptType = "fixed"
+		config.PromptLength = 64
+		config.Seed = seed
+		batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), config, "")
+		if err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		// Concurrent requests arrive in any order.
+		sent := append([]string(nil), prompts...)
+		sort.Strings(sent)
+		return batch, sent
+	}
+
+	batch, first := run(42)
+	if batch.Seed != 42 || batch.Configuration.Seed != 42 {
+		t.Fatalf("expected the batch to record seed 42, got %d", batch.Seed)
+	}
+	if _, again := run(42); !reflect.DeepEqual(first, again) {
+		t.Fatalf("runs with the same seed sent different prompts")
+	}
+	if _, other := run(43); reflect.DeepEqual(first, other) {
+		t.Fatalf("runs with different seeds sent the same prompts")
+	}
+	if batch, _ := run(0); batch.Seed == 0 {
+		t.Fatalf("an unset seed must be resolved and recorded")
+	}
+}
+
+func TestStreamFallbackSkipsInterTokenLatency(t *testing.T) {
+	// Streams break after a few chunks; the retry without streaming succeeds.
+	mock := NewMockServer(MockServerConfig{TTFT: time.Millisecond, ReportUsage: true, Seed: 1})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var request OpenAIRequest
+		if json.Unmarshal(body, &request) == nil && request.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 3; i++ {
+				io.WriteString(w, `data: {"choices":[{"delta":{"content":"tok "}}]}`+"\n\n")
+				w.(http.Flusher).Flush()
+				time.Sleep(5 * time.Millisecond)
+			}
+			io.WriteString(w, "data: {broken\n\n")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		mock.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), mockTestConfiguration(server.URL+"/v1"), "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	for _, result := range batch.Results {
+		if !result.Success {
+			t.Fatalf("result %d failed: %s", result.TestNumber, result.Error)
+		}
+		if result.InterTokenLatency != 0 || result.MaxStall != 0 || result.TimePerOutputToken != 0 {
+			t.Fatalf("result %d: a non-streamed retry must not report ITL or TPOT, got %+v", result.TestNumber, result)
+		}
+	}
+}
+
+func TestInterTokenLatencyCountsEventsNotParts(t *testing.T) {
+	// Every event carries reasoning, answer text and two tool call
+	// fragments; each is still one chunk arrival.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 4; i++ {
+			io.WriteString(w, `data: {"choices":[{"delta":{"reasoning_content":"r ","content":"a ","tool_calls":[`+
+				`{"index":0,"function":{"arguments":"x"}},{"index":1,"function":{"arguments":"y"}}]}}]}`+"\n\n")
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+		io.WriteString(w, `data: {"choices":[],"usage":{"prompt_tokens":4,"completion_tokens":8,"total_tokens":12}}`+"\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), mockTestConfiguration(server.URL+"/v1"), "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	for _, result := range batch.Results {
+		if len(result.interTokenGaps) != 3 || result.InterTokenLatencyP50 < 4 {
+			t.Fatalf("result %d: expected 3 gaps of about 5 ms, got %v", result.TestNumber, result.interTokenGaps)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// DistributionStats describes the spread of a per-request metric.
+// Percentiles use linear interpolation between closest ranks, the same
+// definition numpy (and therefore vLLM's benchmark scripts) uses.
+type DistributionStats struct {
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	StdDev float64 `json:"stdDev"` // population standard deviation
+}
+
+// computeDistribution returns percentile and standard deviation statistics
+// for values. The input slice is not modified.
+func computeDistribution(values []float64) DistributionStats {
+	if len(values) == 0 {
+		return DistributionStats{}
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	return DistributionStats{
+		P50:    percentile(sorted, 50),
+		P90:    percentile(sorted, 90),
+		P95:    percentile(sorted, 95),
+		P99:    percentile(sorted, 99),
+		StdDev: standardDeviation(sorted),
+	}
+}
+
+// percentile returns the p-th percentile (0-100) of an ascending slice.
+func percentile(sorted []float64, p float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n == 1 || p <= 0 {
+		return sorted[0]
+	}
+	if p >= 100 {
+		return sorted[n-1]
+	}
+
+	rank := p / 100 * float64(n-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+
+	weight := rank - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[upper]*weight
+}
+
+// mean returns the arithmetic mean of values, or 0 when there are none.
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func standardDeviation(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+
+	avg := mean(values)
+	var squares float64
+	for _, v := range values {
+		diff := v - avg
+		squares += diff * diff
+	}
+
+	return math.Sqrt(squares / float64(len(values)))
+}
+
+// mannWhitneyGreater returns the one-sided p-value of a Mann-Whitney U test
+// for the hypothesis that values in x tend to be larger than values in y.
+// It uses the normal approximation with tie and continuity corrections,
+// which is adequate from roughly ten samples per side.
+func mannWhitneyGreater(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type sample struct {
+		value float64
+		fromX bool
+	}
+	combined := make([]sample, 0, n1+n2)
+	for _, v := range x {
+		combined = append(combined, sample{v, true})
+	}
+	for _, v := range y {
+		combined = append(combined, sample{v, false})
+	}
+	sort.Slice(combined, func(i, j int) bool { return combined[i].value < combined[j].value })
+
+	// Tied values share the average of the ranks they span.
+	n := len(combined)
+	var rankSumX, tieTerm float64
+	for i := 0; i < n; {
+		j := i
+		for j < n && combined[j].value == combined[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if combined[k].fromX {
+				rankSumX += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			tieTerm += t*t*t - t
+		}
+		i = j
+	}
+
+	u := rankSumX - float64(n1*(n1+1))/2
+	mean := float64(n1*n2) / 2
+	variance := float64(n1*n2) / 12 * (float64(n+1) - tieTerm/float64(n*(n-1)))
+	if variance <= 0 {
+		return 1
+	}
+
+	z := (u - mean - 0.5) / math.Sqrt(variance)
+	return 0.5 * math.Erfc(z/math.Sqrt2)
+}
+
+// twoProportionGreater returns the one-sided p-value of a pooled
+// two-proportion z-test that the rate a/n1 is higher than b/n2.
+func twoProportionGreater(a, n1, b, n2 int) float64 {
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	p1 := float64(a) / float64(n1)
+	p2 := float64(b) / float64(n2)
+	pooled := float64(a+b) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 1
+	}
+
+	return 0.5 * math.Erfc((p1-p2)/se/math.Sqrt2)
+}
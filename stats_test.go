@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestComputeDistribution(t *testing.T) {
+	values := make([]float64, 0, 101)
+	for i := 100; i >= 0; i-- {
+		values = append(values, float64(i))
+	}
+
+	stats := computeDistribution(values)
+	if stats.P50 != 50 || stats.P90 != 90 || stats.P95 != 95 || stats.P99 != 99 {
+		t.Fatalf("unexpected percentiles: %+v", stats)
+	}
+	if math.Abs(stats.StdDev-29.1547) > 0.001 {
+		t.Fatalf("unexpected standard deviation: %f", stats.StdDev)
+	}
+	if values[0] != 100 {
+		t.Fatalf("computeDistribution must not reorder its input")
+	}
+}
+
+func TestPercentileInterpolates(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40}
+	if got := percentile(sorted, 50); got != 25 {
+		t.Fatalf("expected p50 of %v to be 25, got %f", sorted, got)
+	}
+	if got := percentile(sorted, 99); math.Abs(got-39.7) > 1e-9 {
+		t.Fatalf("expected p99 of %v to be 39.7, got %f", sorted, got)
+	}
+	if got := computeDistribution(nil); got != (DistributionStats{}) {
+		t.Fatalf("expected empty input to yield zero stats, got %+v", got)
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Values of StructuredOutputConfiguration.Method.
+const (
+	structuredMethodResponseFormat = "response_format"
+	structuredMethodGuidedJSON     = "guided_json"
+)
+
+// Values of TestResult.StructuredOutput.
+const (
+	structuredConstrained = "constrained"
+	structuredBaseline    = "baseline"
+)
+
+// enabled reports whether requests are constrained to a schema.
+func (sc StructuredOutputConfiguration) enabled() bool {
+	return sc.SchemaPath != ""
+}
+
+// method returns the configured way of sending the schema.
+func (sc StructuredOutputConfiguration) method() string {
+	if sc.Method == "" {
+		return structuredMethodResponseFormat
+	}
+	return sc.Method
+}
+
+// structuredOutput is the schema of a structured output test, loaded once
+// per batch. Requests alternate between constrained and unconstrained, so
+// both halves run under the same load.
+type structuredOutput struct {
+	method string
+	raw    json.RawMessage
+	schema *jsonSchema
+}
+
+// loadStructuredOutput reads the schema of config, or returns nil when
+// requests are unconstrained. It also checks everything else structured
+// output tests depend on.
+func loadStructuredOutput(config TestConfiguration) (*structuredOutput, error) {
+	sc := config.StructuredOutput
+	if !sc.enabled() {
+		return nil, nil
+	}
+	if p := strings.ToLower(config.Provider); p != "" && p != providerOpenAI {
+		return nil, fmt.Errorf("structured output needs the openai provider, got %s", config.Provider)
+	}
+	if config.TestMode == "conversation" {
+		return nil, fmt.Errorf("structured output cannot be used in conversation mode")
+	}
+	if config.ToolConfig.enabled() {
+		return nil, fmt.Errorf("structured output cannot be combined with tool calling")
+	}
+	switch method := sc.method(); method {
+	case structuredMethodResponseFormat, structuredMethodGuidedJSON:
+	default:
+		return nil, fmt.Errorf("unsupported structured output method: %s", method)
+	}
+
+	data, err := os.ReadFile(sc.SchemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON schema: %w", err)
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON schema %s: %w", sc.SchemaPath, err)
+	}
+	return &structuredOutput{method: sc.method(), raw: json.RawMessage(data), schema: &schema}, nil
+}
+
+// constrained reports whether request testNumber is constrained to the
+// schema. Odd test numbers are, even ones form the baseline; the warm-up
+// request is constrained so the server compiles the grammar before
+// measured requests start.
+func (so *structuredOutput) constrained(testNumber int) bool {
+	return testNumber%2 != 0 || testNumber == 0
+}
+
+// apply constrains request to the schema.
+func (so *structuredOutput) apply(request *OpenAIRequest) {
+	if so.method == structuredMethodGuidedJSON {
+		request.GuidedJSON = so.raw
+		return
+	}
+	request.ResponseFormat = &ResponseFormat{
+		Type:       "json_schema",
+		JSONSchema: &JSONSchemaFormat{Name: "benchmark_output", Schema: so.raw},
+	}
+}
+
+// check validates the output of a constrained request against the schema.
+func (so *structuredOutput) check(output string) error {
+	var value interface{}
+	if err := json.Unmarshal([]byte(output), &value); err != nil {
+		return fmt.Errorf("output is not valid JSON: %v", err)
+	}
+	return so.schema.validate("$", value)
+}
+
+// jsonSchema is the subset of JSON Schema output is checked against: types,
+// object properties, arrays, enums, combinators and the common bounds.
+// Other keywords, including $ref, are accepted but not enforced.
+type jsonSchema struct {
+	Type                 interface{}            `json:"type"` // A type name or a list of them
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	Enum                 []interface{}          `json:"enum"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+	OneOf                []*jsonSchema          `json:"oneOf"`
+	AllOf                []*jsonSchema          `json:"allOf"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+}
+
+// types returns the type names the schema allows, or nil for any type.
+func (s *jsonSchema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var names []string
+		for _, name := range t {
+			if name, ok := name.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// jsonType returns the JSON Schema type name of a decoded value.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+// validate checks value against the schema; path locates value in the
+// output for error messages.
+func (s *jsonSchema) validate(path string, value interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	if types := s.types(); len(types) > 0 {
+		actual := jsonType(value)
+		ok := false
+		for _, t := range types {
+			ok = ok || t == actual || (t == "number" && actual == "integer")
+		}
+		if !ok {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), actual)
+		}
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			found = found || reflect.DeepEqual(allowed, value)
+		}
+		if !found {
+			return fmt.Errorf("%s: value is not one of the enum values", path)
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		if err := sub.validate(path, value); err != nil {
+			return err
+		}
+	}
+	if len(s.AnyOf) > 0 && s.matching(s.AnyOf, path, value) == 0 {
+		return fmt.Errorf("%s: value matches none of anyOf", path)
+	}
+	if len(s.OneOf) > 0 && s.matching(s.OneOf, path, value) != 1 {
+		return fmt.Errorf("%s: value must match exactly one of oneOf", path)
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("%s: %g is below the minimum %g", path, v, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s: %g is above the maximum %g", path, v, *s.Maximum)
+		}
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			return fmt.Errorf("%s: string is shorter than %d characters", path, *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return fmt.Errorf("%s: string is longer than %d characters", path, *s.MaxLength)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return fmt.Errorf("%s: array has fewer than %d items", path, *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			return fmt.Errorf("%s: array has more than %d items", path, *s.MaxItems)
+		}
+		for i, item := range v {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		// Sorted so the first error reported does not depend on map order.
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, known := s.Properties[name]
+			if !known {
+				if err := s.validateAdditional(path+"."+name, v[name]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := property.validate(path+"."+name, v[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matching counts the schemas value is valid against.
+func (s *jsonSchema) matching(schemas []*jsonSchema, path string, value interface{}) int {
+	n := 0
+	for _, sub := range schemas {
+		if sub.validate(path, value) == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// validateAdditional checks a property the schema does not list, against
+// additionalProperties: false rejects it, a schema must match it.
+func (s *jsonSchema) validateAdditional(path string, value interface{}) error {
+	if len(s.AdditionalProperties) == 0 {
+		return nil
+	}
+	var allowed bool
+	if json.Unmarshal(s.AdditionalProperties, &allowed) == nil {
+		if !allowed {
+			return fmt.Errorf("%s: additional property not allowed", path)
+		}
+		return nil
+	}
+	var schema jsonSchema
+	if err := json.Unmarshal(s.AdditionalProperties, &schema); err != nil {
+		return nil
+	}
+	return schema.validate(path, value)
+}
+
+// summarizeStructuredOutput compares the decode rate of constrained and
+// baseline requests over the successful results, or returns nil when no
+// result was part of a structured output test.
+func summarizeStructuredOutput(results []TestResult) *StructuredOutputSummary {
+	var constrainedTPS, baselineTPS, constrainedTTFT, baselineTTFT []float64
+	compliant := 0
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		switch result.StructuredOutput {
+		case structuredConstrained:
+			constrainedTPS = append(constrainedTPS, result.OutputTokensPerSecond)
+			constrainedTTFT = append(constrainedTTFT, result.RequestLatency)
+			if result.SchemaError == "" {
+				compliant++
+			}
+		case structuredBaseline:
+			baselineTPS = append(baselineTPS, result.OutputTokensPerSecond)
+			baselineTTFT = append(baselineTTFT, result.RequestLatency)
+		}
+	}
+	if len(constrainedTPS) == 0 && len(baselineTPS) == 0 {
+		return nil
+	}
+
+	summary := &StructuredOutputSummary{
+		ConstrainedRequests:         len(constrainedTPS),
+		BaselineRequests:            len(baselineTPS),
+		CompliantRequests:           compliant,
+		AverageConstrainedTPS:       mean(constrainedTPS),
+		AverageBaselineTPS:          mean(baselineTPS),
+		ConstrainedTPSDistribution:  computeDistribution(constrainedTPS),
+		BaselineTPSDistribution:     computeDistribution(baselineTPS),
+		ConstrainedTTFTDistribution: computeDistribution(constrainedTTFT),
+		BaselineTTFTDistribution:    computeDistribution(baselineTTFT),
+	}
+	if len(constrainedTPS) > 0 {
+		summary.ComplianceRate = float64(compliant) / float64(len(constrainedTPS))
+	}
+	if summary.BaselineTPSDistribution.P50 > 0 && len(constrainedTPS) > 0 {
+		summary.DecodeOverhead = 1 - summary.ConstrainedTPSDistribution.P50/summary.BaselineTPSDistribution.P50
+	}
+	return summary
+}
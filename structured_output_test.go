@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"age": {"type": "integer", "minimum": 0},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"status": {"enum": ["active", "inactive"]}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": false
+}`
+
+func TestSchemaValidation(t *testing.T) {
+	var schema jsonSchema
+	if err := json.Unmarshal([]byte(testSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+	so := &structuredOutput{schema: &schema}
+
+	cases := []struct {
+		output  string
+		wantErr string
+	}{
+		{`{"name": "a", "age": 3}`, ""},
+		{`{"name": "a", "age": 3, "tags": ["x"], "status": "active"}`, ""},
+		{`{"name": "a"`, "not valid JSON"},
+		{`{"name": "a"}`, `missing required property "age"`},
+		{`{"name": "a", "age": 3.5}`, "$.age: expected integer, got number"},
+		{`{"name": "a", "age": -1}`, "below the minimum"},
+		{`{"name": "a", "age": 3, "tags": ["x", "y", "z"]}`, "more than 2 items"},
+		{`{"name": "a", "age": 3, "tags": [1]}`, "$.tags[0]: expected string"},
+		{`{"name": "a", "age": 3, "status": "gone"}`, "enum"},
+		{`{"name": "a", "age": 3, "extra": true}`, "additional property"},
+	}
+	for _, tc := range cases {
+		err := so.check(tc.output)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tc.output, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.output, tc.wantErr, err)
+		}
+	}
+}
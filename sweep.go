@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSweepPoints bounds the cartesian product of a sweep so a typo in a
+// range cannot queue millions of requests.
+const maxSweepPoints = 1000
+
+// sweepFixedFields are numeric fields that are read once per batch, so
+// changing them between points would have no effect: the seed, and the
+// settings of the tool, prefix and conversation workloads.
+var sweepFixedFields = []string{"timeout", "stepConfig", "rateConfig", "slo", "datasetConfig",
+	"seed", "toolConfig", "prefixConfig", "conversationConfig"}
+
+// sweepPoint is one combination of swept values and the configuration it
+// runs with.
+type sweepPoint struct {
+	config      TestConfiguration
+	coordinates map[string]float64
+}
+
+// stepModes maps each step mode to the field it steps and the name used
+// in errors. A step mode is a one-dimensional sweep over that field.
+var stepModes = map[string]struct{ field, kind string }{
+	"concurrency_step":      {"concurrentTests", "concurrency"},
+	"input_step":            {"promptLength", "input"},
+	"image_count_step":      {"visionConfig.count", "image count"},
+	"image_resolution_step": {"visionConfig.resolution", "image resolution"},
+}
+
+// sweepDimensionsFor returns the dimensions a step or sweep test varies, or
+// nil for modes that run a single configuration. image_count_step may start
+// at 0 so the first point is a text-only baseline.
+func sweepDimensionsFor(config TestConfiguration) ([]SweepDimension, error) {
+	if mode, ok := stepModes[config.TestMode]; ok {
+		sc := config.StepConfig
+		minStart := 1
+		if config.TestMode == "image_count_step" {
+			minStart = 0
+		}
+		if sc.Start < minStart || sc.Step <= 0 || sc.End < sc.Start {
+			return nil, fmt.Errorf("invalid step configuration for %s: start=%d, end=%d, step=%d",
+				mode.kind, sc.Start, sc.End, sc.Step)
+		}
+		return []SweepDimension{{Field: mode.field, Start: float64(sc.Start), End: float64(sc.End), Step: float64(sc.Step)}}, nil
+	}
+
+	switch config.TestMode {
+	case "sweep":
+		if len(config.Sweep) == 0 {
+			return nil, fmt.Errorf("sweep mode needs at least one dimension")
+		}
+		return config.Sweep, nil
+	}
+	return nil, nil
+}
+
+// validateSweep checks that every dimension names a distinct sweepable
+// field and yields at least one value, and that the product stays within
+// maxSweepPoints.
+func validateSweep(config TestConfiguration, dims []SweepDimension) error {
+	seen := make(map[string]bool)
+	points := 1
+	for _, dim := range dims {
+		if dim.Field == "" {
+			return fmt.Errorf("sweep dimension has no field")
+		}
+		if seen[dim.Field] {
+			return fmt.Errorf("sweep field %s is listed twice", dim.Field)
+		}
+		seen[dim.Field] = true
+
+		for _, fixed := range sweepFixedFields {
+			if dim.Field == fixed || strings.HasPrefix(dim.Field, fixed+".") {
+				return fmt.Errorf("sweep field %s is fixed for the whole batch and cannot be swept", dim.Field)
+			}
+		}
+		if dim.Field == "promptLength" && config.PromptType == "dataset" {
+			return fmt.Errorf("promptLength cannot be swept with dataset prompts")
+		}
+
+		values, err := sweepValues(dim)
+		if err != nil {
+			return err
+		}
+		// Catches unknown and non-numeric fields before any request is sent.
+		probe := config
+		if err := setConfigField(&probe, dim.Field, values[0]); err != nil {
+			return err
+		}
+
+		points *= len(values)
+		if points > maxSweepPoints {
+			return fmt.Errorf("sweep has more than %d points", maxSweepPoints)
+		}
+	}
+	return nil
+}
+
+// sweepValues expands a dimension into its values. Range values are
+// computed from the step index so float error does not accumulate.
+func sweepValues(dim SweepDimension) ([]float64, error) {
+	if len(dim.Values) > 0 {
+		return dim.Values, nil
+	}
+	if dim.Step <= 0 || dim.End < dim.Start {
+		return nil, fmt.Errorf("invalid sweep range for %s: start=%g, end=%g, step=%g",
+			dim.Field, dim.Start, dim.End, dim.Step)
+	}
+
+	const epsilon = 1e-9
+	var values []float64
+	for i := 0; ; i++ {
+		value := dim.Start + float64(i)*dim.Step
+		if value > dim.End+epsilon {
+			break
+		}
+		values = append(values, value)
+		if len(values) > maxSweepPoints {
+			return nil, fmt.Errorf("sweep has more than %d points", maxSweepPoints)
+		}
+	}
+	return values, nil
+}
+
+// buildSweepPoints returns the cartesian product of dims applied to config.
+// The first dimension varies slowest, so points run in the order a nested
+// loop over the dimensions would visit them.
+func buildSweepPoints(config TestConfiguration, dims []SweepDimension) ([]sweepPoint, error) {
+	if err := validateSweep(config, dims); err != nil {
+		return nil, err
+	}
+
+	points := []sweepPoint{{config: config, coordinates: map[string]float64{}}}
+	for _, dim := range dims {
+		values, err := sweepValues(dim)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make([]sweepPoint, 0, len(points)*len(values))
+		for _, point := range points {
+			for _, value := range values {
+				p := sweepPoint{config: point.config, coordinates: make(map[string]float64, len(dims))}
+				for field, v := range point.coordinates {
+					p.coordinates[field] = v
+				}
+				if err := setConfigField(&p.config, dim.Field, value); err != nil {
+					return nil, err
+				}
+				p.coordinates[dim.Field] = value
+				next = append(next, p)
+			}
+		}
+		points = next
+	}
+
+	for _, point := range points {
+		if err := validateSweepPointConfig(point.config); err != nil {
+			return nil, fmt.Errorf("sweep point %s: %w", formatSweepCoordinates(point.coordinates), err)
+		}
+	}
+	return points, nil
+}
+
+// validateSweepPointConfig rejects points the request loop cannot run.
+func validateSweepPointConfig(config TestConfiguration) error {
+	if config.ConcurrentTests <= 0 {
+		return fmt.Errorf("concurrentTests must be positive, got %d", config.ConcurrentTests)
+	}
+	if config.TestCount <= 0 {
+		return fmt.Errorf("testCount must be positive, got %d", config.TestCount)
+	}
+	if config.MaxTokens < 0 || config.PromptLength < 0 {
+		return fmt.Errorf("token counts cannot be negative")
+	}
+	return nil
+}
+
+// setConfigField sets the numeric field at path, a dot-separated list of
+// JSON field names, to value. Integer fields only accept whole numbers.
+func setConfigField(config *TestConfiguration, path string, value float64) error {
+	field := reflect.ValueOf(config).Elem()
+	for _, name := range strings.Split(path, ".") {
+		if field.Kind() != reflect.Struct {
+			return fmt.Errorf("unknown sweep field: %s", path)
+		}
+		next, ok := fieldByJSONName(field, name)
+		if !ok {
+			return fmt.Errorf("unknown sweep field: %s", path)
+		}
+		field = next
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value != math.Trunc(value) {
+			return fmt.Errorf("sweep field %s needs whole numbers, got %g", path, value)
+		}
+		field.SetInt(int64(value))
+	case reflect.Float32, reflect.Float64:
+		field.SetFloat(value)
+	default:
+		return fmt.Errorf("sweep field %s is not numeric", path)
+	}
+	return nil
+}
+
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// summarizeSweepPoint reduces the results of one point. elapsed is the
+// wall time from the point's first dispatch until its last request
+// finished.
+func summarizeSweepPoint(coordinates map[string]float64, summary TestSummary, results []TestResult, elapsed time.Duration) SweepPointSummary {
+	point := SweepPointSummary{
+		Coordinates: coordinates,
+		Summary:     summary,
+		Duration:    float64(elapsed) / float64(time.Millisecond),
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		var completionTokens int
+		for _, result := range results {
+			if result.Success {
+				completionTokens += result.CompletionTokens
+			}
+		}
+		point.TotalOutputTokensPerSecond = float64(completionTokens) / seconds
+	}
+	return point
+}
+
+// formatSweepCoordinates renders coordinates as "field=value" pairs
+// sorted by field.
+func formatSweepCoordinates(coordinates map[string]float64) string {
+	fields := make([]string, 0, len(coordinates))
+	for field := range coordinates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%s=%g", field, coordinates[field]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSweepPointsCartesianProduct(t *testing.T) {
+	config := defaultTestConfiguration()
+	dims := []SweepDimension{
+		{Field: "maxTokens", Values: []float64{64, 128}},
+		{Field: "temperature", Start: 0, End: 1, Step: 0.5},
+	}
+
+	points, err := buildSweepPoints(config, dims)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(points) != 6 {
+		t.Fatalf("expected 2x3 points, got %d", len(points))
+	}
+
+	// The first dimension varies slowest.
+	want := []struct {
+		maxTokens   int
+		temperature float32
+	}{{64, 0}, {64, 0.5}, {64, 1}, {128, 0}, {128, 0.5}, {128, 1}}
+	for i, w := range want {
+		p := points[i]
+		if p.config.MaxTokens != w.maxTokens || p.config.Temperature != w.temperature {
+			t.Fatalf("point %d: got maxTokens=%d temperature=%g", i, p.config.MaxTokens, p.config.Temperature)
+		}
+		if p.coordinates["maxTokens"] != float64(w.maxTokens) || p.coordinates["temperature"] != float64(w.temperature) {
+			t.Fatalf("point %d: unexpected coordinates %v", i, p.coordinates)
+		}
+	}
+	if config.MaxTokens != defaultTestConfiguration().MaxTokens {
+		t.Fatalf("building points must not modify the base configuration")
+	}
+}
+
+func TestBuildSweepPointsRejectsInvalidDimensions(t *testing.T) {
+	cases := []struct {
+		name    string
+		dim     SweepDimension
+		wantErr string
+	}{
+		{"unknown field", SweepDimension{Field: "nope", Values: []float64{1}}, "unknown sweep field"},
+		{"non-numeric field", SweepDimension{Field: "model", Values: []float64{1}}, "not numeric"},
+		{"fractional int", SweepDimension{Field: "maxTokens", Values: []float64{1.5}}, "whole numbers"},
+		{"fixed field", SweepDimension{Field: "rateConfig.requestRate", Values: []float64{1}}, "cannot be swept"},
+		{"seed", SweepDimension{Field: "seed", Values: []float64{1, 2}}, "cannot be swept"},
+		{"tool count", SweepDimension{Field: "toolConfig.count", Values: []float64{1, 2}}, "cannot be swept"},
+		{"prefix length", SweepDimension{Field: "prefixConfig.length", Values: []float64{8, 16}}, "cannot be swept"},
+		{"conversation turns", SweepDimension{Field: "conversationConfig.turns", Values: []float64{2, 4}}, "cannot be swept"},
+		{"empty range", SweepDimension{Field: "topP", Start: 1, End: 0, Step: 0.1}, "invalid sweep range"},
+		{"zero concurrency", SweepDimension{Field: "concurrentTests", Values: []float64{0}}, "must be positive"},
+		{"too many points", SweepDimension{Field: "maxTokens", Start: 1, End: 5000, Step: 1}, "more than"},
+	}
+	for _, tc := range cases {
+		_, err := buildSweepPoints(defaultTestConfiguration(), []SweepDimension{tc.dim})
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestSweepFlagParsesValuesAndRanges(t *testing.T) {
+	var dims sweepFlag
+	if err := dims.Set("maxTokens=128, 256"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dims.Set("temperature=0:1:0.25"); err != nil {
+		t.Fatal(err)
+	}
+	if len(dims) != 2 || len(dims[0].Values) != 2 || dims[0].Values[1] != 256 {
+		t.Fatalf("unexpected values dimension: %+v", dims)
+	}
+	if dims[1].Start != 0 || dims[1].End != 1 || dims[1].Step != 0.25 {
+		t.Fatalf("unexpected range dimension: %+v", dims[1])
+	}
+	if err := dims.Set("maxTokens"); err == nil {
+		t.Fatalf("expected an error without values")
+	}
+	if err := dims.Set("topP=0:1"); err == nil {
+		t.Fatalf("expected an error for an incomplete range")
+	}
+}
+
+func TestRunSweepRecordsCoordinates(t *testing.T) {
+	server := httptest.NewServer(NewMockServer(MockServerConfig{TTFT: 5 * time.Millisecond, TokensPerSecond: 2000, ReportUsage: true, Seed: 1}))
+	defer server.Close()
+
+	config := mockTestConfiguration(server.URL + "/v1")
+	config.TestMode = "sweep"
+	config.TestCount = 1
+	config.Sweep = []SweepDimension{
+		{Field: "maxTokens", Values: []float64{4, 8}},
+		{Field: "concurrentTests", Values: []float64{1, 2}},
+	}
+
+	batch, err := NewSpeedTestService().RunSpeedTest(context.Background(), config, "")
+	if err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	if batch.Summary.TotalTests != 6 {
+		t.Fatalf("expected 1+2 requests per max_tokens value, got %d", batch.Summary.TotalTests)
+	}
+	for _, result := range batch.Results {
+		maxTokens := result.SweepPoint["maxTokens"]
+		if maxTokens == 0 || result.SweepPoint["concurrentTests"] == 0 {
+			t.Fatalf("result %d has no sweep coordinates: %v", result.TestNumber, result.SweepPoint)
+		}
+		if result.CompletionTokens != int(maxTokens) || result.Configuration.MaxTokens != int(maxTokens) {
+			t.Fatalf("result %d ran with the wrong max_tokens: got %d, point %v", result.TestNumber, result.CompletionTokens, result.SweepPoint)
+		}
+	}
+
+	if len(batch.SweepPoints) != 4 {
+		t.Fatalf("expected 4 sweep point summaries, got %d", len(batch.SweepPoints))
+	}
+	last := batch.SweepPoints[3]
+	if last.Coordinates["maxTokens"] != 8 || last.Coordinates["concurrentTests"] != 2 || last.Summary.TotalTests != 2 {
+		t.Fatalf("unexpected last point: %+v", last)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const tgiDefaultEndpoint = "http://localhost:8080"
+
+// TGIClient talks to the Hugging Face Text Generation Inference
+// /generate and /generate_stream endpoints. These take raw text, so chat
+// messages are flattened without applying the model's chat template.
+type TGIClient struct {
+	apiEndpoint string
+	apiKey      string
+	httpClient  *http.Client
+}
+
+// NewTGIClient creates a client for the TGI server at apiEndpoint
+// (e.g. http://localhost:8080). The API key is optional.
+func NewTGIClient(apiEndpoint, apiKey string, timeout int) *TGIClient {
+	if apiEndpoint == "" {
+		apiEndpoint = tgiDefaultEndpoint
+	}
+
+	return &TGIClient{
+		apiEndpoint: strings.TrimRight(apiEndpoint, "/"),
+		apiKey:      apiKey,
+		httpClient: &http.Client{
+			Timeout: time.Duration(timeout) * time.Second,
+		},
+	}
+}
+
+// tgiParameters only sets sampling fields TGI accepts: temperature must be
+// strictly positive and top_p strictly inside (0, 1).
+type tgiParameters struct {
+	MaxNewTokens        int      `json:"max_new_tokens,omitempty"`
+	Temperature         *float32 `json:"temperature,omitempty"`
+	TopP                *float32 `json:"top_p,omitempty"`
+	FrequencyPenalty    *float32 `json:"frequency_penalty,omitempty"`
+	DoSample            bool     `json:"do_sample,omitempty"`
+	Details             bool     `json:"details"`
+	DecoderInputDetails bool     `json:"decoder_input_details,omitempty"` // not allowed when streaming
+	ReturnFullText      bool     `json:"return_full_text"`
+}
+
+type tgiRequest struct {
+	Inputs     string        `json:"inputs"`
+	Parameters tgiParameters `json:"parameters"`
+}
+
+type tgiDetails struct {
+	FinishReason    string            `json:"finish_reason"`
+	GeneratedTokens int               `json:"generated_tokens"`
+	InputLength     int               `json:"input_length"` // newer TGI versions, streaming only
+	Prefill         []json.RawMessage `json:"prefill"`      // with decoder_input_details
+}
+
+type tgiStreamEvent struct {
+	Token struct {
+		Text    string `json:"text"`
+		Special bool   `json:"special"`
+	} `json:"token"`
+	Details *tgiDetails `json:"details"`
+	Error   string      `json:"error"`
+}
+
+type tgiResponse struct {
+	GeneratedText string      `json:"generated_text"`
+	Details       *tgiDetails `json:"details"`
+}
+
+// GenerateCompletion sends request to /generate_stream or, without
+// streaming, to /generate.
+func (c *TGIClient) GenerateCompletion(ctx context.Context, request OpenAIRequest, headers map[string]string, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
+	payload := tgiRequest{
+		Inputs: flattenMessages(request.Messages),
+		Parameters: tgiParameters{
+			MaxNewTokens:        request.MaxTokens,
+			Details:             true,
+			DecoderInputDetails: !request.Stream,
+		},
+	}
+	if request.Temperature > 0 {
+		temperature := request.Temperature
+		payload.Parameters.Temperature = &temperature
+		payload.Parameters.DoSample = true
+	}
+	if request.TopP > 0 && request.TopP < 1 {
+		topP := request.TopP
+		payload.Parameters.TopP = &topP
+		payload.Parameters.DoSample = true
+	}
+	if request.FrequencyPenalty != 0 {
+		penalty := request.FrequencyPenalty
+		payload.Parameters.FrequencyPenalty = &penalty
+	}
+
+	path := "/generate"
+	if request.Stream {
+		path = "/generate_stream"
+	}
+
+	resp, startTime, err := postJSON(ctx, c.httpClient, c.apiEndpoint+path, payload, headers, func(req *http.Request) {
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if request.Stream {
+		return c.handleStreamingResponse(resp.Body, startTime, request.Model, onToken, onFirstToken)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading response: %v", err)
+	}
+
+	var generated tgiResponse
+	if err := json.Unmarshal(body, &generated); err != nil {
+		return nil, 0, fmt.Errorf("error unmarshaling response: %v", err)
+	}
+
+	var usage Usage
+	var finishReason string
+	if generated.Details != nil {
+		usage = tgiToUsage(*generated.Details, len(generated.Details.Prefill))
+		finishReason = generated.Details.FinishReason
+	}
+
+	return newTextResponse("", request.Model, generated.GeneratedText, finishReason, usage), time.Since(startTime), nil
+}
+
+func (c *TGIClient) handleStreamingResponse(body io.Reader, startTime time.Time, model string, onToken TokenCallback, onFirstToken func(time.Duration)) (*OpenAIResponse, time.Duration, error) {
+	reader := bufio.NewReader(body)
+	collector := newStreamCollector(startTime, onToken, onFirstToken)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, 0, fmt.Errorf("error reading stream response: %v", err)
+		}
+
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event tgiStreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			return nil, 0, fmt.Errorf("error unmarshaling stream event: %v", err)
+		}
+		if event.Error != "" {
+			return nil, 0, fmt.Errorf("stream error: %s", event.Error)
+		}
+
+		if !event.Token.Special {
+			collector.add(event.Token.Text)
+		}
+
+		// The last event carries the full text and generation details.
+		if event.Details != nil {
+			usage := tgiToUsage(*event.Details, event.Details.InputLength)
+			return collector.response("", model, event.Details.FinishReason, usage), collector.firstTokenLatency(), nil
+		}
+	}
+
+	return collector.response("", model, "", Usage{}), collector.firstTokenLatency(), nil
+}
+
+func tgiToUsage(details tgiDetails, promptTokens int) Usage {
+	return Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: details.GeneratedTokens,
+		TotalTokens:      promptTokens + details.GeneratedTokens,
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Values of TestResult.ErrorKind. Requests that fail for other reasons
+// (transport errors, HTTP errors) leave it empty.
+const (
+	errorKindMalformedToolCall = "malformed_tool_call"
+	errorKindMissingToolCall   = "missing_tool_call"
+)
+
+// errToolCallIndex reports a streamed tool call fragment whose index skips
+// ahead of or before the calls seen so far. Retrying without streaming
+// would hide it, so the request fails as a malformed tool call.
+var errToolCallIndex = errors.New("malformed tool call stream: unexpected tool call index")
+
+// Values of ToolConfiguration.ToolChoice besides the name of a tool.
+const (
+	toolChoiceRequired = "required"
+	toolChoiceAuto     = "auto"
+)
+
+// builtinTools are agent-style function definitions with argument schemas
+// of various sizes. Tool tests send the first ToolConfiguration.Count.
+var builtinTools = []Tool{
+	builtinTool("get_weather", "Get the current weather for a location.",
+		`{"type":"object","properties":{"location":{"type":"string","description":"City and country"},"unit":{"type":"string","enum":["celsius","fahrenheit"]}},"required":["location"]}`),
+	builtinTool("search_web", "Search the web and return the top results.",
+		`{"type":"object","properties":{"query":{"type":"string"},"max_results":{"type":"integer","minimum":1,"maximum":20}},"required":["query"]}`),
+	builtinTool("get_stock_price", "Look up the latest price of a stock.",
+		`{"type":"object","properties":{"symbol":{"type":"string","description":"Ticker symbol"}},"required":["symbol"]}`),
+	builtinTool("send_email", "Send an email on behalf of the user.",
+		`{"type":"object","properties":{"to":{"type":"array","items":{"type":"string"}},"subject":{"type":"string"},"body":{"type":"string"}},"required":["to","subject","body"]}`),
+	builtinTool("create_calendar_event", "Create an event in the user's calendar.",
+		`{"type":"object","properties":{"title":{"type":"string"},"start":{"type":"string","description":"ISO 8601 start time"},"end":{"type":"string","description":"ISO 8601 end time"},"attendees":{"type":"array","items":{"type":"string"}}},"required":["title","start"]}`),
+	builtinTool("query_database", "Run a read-only SQL query against the analytics database.",
+		`{"type":"object","properties":{"sql":{"type":"string"},"limit":{"type":"integer"}},"required":["sql"]}`),
+	builtinTool("translate_text", "Translate text into another language.",
+		`{"type":"object","properties":{"text":{"type":"string"},"target_language":{"type":"string","description":"ISO 639-1 code"}},"required":["text","target_language"]}`),
+	builtinTool("book_flight", "Book a flight for the user.",
+		`{"type":"object","properties":{"origin":{"type":"string"},"destination":{"type":"string"},"date":{"type":"string"},"passengers":{"type":"integer","minimum":1}},"required":["origin","destination","date"]}`),
+}
+
+func builtinTool(name, description, parameters string) Tool {
+	return Tool{Type: "function", Function: ToolFunction{Name: name, Description: description, Parameters: json.RawMessage(parameters)}}
+}
+
+// enabled reports whether requests carry tool definitions.
+func (tc ToolConfiguration) enabled() bool {
+	return tc.Count > 0 || tc.Path != ""
+}
+
+// choice returns the configured tool choice, "required" when unset.
+func (tc ToolConfiguration) choice() string {
+	if tc.ToolChoice == "" {
+		return toolChoiceRequired
+	}
+	return tc.ToolChoice
+}
+
+// loadTools returns the tool definitions of a tool test, or nil when
+// config sends none. It also checks everything else tool tests depend on.
+func loadTools(config TestConfiguration) ([]Tool, error) {
+	tc := config.ToolConfig
+	if !tc.enabled() {
+		return nil, nil
+	}
+	if p := strings.ToLower(config.Provider); p != "" && p != providerOpenAI {
+		return nil, fmt.Errorf("tool calling needs the openai provider, got %s", config.Provider)
+	}
+	if config.TestMode == "conversation" {
+		return nil, fmt.Errorf("tool calling cannot be used in conversation mode")
+	}
+
+	var tools []Tool
+	if tc.Path != "" {
+		data, err := os.ReadFile(tc.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tool definitions: %w", err)
+		}
+		if err := json.Unmarshal(data, &tools); err != nil {
+			return nil, fmt.Errorf("failed to parse tool definitions %s: %w", tc.Path, err)
+		}
+		if len(tools) == 0 {
+			return nil, fmt.Errorf("tool definitions %s are empty", tc.Path)
+		}
+		for i, tool := range tools {
+			if tool.Function.Name == "" {
+				return nil, fmt.Errorf("tool %d of %s has no function name", i+1, tc.Path)
+			}
+			if tool.Type == "" {
+				tools[i].Type = "function"
+			}
+		}
+	} else {
+		if tc.Count > len(builtinTools) {
+			return nil, fmt.Errorf("invalid tool configuration: count must be between 1 and %d, got %d", len(builtinTools), tc.Count)
+		}
+		tools = builtinTools[:tc.Count]
+	}
+
+	switch choice := tc.choice(); choice {
+	case toolChoiceRequired, toolChoiceAuto:
+	default:
+		found := false
+		for _, tool := range tools {
+			found = found || tool.Function.Name == choice
+		}
+		if !found {
+			return nil, fmt.Errorf("tool choice %s is neither auto, required nor the name of a tool", choice)
+		}
+	}
+	return tools, nil
+}
+
+// toolChoiceParam encodes a tool choice for the request body.
+func toolChoiceParam(choice string) interface{} {
+	if choice == toolChoiceRequired || choice == toolChoiceAuto {
+		return choice
+	}
+	return map[string]interface{}{"type": "function", "function": map[string]string{"name": choice}}
+}
+
+// checkToolCalls validates the tool calls of a response. It returns the
+// failure class and message, or empty strings when the calls are usable:
+// every call must name a function and carry arguments that parse as a JSON
+// object, and a call must be present unless the model may choose to answer.
+func checkToolCalls(calls []ToolCall, choice string) (string, string) {
+	if len(calls) == 0 {
+		if choice == toolChoiceAuto {
+			return "", ""
+		}
+		return errorKindMissingToolCall, "response contains no tool call"
+	}
+	for i, call := range calls {
+		if call.Function.Name == "" {
+			return errorKindMalformedToolCall, fmt.Sprintf("tool call %d has no function name", i+1)
+		}
+		var arguments map[string]interface{}
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil {
+			return errorKindMalformedToolCall, fmt.Sprintf("tool call %d (%s) has malformed arguments: %v", i+1, call.Function.Name, err)
+		}
+	}
+	return "", ""
+}
+
+// summarizeToolCalls reports tool call timing and failure classes, or nil
+// when the results come from a test without tools.
+func summarizeToolCalls(results []TestResult) *ToolCallSummary {
+	var summary ToolCallSummary
+	var firstCall, callTPS []float64
+	for _, result := range results {
+		if !result.Configuration.ToolConfig.enabled() {
+			continue
+		}
+		summary.Requests++
+		switch result.ErrorKind {
+		case errorKindMalformedToolCall:
+			summary.MalformedCalls++
+		case errorKindMissingToolCall:
+			summary.MissingCalls++
+		}
+		if !result.Success || result.ToolCalls == 0 {
+			continue
+		}
+		summary.RequestsWithCalls++
+		if result.TimeToFirstToolCall > 0 {
+			firstCall = append(firstCall, result.TimeToFirstToolCall)
+		}
+		if result.ToolCallTokensPerSecond > 0 {
+			callTPS = append(callTPS, result.ToolCallTokensPerSecond)
+		}
+	}
+	if summary.Requests == 0 {
+		return nil
+	}
+
+	summary.MalformedRate = float64(summary.MalformedCalls) / float64(summary.Requests)
+	summary.AverageTimeToFirstToolCall = mean(firstCall)
+	summary.TimeToFirstToolCallDistribution = computeDistribution(firstCall)
+	summary.AverageToolCallTokensPerSecond = mean(callTPS)
+	summary.ToolCallTokensPerSecondDistribution = computeDistribution(callTPS)
+	return &summary
+}
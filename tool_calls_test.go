@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTools(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tools.json")
+	if err := os.WriteFile(path, []byte(`[{"function": {"name": "lookup", "parameters": {"type": "object"}}}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		tc      ToolConfiguration
+		mode    string
+		wantErr string
+		want    int
+	}{
+		{"off", ToolConfiguration{}, "normal", "", 0},
+		{"builtin", ToolConfiguration{Count: 2}, "normal", "", 2},
+		{"file", ToolConfiguration{Path: path, ToolChoice: "lookup"}, "normal", "", 1},
+		{"too many", ToolConfiguration{Count: len(builtinTools) + 1}, "normal", "count must be between", 0},
+		{"unknown choice", ToolConfiguration{Count: 2, ToolChoice: "book_flight"}, "normal", "tool choice", 0},
+		{"conversation", ToolConfiguration{Count: 1}, "conversation", "conversation mode", 0},
+	}
+	for _, tc := range cases {
+		config := defaultTestConfiguration()
+		config.TestMode = tc.mode
+		config.ToolConfig = tc.tc
+		tools, err := loadTools(config)
+		if tc.wantErr == "" {
+			if err != nil || len(tools) != tc.want {
+				t.Errorf("%s: expected %d tools, got %d (%v)", tc.name, tc.want, len(tools), err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// needsVision reports whether any request of a batch with these step
+// configurations carries images.
+func needsVision(configs []TestConfiguration) bool {
+	for _, config := range configs {
+		if config.VisionConfig.Count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validateVisionConfig checks the image settings of one step.
+func validateVisionConfig(config TestConfiguration) error {
+	vc := config.VisionConfig
+	if vc.Count < 0 || vc.Resolution < 0 {
+		return fmt.Errorf("invalid vision configuration: count and resolution must not be negative")
+	}
+	if vc.Count == 0 {
+		return nil
+	}
+	if p := strings.ToLower(config.Provider); p != "" && p != providerOpenAI {
+		return fmt.Errorf("image inputs need the openai provider, got %s", config.Provider)
+	}
+	if config.PromptType == "dataset" {
+		return fmt.Errorf("image inputs cannot be used with dataset prompts")
+	}
+	if config.TestMode == "conversation" {
+		return fmt.Errorf("image inputs cannot be used in conversation mode")
+	}
+	if len(vc.Images) == 0 && vc.Resolution == 0 {
+		return fmt.Errorf("invalid vision configuration: generated images need a resolution")
+	}
+	switch vc.Detail {
+	case "", "auto", "low", "high":
+	default:
+		return fmt.Errorf("unsupported image detail: %s", vc.Detail)
+	}
+	return nil
+}
+
+// visionImage is a local image file loaded for a batch.
+type visionImage struct {
+	path          string
+	data          []byte
+	mime          string
+	width, height int
+}
+
+// visionWorkload attaches images to the requests of a batch. Files are
+// read once and each scaled size is encoded once. Without files, every
+// image of every request is fresh noise derived from the seed, so servers
+// that cache encoded images cannot skip the vision encoder.
+type visionWorkload struct {
+	files []visionImage
+	seed  int64
+
+	// templateOverhead is the prompt tokens the server adds to the text of
+	// a request, which are not image tokens. Image tokens are only
+	// reported once it is known.
+	templateOverhead      int
+	templateOverheadKnown bool
+
+	mu     sync.Mutex
+	scaled map[[2]int]visionURL // (file index, resolution) -> encoded image
+}
+
+type visionURL struct {
+	url     string
+	longest int
+}
+
+// newVisionWorkload loads the images of config, or returns nil when no
+// step sends any.
+func newVisionWorkload(config TestConfiguration, steps []TestConfiguration) (*visionWorkload, error) {
+	if !needsVision(steps) {
+		return nil, nil
+	}
+	for _, step := range steps {
+		if err := validateVisionConfig(step); err != nil {
+			return nil, err
+		}
+	}
+
+	w := &visionWorkload{seed: config.Seed, scaled: make(map[[2]int]visionURL)}
+	for _, path := range config.VisionConfig.Images {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image %s: %w", path, err)
+		}
+		w.files = append(w.files, visionImage{
+			path: path, data: data, mime: http.DetectContentType(data),
+			width: cfg.Width, height: cfg.Height,
+		})
+	}
+	return w, nil
+}
+
+// parts returns the image parts of request testNumber and the longest side
+// of the images sent. Files are used in turn across requests.
+func (w *visionWorkload) parts(vc VisionConfiguration, testNumber int) ([]ContentPart, int, error) {
+	parts := make([]ContentPart, 0, vc.Count)
+	longest := 0
+	for k := 0; k < vc.Count; k++ {
+		var encoded visionURL
+		var err error
+		if len(w.files) > 0 {
+			encoded, err = w.file((testNumber*vc.Count+k)%len(w.files), vc.Resolution)
+		} else {
+			encoded, err = generateImage(deriveSeed(w.seed, "image", testNumber, k), vc.Resolution)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		longest = max(longest, encoded.longest)
+		parts = append(parts, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: encoded.url, Detail: vc.Detail}})
+	}
+	return parts, longest, nil
+}
+
+// file returns file index scaled so its longest side is resolution, or as
+// is when resolution is 0.
+func (w *visionWorkload) file(index, resolution int) (visionURL, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	key := [2]int{index, resolution}
+	if encoded, ok := w.scaled[key]; ok {
+		return encoded, nil
+	}
+
+	f := w.files[index]
+	encoded := visionURL{url: dataURL(f.mime, f.data), longest: max(f.width, f.height)}
+	if resolution > 0 {
+		src, _, err := image.Decode(bytes.NewReader(f.data))
+		if err != nil {
+			return visionURL{}, fmt.Errorf("failed to decode image %s: %w", f.path, err)
+		}
+		scaled := scaleImage(src, resolution)
+		if encoded, err = encodeJPEG(scaled); err != nil {
+			return visionURL{}, err
+		}
+	}
+	w.scaled[key] = encoded
+	return encoded, nil
+}
+
+// generateImage returns a square noise image. Noise barely compresses, so
+// the payload is as large as a photo of the same size.
+func generateImage(seed int64, resolution int) (visionURL, error) {
+	img := image.NewRGBA(image.Rect(0, 0, resolution, resolution))
+	rand.New(rand.NewSource(seed)).Read(img.Pix)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return encodeJPEG(img)
+}
+
+// scaleImage resizes src with nearest-neighbour sampling so its longest
+// side is longest pixels, keeping the aspect ratio. The vision encoder's
+// cost depends on the size, not on the quality of the scaling.
+func scaleImage(src image.Image, longest int) image.Image {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w >= h {
+		w, h = longest, max(h*longest/w, 1)
+	} else {
+		w, h = max(w*longest/h, 1), longest
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/h
+		for x := 0; x < w; x++ {
+			dst.Set(x, y, src.At(bounds.Min.X+x*bounds.Dx()/w, sy))
+		}
+	}
+	return dst
+}
+
+func encodeJPEG(img image.Image) (visionURL, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return visionURL{}, fmt.Errorf("failed to encode image: %w", err)
+	}
+	bounds := img.Bounds()
+	return visionURL{url: dataURL("image/jpeg", buf.Bytes()), longest: max(bounds.Dx(), bounds.Dy())}, nil
+}
+
+func dataURL(mime string, data []byte) string {
+	return "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// summarizeVision reports how much of the prompt images took over the
+// successful requests that carried any, or nil when none did.
+func summarizeVision(results []TestResult) *VisionSummary {
+	var images, imageTokens, ttft []float64
+	var imageTotal, promptTotal int
+	for _, result := range results {
+		if !result.Success || result.Images == 0 {
+			continue
+		}
+		images = append(images, float64(result.Images))
+		imageTokens = append(imageTokens, float64(result.ImageTokens))
+		ttft = append(ttft, result.RequestLatency)
+		imageTotal += result.ImageTokens
+		promptTotal += result.PromptTokens
+	}
+	if len(images) == 0 {
+		return nil
+	}
+
+	summary := &VisionSummary{
+		Requests:           len(images),
+		AverageImages:      mean(images),
+		AverageImageTokens: mean(imageTokens),
+		AverageTTFT:        mean(ttft),
+		TTFTDistribution:   computeDistribution(ttft),
+	}
+	if promptTotal > 0 {
+		summary.ImageTokenShare = float64(imageTotal) / float64(promptTotal)
+	}
+	return summary
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMessageContentParts(t *testing.T) {
+	message := Message{Role: "user", Content: "describe", Parts: []ContentPart{
+		{Type: "text", Text: "describe"},
+		{Type: "image_url", ImageURL: &ImageURL{URL: "data:image/png;base64,AAAA"}},
+	}}
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"content":[{"type":"text","text":"describe"},{"type":"image_url"`) {
+		t.Fatalf("expected a content array, got %s", data)
+	}
+
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Content != "describe" || len(decoded.Parts) != 2 || decoded.Parts[1].ImageURL.URL != "data:image/png;base64,AAAA" {
+		t.Fatalf("content array did not round-trip: %+v", decoded)
+	}
+
+	plain, _ := json.Marshal(Message{Role: "user", Content: "hi"})
+	if string(plain) != `{"role":"user","content":"hi"}` {
+		t.Fatalf("text messages should keep string content, got %s", plain)
+	}
+	if err := json.Unmarshal(plain, &decoded); err != nil || decoded.Content != "hi" || decoded.Parts != nil {
+		t.Fatalf("string content did not round-trip: %+v (%v)", decoded, err)
+	}
+}